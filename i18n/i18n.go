@@ -29,6 +29,9 @@ var SUPPORTED_LOCALES = []string{
 
 var resourcePath = filepath.Join("i18n", "resources")
 
+// the locale most recently chosen by InitWithLocale
+var currentLocale = DEFAULT_LOCALE
+
 func GetResourcePath() string {
 	return resourcePath
 }
@@ -61,9 +64,15 @@ func InitWithLocale(locale string) goi18n.TranslateFunc {
 	if err != nil {
 		panic(err)
 	}
+	currentLocale = locale
 	return goi18n.MustTfunc(locale)
 }
 
+// CurrentLocale returns the locale that T translates into
+func CurrentLocale() string {
+	return currentLocale
+}
+
 func loadFromAsset(locale string) (err error) {
 	assetName := locale + ".all.json"
 	assetKey := filepath.Join(resourcePath, assetName)
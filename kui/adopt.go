@@ -0,0 +1,50 @@
+package kui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// populated returns whether dir exists and has anything in it
+func populated(dir string) bool {
+	entries, err := ioutil.ReadDir(dir)
+	return err == nil && len(entries) > 0
+}
+
+// validateExtract checks an extracted Kui base that lacks its success
+// marker, e.g. because we were killed just before writing it. We
+// record the manifest and provenance only after a complete extraction,
+// so if they are present, and the extract matches the manifest, the
+// extract can be trusted.
+func validateExtract(extractedDir string, manifestFile string, provenanceFile string, command string, options ExtractOptions) error {
+	if readProvenance(provenanceFile).Version == "" {
+		return fmt.Errorf("no provenance recorded")
+	}
+
+	expected, err := readManifest(manifestFile)
+	if err != nil {
+		return fmt.Errorf("no manifest recorded: %v", err)
+	}
+	actual, err := expected.buildLike(extractedDir)
+	if err != nil {
+		return err
+	}
+	if diff := expected.Diff(actual); !diff.Empty() {
+		return fmt.Errorf("extract does not match its manifest")
+	}
+
+	// the extract must also be the subset we are now asking for
+	for _, entry := range actual.Entries {
+		if !options.includes(entry.Path) {
+			return fmt.Errorf("extract includes %s, which KASK_EXTRACT_INCLUDE excludes", entry.Path)
+		}
+	}
+	if rel, err := filepath.Rel(extractedDir, command); err == nil && options.includes(filepath.ToSlash(rel)) {
+		if _, err := os.Stat(command); err != nil {
+			return fmt.Errorf("Kui base is missing its executable: %v", err)
+		}
+	}
+	return nil
+}
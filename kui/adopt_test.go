@@ -0,0 +1,77 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// simulates a kask killed just before it wrote the success marker
+func (suite *KaskTestSuite) TestAdoptCompleteUnmarkedExtract() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksum := sha256Hex(dist)
+	gets := 0
+	server := serveDistCountingGets(&dist, &checksum, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	successFile := filepath.Join(pluginDir, "cache-1.2.3", "success")
+	suite.Require().Nil(os.Remove(successFile))
+
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Nil(err)
+	suite.Equal(1, gets, "a complete extract should be adopted, not re-fetched")
+	suite.FileExists(successFile)
+}
+
+// simulates a kask killed part way through extracting
+func (suite *KaskTestSuite) TestWipeIncompleteUnmarkedExtract() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksum := sha256Hex(dist)
+	gets := 0
+	server := serveDistCountingGets(&dist, &checksum, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	targetDir := filepath.Join(pluginDir, "cache-1.2.3")
+	suite.Require().Nil(os.Remove(filepath.Join(targetDir, "success")))
+	suite.Require().Nil(os.Remove(filepath.Join(targetDir, "manifest.json")))
+	stale := filepath.Join(targetDir, "extract", "stale")
+	suite.Require().Nil(ioutil.WriteFile(stale, []byte("left over"), 0644))
+
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Nil(err)
+	suite.Equal(2, gets)
+	suite.FileExists(filepath.Join(targetDir, "success"))
+	suite.FileExists(filepath.Join(targetDir, "extract", rootCommandPath(suite.pluginContext, PlatformKey())))
+	_, err = os.Stat(stale)
+	suite.True(os.IsNotExist(err), "the incomplete extract should have been wiped")
+}
+
+func (suite *KaskTestSuite) TestValidateExtractRejectsTampering() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	command, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	targetDir := filepath.Join(pluginDir, "cache-1.2.3")
+	extractedDir := filepath.Join(targetDir, "extract")
+	manifestFile := filepath.Join(targetDir, "manifest.json")
+	provenanceFile := filepath.Join(targetDir, "provenance.json")
+
+	suite.Nil(validateExtract(extractedDir, manifestFile, provenanceFile, command.Path, ExtractOptions{}))
+
+	suite.Require().Nil(os.Remove(command.Path))
+	suite.NotNil(validateExtract(extractedDir, manifestFile, provenanceFile, command.Path, ExtractOptions{}))
+}
@@ -0,0 +1,27 @@
+package kui
+
+// aliasTable maps the alias of each of the given commands to the
+// command's name. An alias may not shadow a command, nor stand for
+// more than one.
+func aliasTable(commands []Command) (map[string]string, error) {
+	names := map[string]bool{}
+	for _, command := range commands {
+		names[command.Name] = true
+	}
+
+	aliases := map[string]string{}
+	for _, command := range commands {
+		alias := command.Alias
+		if alias == "" || alias == command.Name {
+			continue
+		}
+		if names[alias] {
+			return nil, newErrorf(UsageError, "the alias %s of %s is itself a command", alias, command.Name)
+		}
+		if other, taken := aliases[alias]; taken && other != command.Name {
+			return nil, newErrorf(UsageError, "%s is an alias of both %s and %s", alias, other, command.Name)
+		}
+		aliases[alias] = command.Name
+	}
+	return aliases, nil
+}
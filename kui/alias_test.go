@@ -0,0 +1,23 @@
+package kui
+
+func (suite *KaskTestSuite) TestAliasTable() {
+	aliases, err := aliasTable(suite.cmd.GetMetadata().Commands)
+	suite.Nil(err)
+	suite.Equal("install", aliases["i"])
+	suite.NotContains(aliases, "kask")
+	suite.NotContains(aliases, "ls", "ls is a command of Kui's own")
+	suite.NotContains(aliases, "rm", "rm is a command of Kui's own")
+
+	_, err = aliasTable([]Command{{Name: "list", Alias: "ls"}, {Name: "ls"}})
+	suite.Equal(UsageError, KindOf(err), "an alias may not shadow a command")
+
+	_, err = aliasTable([]Command{{Name: "list", Alias: "l"}, {Name: "logs", Alias: "l"}})
+	suite.Equal(UsageError, KindOf(err), "an alias may not stand for two commands")
+}
+
+func (suite *KaskTestSuite) TestRunResolvesAlias() {
+	suite.skipUnlessLinux()
+	forwarded, err := suite.runFakeKui("i", "some-plugin")
+	suite.Nil(err)
+	suite.Equal([]string{"install", "some-plugin"}, forwarded)
+}
@@ -0,0 +1,192 @@
+package kui
+
+import (
+	stdcontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Extracts are stored by the checksum of the archive they came from,
+// in cache/blobs/<sha256>, and each cache-<version>/extract is a
+// symlink to one of those. Versions whose dists are identical thus
+// share one extract, and re-tagging a dist needs no new download.
+// Windows may not let us create symlinks, so there each version keeps
+// an extract of its own; as may tests, elsewhere.
+var contentAddressed = func() bool {
+	return runtime.GOOS != "windows"
+}
+
+// blob is the shared extract of a dist with a given checksum
+type blob struct {
+	dir          string
+	extractedDir string
+	manifestFile string
+	successFile  string
+}
+
+// blobOf returns the blob of the dist with the given checksum. An
+// extract of a subset of the dist is a different thing than that of
+// the whole, so the subset is part of the key.
+func blobOf(pluginDir string, checksum string, options ExtractOptions) blob {
+	name := checksum
+	if options.Include != "" {
+		hash := sha256.Sum256([]byte(options.Include))
+		name += "-" + hex.EncodeToString(hash[:])[:12]
+	}
+	dir := filepath.Join(pluginDir, "cache", "blobs", name)
+	return blob{
+		dir:          dir,
+		extractedDir: filepath.Join(dir, "extract"),
+		manifestFile: filepath.Join(dir, "manifest.json"),
+		successFile:  filepath.Join(dir, "success"),
+	}
+}
+
+// intact returns whether the blob was completely extracted, and still
+// matches what was extracted
+func (b blob) intact() bool {
+	if _, err := os.Stat(b.successFile); err != nil {
+		return false
+	}
+	expected, err := readManifest(b.manifestFile)
+	if err != nil {
+		return false
+	}
+	actual, err := expected.buildLike(b.extractedDir)
+	return err == nil && expected.Diff(actual).Empty()
+}
+
+// seal records the blob as complete
+func (b blob) seal(context Context) error {
+	manifest, err := BuildManifest(b.extractedDir, manifestHashes(context))
+	if err != nil {
+		return err
+	}
+	if err := writeManifest(b.manifestFile, manifest); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(b.successFile, nil, 0644)
+}
+
+// extractBlob extracts the archive into a fresh blob
+func extractBlob(ctx stdcontext.Context, context Context, url string, archive string, b blob, options ExtractOptions) (string, error) {
+	if err := os.RemoveAll(b.dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(b.extractedDir, 0755); err != nil {
+		return "", err
+	}
+	extractor, err := extractDist(ctx, context, url, archive, b.extractedDir, options)
+	if err != nil {
+		// half a blob is of no use to any version
+		os.RemoveAll(b.dir)
+		return "", err
+	}
+	if err := b.seal(context); err != nil {
+		os.RemoveAll(b.dir)
+		return "", newError(ExtractError, fmt.Errorf("unable to record the manifest of the Kui base: %w", err))
+	}
+	return extractor, nil
+}
+
+// linkToBlob makes extractedDir a symlink to the extract of the blob
+func linkToBlob(extractedDir string, b blob) error {
+	target, err := filepath.Rel(filepath.Dir(extractedDir), b.extractedDir)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(extractedDir); err != nil {
+		return err
+	}
+	return os.Symlink(target, extractedDir)
+}
+
+// migrateToBlob moves the extract of a version cached before we were
+// content addressed into the blob of the given checksum, and links the
+// version to it
+func migrateToBlob(context Context, pluginDir string, cache cacheLayout, checksum string, options ExtractOptions) error {
+	if info, err := os.Lstat(cache.extractedDir); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	b := blobOf(pluginDir, checksum, options)
+	if b.intact() {
+		// another version has already contributed this content
+		return linkToBlob(cache.extractedDir, b)
+	}
+
+	if err := os.RemoveAll(b.dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(cache.extractedDir, b.extractedDir); err != nil {
+		return err
+	}
+	if err := linkToBlob(cache.extractedDir, b); err != nil {
+		// put things back as we found them
+		os.Rename(b.extractedDir, cache.extractedDir)
+		return err
+	}
+	return b.seal(context)
+}
+
+// extractDist extracts the archive into dir, and makes sure that the
+// Kui executable, if it is part of what we extracted, can be run
+func extractDist(ctx stdcontext.Context, context Context, url string, archive string, dir string, options ExtractOptions) (string, error) {
+	format := distFormat(context, url)
+	extractor, err := unarchive(ctx, format, archive, dir, options)
+	if err != nil {
+		if ctx.Err() == nil {
+			err = explainFormatError(url, archive, format, err)
+		}
+		return "", newError(ExtractError, timedOut(ctx, context, err))
+	}
+
+	command := GetRootCommand(context, dir).Path
+	if rel, err := filepath.Rel(dir, command); err == nil && options.includes(filepath.ToSlash(rel)) {
+		if err := ensureExecutable(command); err != nil {
+			return "", newError(ExtractError, err)
+		}
+	}
+	return extractor, nil
+}
+
+// sameContentAs looks among the other cached versions for one whose
+// dist, fetched from the same host as url, had the given ETag,
+// returning the checksum of that dist. Object stores derive a strong
+// ETag from the content, so the same ETag from the same store means
+// the same bytes; but another host's ETags say nothing of ours, and
+// even a matching ETag is trusted only alongside the checksum we
+// expect, and only if the other version's blob is intact.
+func sameContentAs(context Context, pluginDir string, version string, url string, etag string, expected string, options ExtractOptions) string {
+	if !contentAddressed() || expected == "" || etag == "" || strings.HasPrefix(etag, "W/") {
+		return ""
+	}
+	host, err := hostPort(url)
+	if err != nil {
+		return ""
+	}
+
+	provenances, _ := filepath.Glob(filepath.Join(stateDirOf(context, pluginDir), "cache-*", "provenance.json"))
+	for _, file := range provenances {
+		provenance := readProvenance(file)
+		if provenance.Version == version || provenance.ETag != etag || provenance.Platform != PlatformKey() || provenance.SHA256 != expected {
+			continue
+		}
+		if other, err := hostPort(provenance.URL); err != nil || other != host {
+			continue
+		}
+		if blobOf(pluginDir, provenance.SHA256, options).intact() {
+			return provenance.SHA256
+		}
+	}
+	return ""
+}
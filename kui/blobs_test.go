@@ -0,0 +1,127 @@
+package kui
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestIdenticalVersionsShareOneBlob() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksum := sha256Hex(dist)
+	gets := 0
+	server := serveDistCountingGets(&dist, &checksum, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	command, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.4", false)
+	suite.Require().Nil(err)
+	suite.Equal(1, gets, "a re-tagged dist should not be downloaded again")
+	suite.FileExists(command.Path)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	first, err := os.Readlink(filepath.Join(pluginDir, "cache-1.2.3", "extract"))
+	suite.Require().Nil(err)
+	second, err := os.Readlink(filepath.Join(pluginDir, "cache-1.2.4", "extract"))
+	suite.Require().Nil(err)
+	suite.Equal(first, second)
+
+	blobs, err := filepath.Glob(filepath.Join(pluginDir, "cache", "blobs", "*"))
+	suite.Nil(err)
+	suite.Equal([]string{filepath.Join(pluginDir, "cache", "blobs", checksum)}, blobs)
+}
+
+func (suite *KaskTestSuite) TestMigrateToBlob() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksum := sha256Hex(dist)
+	gets := 0
+	server := serveDistCountingGets(&dist, &checksum, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+
+	// turn it back into a cache from before content addressing
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	extractedDir := filepath.Join(pluginDir, "cache-1.2.3", "extract")
+	blob := blobOf(pluginDir, checksum, ExtractOptions{})
+	suite.Require().Nil(os.Remove(extractedDir))
+	suite.Require().Nil(os.Rename(blob.extractedDir, extractedDir))
+	suite.Require().Nil(os.RemoveAll(filepath.Join(pluginDir, "cache")))
+
+	command, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.Equal(1, gets)
+	suite.FileExists(command.Path)
+
+	info, err := os.Lstat(extractedDir)
+	suite.Require().Nil(err)
+	suite.True(info.Mode()&os.ModeSymlink != 0, "the extract should have moved into a blob")
+	suite.True(blob.intact())
+}
+
+func (suite *KaskTestSuite) TestSameETagReusesExtract() {
+	suite.skipUnlessLinux()
+	gets, notModified := 0, 0
+	dist := makeFakeDist()
+	server := serveWithETag(dist, `"abc123"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_SHA256", sha256Hex(dist))()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	command, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.4", false)
+	suite.Require().Nil(err)
+	suite.Equal(1, gets, "a dist with the ETag of a cached one should not be downloaded again")
+	suite.FileExists(command.Path)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	first := readProvenance(filepath.Join(pluginDir, "cache-1.2.3", "provenance.json"))
+	second := readProvenance(filepath.Join(pluginDir, "cache-1.2.4", "provenance.json"))
+	suite.Equal(first.SHA256, second.SHA256)
+}
+
+func (suite *KaskTestSuite) TestSameETagNeedsChecksumAndHost() {
+	suite.skipUnlessLinux()
+	gets, notModified := 0, 0
+	dist := makeFakeDist()
+	server := serveWithETag(dist, `"abc123"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	checksum := sha256Hex(dist)
+	url := server.URL + "/kui-1.2.4.zip"
+	suite.Equal(checksum, sameContentAs(suite.pluginContext, pluginDir, "1.2.4", url, `"abc123"`, checksum, ExtractOptions{}))
+	suite.Empty(sameContentAs(suite.pluginContext, pluginDir, "1.2.4", url, `"abc123"`, "", ExtractOptions{}), "an ETag alone should not be trusted")
+	suite.Empty(sameContentAs(suite.pluginContext, pluginDir, "1.2.4", "https://elsewhere.example/kui-1.2.4.zip", `"abc123"`, checksum, ExtractOptions{}), "another host's ETag says nothing of ours")
+}
+
+func (suite *KaskTestSuite) TestSameETagNeedsIntactBlob() {
+	suite.skipUnlessLinux()
+	gets, notModified := 0, 0
+	dist := makeFakeDist()
+	server := serveWithETag(dist, `"abc123"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_SHA256", sha256Hex(dist))()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	checksum := readProvenance(filepath.Join(pluginDir, "cache-1.2.3", "provenance.json")).SHA256
+	suite.Require().Nil(os.Remove(filepath.Join(blobOf(pluginDir, checksum, ExtractOptions{}).extractedDir, rootCommandPath(suite.pluginContext, PlatformKey()))))
+
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.4", false)
+	suite.Require().Nil(err)
+	suite.Equal(2, gets, "a damaged extract should not be trusted")
+}
@@ -0,0 +1,120 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// a manifest, of bundles or of a catalog snapshot, names a few lists of
+// plugins; anything bigger than this is not one
+const maxManifestSize = 256 * 1024
+
+// bundleVariable returns the variable that may define the bundle of the
+// given name, e.g. KASK_BUNDLE_K8S_TOOLS for k8s-tools
+func bundleVariable(name string) string {
+	return "KASK_BUNDLE_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// bundlePlugins returns the plugins of the bundle of the given name, as
+// defined by its KASK_BUNDLE_<NAME>, e.g. in the config file, or else by
+// the KASK_BUNDLES manifest
+func bundlePlugins(context Context, name string) ([]string, error) {
+	if value, ok := lookupEnv(context, bundleVariable(name)); ok {
+		return splitBundle(name, value)
+	}
+
+	location := getenv(context, "KASK_BUNDLES")
+	if location == "" {
+		return nil, newErrorf(UsageError, "no bundle %s: set %s, or KASK_BUNDLES to a manifest that defines it", name, bundleVariable(name))
+	}
+	body, err := readPluginManifest(context, location)
+	if err != nil {
+		return nil, newError(DownloadError, fmt.Errorf("unable to read the bundles manifest %s: %w", location, err))
+	}
+	bundles, err := parseBundles(body)
+	if err != nil {
+		return nil, newError(UsageError, err)
+	}
+	plugins, ok := bundles[name]
+	if !ok || len(plugins) == 0 {
+		return nil, newErrorf(UsageError, "the bundles manifest %s has no bundle %s", location, name)
+	}
+	return plugins, nil
+}
+
+// splitBundle splits the comma-separated plugins of a bundle
+func splitBundle(name string, value string) ([]string, error) {
+	plugins := []string{}
+	for _, plugin := range strings.Split(value, ",") {
+		if plugin = strings.TrimSpace(plugin); plugin != "" {
+			plugins = append(plugins, plugin)
+		}
+	}
+	if len(plugins) == 0 {
+		return nil, newErrorf(UsageError, "%s names no plugins", bundleVariable(name))
+	}
+	return plugins, nil
+}
+
+// readPluginManifest reads the manifest at the given url or path
+func readPluginManifest(context Context, location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return ioutil.ReadFile(location)
+	}
+
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), distLatestTimeout)
+	defer cancel()
+
+	var body []byte
+	err := withRetries(ctx, context, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := doRequest(context, req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return &HTTPStatusError{location, resp.StatusCode, resp.Status}
+		}
+		body, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+		return err
+	})
+	if err == nil && len(body) > maxManifestSize {
+		return nil, fmt.Errorf("it is too large")
+	}
+	return body, err
+}
+
+// parseBundles reads a bundles manifest, e.g.
+//
+//	{"bundles": {"k8s-tools": ["plugin-a", "plugin-b"]}}
+func parseBundles(body []byte) (map[string][]string, error) {
+	var manifest struct {
+		Bundles map[string][]string `json:"bundles"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to parse the bundles manifest: %v", err)
+	}
+	return manifest.Bundles, nil
+}
+
+// expandBundle turns `bundle install <name> [flags...]` into the
+// install of the bundle's plugins, with the same flags
+func expandBundle(context Context, args []string) ([]string, error) {
+	if len(args) < 2 || args[0] != "install" || strings.HasPrefix(args[1], "-") {
+		return nil, newErrorf(UsageError, "usage: kask bundle install <name> [--keep-going] [--force]")
+	}
+	plugins, err := bundlePlugins(context, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{"install"}, plugins...), args[2:]...), nil
+}
@@ -0,0 +1,75 @@
+package kui
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestBundleInstall() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_LIST", `[]`)()
+	defer setenv("KASK_BUNDLE_K8S_TOOLS", "plugin-a, plugin-b")()
+
+	var forwarded []string
+	var err error
+	output := suite.captureStdout(func() {
+		forwarded, err = suite.runFakeKui("bundle", "install", "k8s-tools")
+	})
+	suite.Nil(err)
+	suite.Equal([]string{"install", "plugin-b"}, forwarded, "the plugins should be installed in sequence")
+	suite.Contains(output, blue("Installed:")+" plugin-a, plugin-b\n")
+}
+
+func (suite *KaskTestSuite) TestBundleInstallKeepGoing() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_LIST", `[]`)()
+	defer setenv("FAKE_KUI_FAIL_ON", "bad")()
+	defer setenv("KASK_BUNDLE_MIXED", "bad,good")()
+
+	var err error
+	output := suite.captureStdout(func() {
+		_, err = suite.runFakeKui("bundle", "install", "mixed", "--keep-going")
+	})
+	suite.Equal(ExitChild, ExitCode(err))
+	suite.Contains(output, blue("Installed:")+" good\n")
+	suite.Contains(output, yellow("Failed:")+" bad\n")
+}
+
+func (suite *KaskTestSuite) TestBundleFromManifest() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"bundles": {"k8s-tools": ["plugin-a", "plugin-b"]}}`))
+	}))
+	defer server.Close()
+	defer setenv("KASK_BUNDLES", server.URL)()
+
+	plugins, err := bundlePlugins(suite.pluginContext, "k8s-tools")
+	suite.Nil(err)
+	suite.Equal([]string{"plugin-a", "plugin-b"}, plugins)
+
+	_, err = bundlePlugins(suite.pluginContext, "nope")
+	suite.Equal(UsageError, KindOf(err))
+
+	// as a local file, and with the config's definition winning
+	manifest := filepath.Join(suite.SaveDir, "bundles.json")
+	suite.Require().Nil(ioutil.WriteFile(manifest, []byte(`{"bundles": {"k8s-tools": ["plugin-c"]}}`), 0644))
+	defer setenv("KASK_BUNDLES", manifest)()
+	plugins, err = bundlePlugins(suite.pluginContext, "k8s-tools")
+	suite.Nil(err)
+	suite.Equal([]string{"plugin-c"}, plugins)
+
+	defer setenv("KASK_BUNDLE_K8S_TOOLS", "plugin-d")()
+	plugins, err = bundlePlugins(suite.pluginContext, "k8s-tools")
+	suite.Nil(err)
+	suite.Equal([]string{"plugin-d"}, plugins)
+}
+
+func (suite *KaskTestSuite) TestBundleUsage() {
+	_, err := expandBundle(suite.pluginContext, []string{"install"})
+	suite.Equal(UsageError, KindOf(err))
+	_, err = expandBundle(suite.pluginContext, []string{"remove", "k8s-tools"})
+	suite.Equal(UsageError, KindOf(err))
+	_, err = expandBundle(suite.pluginContext, []string{"install", "undefined-bundle"})
+	suite.Equal(UsageError, KindOf(err))
+}
@@ -0,0 +1,96 @@
+package kui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cacheLayout is where, within the plugin directory, the pieces of a
+// cached Kui base live; its state, i.e. the success marker and the
+// provenance, may live elsewhere, per KASK_STATE_DIR
+type cacheLayout struct {
+	dir            string
+	stateDir       string
+	successFile    string
+	extractedDir   string
+	downloadedFile string
+	validatorsFile string
+	manifestFile   string
+	provenanceFile string
+}
+
+func cacheLayoutOf(context Context, pluginDir string, version string) cacheLayout {
+	return cacheLayoutIn(pluginDir, stateDirOf(context, pluginDir), version)
+}
+
+// stateDirOf returns where the state of the caches in the given plugin
+// directory lives: KASK_STATE_DIR, for when the plugin directory may
+// not be written, else the plugin directory itself
+func stateDirOf(context Context, pluginDir string) string {
+	if stateDir := getenv(context, "KASK_STATE_DIR"); stateDir != "" {
+		return stateDir
+	}
+	return pluginDir
+}
+
+// cacheLayoutIn lays out the cache of the given version in pluginDir,
+// with its state in stateDir
+func cacheLayoutIn(pluginDir string, stateDir string, version string) cacheLayout {
+	dir := filepath.Join(pluginDir, "cache-"+version)
+	state := filepath.Join(stateDir, "cache-"+version)
+	return cacheLayout{
+		dir:            dir,
+		stateDir:       state,
+		successFile:    filepath.Join(state, "success"),
+		extractedDir:   filepath.Join(dir, "extract"),
+		downloadedFile: filepath.Join(dir, "downloaded.zip"),
+		validatorsFile: filepath.Join(dir, "validators.json"),
+		manifestFile:   filepath.Join(dir, "manifest.json"),
+		provenanceFile: filepath.Join(state, "provenance.json"),
+	}
+}
+
+// CacheStatus describes the cached Kui base of one version
+type CacheStatus struct {
+	Version       string      `json:"version"`
+	PluginDir     string      `json:"pluginDir"`
+	CacheDir      string      `json:"cacheDir"`
+	ExtractedDir  string      `json:"extractedDir"`
+	Binary        string      `json:"binary"`
+	SuccessExists bool        `json:"successExists"`
+	BinaryExists  bool        `json:"binaryExists"`
+	Provenance    *Provenance `json:"provenance,omitempty"`
+}
+
+// CacheStatus describes the cache of the Kui base that we would run
+func (component *KuiComponent) CacheStatus(context Context) (CacheStatus, error) {
+	return component.CacheStatusOf(context, component.DistVersion(context))
+}
+
+// CacheStatusOf describes the cache of the given version of the Kui base
+func (component *KuiComponent) CacheStatusOf(context Context, version string) (CacheStatus, error) {
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return CacheStatus{}, fmt.Errorf("unable to locate the plugin directory: %w", err)
+	}
+	cache := cacheLayoutOf(context, pluginDir, version)
+
+	status := CacheStatus{
+		Version:      version,
+		PluginDir:    pluginDir,
+		CacheDir:     cache.dir,
+		ExtractedDir: cache.extractedDir,
+		Binary:       GetRootCommand(context, cache.extractedDir).Path,
+	}
+	if _, err := os.Stat(cache.successFile); err == nil {
+		status.SuccessExists = true
+	}
+	if info, err := os.Stat(status.Binary); err == nil && !info.IsDir() {
+		status.BinaryExists = true
+	}
+	if provenance := readProvenance(cache.provenanceFile); provenance.Version != "" {
+		status.Provenance = &provenance
+	}
+	return status, nil
+}
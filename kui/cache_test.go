@@ -0,0 +1,85 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestCacheStatusWhenEmpty() {
+	defer suite.isolate("http://127.0.0.1:1/")()
+
+	status, err := suite.cmd.CacheStatus(suite.pluginContext)
+	suite.Require().Nil(err)
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	suite.Equal(suite.version, status.Version)
+	suite.Equal(pluginDir, status.PluginDir)
+	suite.Equal(filepath.Join(pluginDir, "cache-"+suite.version), status.CacheDir)
+	suite.Equal(filepath.Join(status.CacheDir, "extract"), status.ExtractedDir)
+	suite.False(status.SuccessExists)
+	suite.False(status.BinaryExists)
+	suite.Nil(status.Provenance)
+}
+
+func (suite *KaskTestSuite) TestCacheStatusWhenPopulated() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	command, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+
+	status, err := suite.cmd.CacheStatus(suite.pluginContext)
+	suite.Require().Nil(err)
+	suite.Equal(command.Path, status.Binary)
+	suite.True(status.SuccessExists)
+	suite.True(status.BinaryExists)
+	suite.Require().NotNil(status.Provenance)
+	suite.Equal(suite.version, status.Provenance.Version)
+	suite.Equal(PlatformKey(), status.Provenance.Platform)
+}
+
+func (suite *KaskTestSuite) TestSeparateStateDir() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	stateDir, err := ioutil.TempDir(suite.SaveDir, "state")
+	suite.Require().Nil(err)
+	defer setenv("KASK_STATE_DIR", stateDir)()
+
+	cmd, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.FileExists(cmd.Path)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	cacheDir := filepath.Join(pluginDir, "cache-1.2.3")
+	suite.FileExists(filepath.Join(stateDir, "cache-1.2.3", "success"))
+	suite.Equal("1.2.3", readProvenance(filepath.Join(stateDir, "cache-1.2.3", "provenance.json")).Version)
+	for _, name := range []string{"success", "provenance.json"} {
+		_, err := os.Stat(filepath.Join(cacheDir, name))
+		suite.True(os.IsNotExist(err), "%s belongs in the state directory", name)
+	}
+
+	// the extract, now as good as read-only, is run as is
+	before, err := ioutil.ReadDir(cacheDir)
+	suite.Require().Nil(err)
+	cmd, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.True(suite.cmd.result.CacheUsed)
+	suite.FileExists(cmd.Path)
+	after, err := ioutil.ReadDir(cacheDir)
+	suite.Require().Nil(err)
+	suite.Equal(len(before), len(after))
+	for idx := range before {
+		suite.Equal(before[idx].Name(), after[idx].Name())
+		suite.Equal(before[idx].ModTime(), after[idx].ModTime())
+	}
+
+	status, err := suite.cmd.CacheStatusOf(suite.pluginContext, "1.2.3")
+	suite.Require().Nil(err)
+	suite.True(status.SuccessExists)
+	suite.Require().NotNil(status.Provenance)
+	suite.Equal("1.2.3", status.Provenance.Version)
+}
@@ -0,0 +1,201 @@
+package kui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kui-shell/kask/i18n"
+)
+
+var agePattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([a-zA-Z]+)$`)
+
+var ageUnits = map[string]time.Duration{
+	"s":       time.Second,
+	"sec":     time.Second,
+	"secs":    time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hrs":     time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       24 * time.Hour,
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+	"w":       7 * 24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+}
+
+// parseAge parses a duration, either as Go writes them, e.g. "36h" or
+// "1h30m", or for humans, e.g. "30d", "2 weeks", or "1.5 days"
+func parseAge(text string) (time.Duration, error) {
+	text = strings.TrimSpace(text)
+	if age, err := time.ParseDuration(text); err == nil {
+		if age < 0 {
+			return 0, fmt.Errorf("invalid age %q: it may not be negative", text)
+		}
+		return age, nil
+	}
+
+	match := agePattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0, fmt.Errorf("invalid age %q", text)
+	}
+	unit, ok := ageUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid age %q: unknown unit %s", text, match[2])
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", text)
+	}
+	return time.Duration(value * float64(unit)), nil
+}
+
+// parseOlderThan parses the options of `cache list` and `cache clean`,
+// i.e. --older-than <age> (or --since <age>); zero means no filter
+func parseOlderThan(args []string) (time.Duration, error) {
+	var age time.Duration
+	for idx := 0; idx < len(args); idx++ {
+		value := ""
+		switch arg := args[idx]; {
+		case (arg == "--older-than" || arg == "--since") && idx+1 < len(args):
+			value = args[idx+1]
+			idx++
+		case strings.HasPrefix(arg, "--older-than="):
+			value = strings.TrimPrefix(arg, "--older-than=")
+		case strings.HasPrefix(arg, "--since="):
+			value = strings.TrimPrefix(arg, "--since=")
+		default:
+			return 0, fmt.Errorf("unexpected argument %s", arg)
+		}
+
+		var err error
+		if age, err = parseAge(value); err != nil {
+			return 0, err
+		}
+	}
+	return age, nil
+}
+
+// olderThan keeps those of the versions fetched more than age ago; an
+// age of zero keeps them all
+func olderThan(versions []cachedVersion, age time.Duration, now time.Time) []cachedVersion {
+	if age == 0 {
+		return versions
+	}
+	var old []cachedVersion
+	for _, v := range versions {
+		if now.Sub(v.fetchedAt) > age {
+			old = append(old, v)
+		}
+	}
+	return old
+}
+
+// CacheList lists the cached versions of the Kui base, oldest first,
+// limited to those fetched more than age ago, if age is not zero
+func (component *KuiComponent) CacheList(context Context, age time.Duration) ([]cachedVersion, error) {
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return nil, fmt.Errorf("unable to locate the plugin directory: %w", err)
+	}
+	versions, err := cachedVersions(context, pluginDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return olderThan(versions, age, time.Now()), nil
+}
+
+// CacheClean removes the cached versions of the Kui base fetched more
+// than age ago; the version we would run is kept regardless. It returns
+// the versions removed, and the bytes freed.
+func (component *KuiComponent) CacheClean(context Context, age time.Duration) ([]string, int64, error) {
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to locate the plugin directory: %w", err)
+	}
+	old, err := component.CacheList(context, age)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	shared := filepath.Join(pluginDir, "cache")
+	before := diskUsage(shared)
+	var freed int64
+	var removed []string
+	active := component.DistVersion(context)
+	for _, v := range old {
+		if v.version == active {
+			continue
+		}
+		size := diskUsage(v.dir)
+		if err := os.RemoveAll(v.dir); err != nil {
+			return removed, freed, err
+		}
+		os.RemoveAll(cacheLayoutOf(context, pluginDir, v.version).stateDir)
+		removed = append(removed, v.version)
+		freed += size
+	}
+	if len(removed) > 0 {
+		removeUnusedBlobs(context, pluginDir)
+	}
+	return removed, freed + before - diskUsage(shared), nil
+}
+
+func (component *KuiComponent) printCacheList(context Context, args []string) error {
+	age, err := parseOlderThan(args)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	versions, err := component.CacheList(context, age)
+	if err != nil {
+		return err
+	}
+
+	active := component.DistVersion(context)
+	locale := i18n.CurrentLocale()
+	for _, v := range versions {
+		marker := ""
+		if v.version == active {
+			marker = " " + yellow("(active)")
+		}
+		fmt.Printf("%v\t%s\t%s%s\n", blue(v.version), v.fetchedAt.Format(time.RFC3339), FormatBytes(locale, diskUsage(v.dir)), marker)
+	}
+	return nil
+}
+
+func (component *KuiComponent) cleanCache(context Context, args []string) error {
+	age, err := parseOlderThan(args)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	if age == 0 {
+		return newErrorf(UsageError, "usage: kask cache clean --older-than <age>, e.g. --older-than 30d")
+	}
+
+	removed, freed, err := component.CacheClean(context, age)
+	for _, version := range removed {
+		fmt.Printf("Removed Kui base %s\n", version)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Freed %s\n", FormatBytes(i18n.CurrentLocale(), freed))
+	return nil
+}
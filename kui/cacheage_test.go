@@ -0,0 +1,77 @@
+package kui
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (suite *KaskTestSuite) TestParseAge() {
+	day := 24 * time.Hour
+	for text, expected := range map[string]time.Duration{
+		"36h": 36 * time.Hour, "1h30m": 90 * time.Minute, "30d": 30 * day, "2 weeks": 14 * day,
+		"1.5 days": 36 * time.Hour, "1W": 7 * day, "45 minutes": 45 * time.Minute,
+	} {
+		age, err := parseAge(text)
+		suite.Nil(err, text)
+		suite.Equal(expected, age, text)
+	}
+	for _, text := range []string{"", "soon", "3 fortnights", "-1h"} {
+		_, err := parseAge(text)
+		suite.NotNil(err, text)
+	}
+}
+
+func (suite *KaskTestSuite) TestCacheOlderThan() {
+	suite.skipUnlessLinux()
+	defer suite.isolate("http://127.0.0.1:0")()
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+
+	now := time.Now()
+	day := 24 * time.Hour
+	suite.fakeCachedVersion(pluginDir, suite.version, now.Add(-90*day), "")
+	suite.fakeCachedVersion(pluginDir, "1.0.0", now.Add(-60*day), "a")
+	suite.fakeCachedVersion(pluginDir, "2.0.0", now.Add(-40*day), "")
+	suite.fakeCachedVersion(pluginDir, "3.0.0", now.Add(-2*day), "b")
+
+	// a cache without provenance is as old as its directory
+	suite.fakeCachedVersion(pluginDir, "4.0.0", time.Time{}, "")
+	unknown := filepath.Join(pluginDir, "cache-4.0.0")
+	suite.Require().Nil(os.Remove(filepath.Join(unknown, "provenance.json")))
+	suite.Require().Nil(os.Chtimes(unknown, now.Add(-45*day), now.Add(-45*day)))
+
+	listed, err := suite.cmd.CacheList(suite.pluginContext, 30*day)
+	suite.Require().Nil(err)
+	var versions []string
+	for _, v := range listed {
+		versions = append(versions, v.version)
+	}
+	suite.Equal([]string{suite.version, "1.0.0", "4.0.0", "2.0.0"}, versions)
+
+	all, err := suite.cmd.CacheList(suite.pluginContext, 0)
+	suite.Nil(err)
+	suite.Len(all, 5)
+
+	output := suite.captureStdout(func() {
+		suite.Nil(suite.cmd.Run(*suite.pluginContext, []string{"kask", "cache", "clean", "--older-than", "30d"}))
+	})
+	suite.Contains(output, "Removed Kui base 1.0.0")
+	suite.Contains(output, "Removed Kui base 2.0.0")
+	suite.Contains(output, "Removed Kui base 4.0.0")
+
+	suite.DirExists(filepath.Join(pluginDir, "cache-"+suite.version), "the active version should never go")
+	suite.DirExists(filepath.Join(pluginDir, "cache-3.0.0"), "a recent version should stay")
+	for _, version := range []string{"1.0.0", "2.0.0", "4.0.0"} {
+		_, err = os.Stat(filepath.Join(pluginDir, "cache-"+version))
+		suite.True(os.IsNotExist(err), version)
+	}
+	_, err = os.Stat(filepath.Join(pluginDir, "cache", "blobs", "a"))
+	suite.True(os.IsNotExist(err), "a blob no version uses should go")
+	suite.DirExists(filepath.Join(pluginDir, "cache", "blobs", "b"))
+}
+
+func (suite *KaskTestSuite) TestCacheCleanUsage() {
+	suite.Equal(ExitUsage, ExitCode(suite.cmd.Run(*suite.pluginContext, []string{"kask", "cache", "clean"})))
+	suite.Equal(ExitUsage, ExitCode(suite.cmd.Run(*suite.pluginContext, []string{"kask", "cache", "clean", "--older-than", "a while"})))
+	suite.Equal(ExitUsage, ExitCode(suite.cmd.Run(*suite.pluginContext, []string{"kask", "cache", "list", "--newest"})))
+}
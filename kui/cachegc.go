@@ -0,0 +1,221 @@
+package kui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kui-shell/kask/i18n"
+)
+
+var sizePattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([a-zA-Z]*)$`)
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// parseSize parses a size for humans, e.g. "500MB", "1.5 GB", or
+// "2GiB"; as in FormatBytes, KB and friends are powers of 1000
+func parseSize(text string) (int64, error) {
+	match := sizePattern.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+	unit, ok := sizeUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %s", text, match[2])
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+	return int64(value * unit), nil
+}
+
+// diskUsage returns the total size of the files beneath dir, not
+// following symlinks
+func diskUsage(dir string) int64 {
+	var total int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// cachedVersion is one cache-<version> directory
+type cachedVersion struct {
+	version   string
+	dir       string
+	fetchedAt time.Time
+}
+
+// cachedVersions lists the cached versions, oldest first; the age of
+// one is when it was fetched, else when its directory last changed
+func cachedVersions(context Context, pluginDir string) ([]cachedVersion, error) {
+	entries, err := ioutil.ReadDir(pluginDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []cachedVersion
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "cache-") {
+			continue
+		}
+		version := strings.TrimPrefix(entry.Name(), "cache-")
+		cache := cacheLayoutOf(context, pluginDir, version)
+		fetchedAt := readProvenance(cache.provenanceFile).FetchedAt
+		if fetchedAt.IsZero() {
+			fetchedAt = entry.ModTime()
+		}
+		versions = append(versions, cachedVersion{version, cache.dir, fetchedAt})
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i].fetchedAt.Before(versions[j].fetchedAt) })
+	return versions, nil
+}
+
+// removeUnusedBlobs removes the blobs that no cached version links to,
+// returning the bytes they took
+func removeUnusedBlobs(context Context, pluginDir string) int64 {
+	blobsDir := filepath.Join(pluginDir, "cache", "blobs")
+	entries, err := ioutil.ReadDir(blobsDir)
+	if err != nil {
+		return 0
+	}
+
+	used := map[string]bool{}
+	if versions, err := cachedVersions(context, pluginDir); err == nil {
+		for _, v := range versions {
+			if target, err := filepath.EvalSymlinks(cacheLayoutOf(context, pluginDir, v.version).extractedDir); err == nil {
+				used[filepath.Dir(target)] = true
+			}
+		}
+	}
+
+	var freed int64
+	for _, entry := range entries {
+		dir := filepath.Join(blobsDir, entry.Name())
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil && !used[resolved] {
+			size := diskUsage(dir)
+			if os.RemoveAll(dir) == nil {
+				freed += size
+			}
+		}
+	}
+	return freed
+}
+
+// CacheGC removes the oldest cached versions of the Kui base until the
+// cache takes at most maxSize bytes; the version we would run is kept
+// regardless. It returns the versions removed, and the bytes freed.
+func (component *KuiComponent) CacheGC(context Context, maxSize int64) ([]string, int64, error) {
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to locate the plugin directory: %w", err)
+	}
+	versions, err := cachedVersions(context, pluginDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+
+	// the usage of the cache, walked once, and then less what we remove
+	usage := diskUsage(filepath.Join(pluginDir, "cache"))
+	for _, v := range versions {
+		usage += diskUsage(v.dir)
+	}
+
+	active := component.DistVersion(context)
+	var removed []string
+	var freed int64
+	for idx := 0; idx < len(versions) && usage-freed > maxSize; {
+		if versions[idx].version == active {
+			idx++
+			continue
+		}
+		size := diskUsage(versions[idx].dir)
+		if err := os.RemoveAll(versions[idx].dir); err != nil {
+			return removed, freed, err
+		}
+		freed += size
+		os.RemoveAll(cacheLayoutOf(context, pluginDir, versions[idx].version).stateDir)
+		removed = append(removed, versions[idx].version)
+		versions = append(versions[:idx], versions[idx+1:]...)
+		freed += removeUnusedBlobs(context, pluginDir)
+	}
+	return removed, freed, nil
+}
+
+// Cache implements `kask cache gc --max-size <size>`, `kask cache list
+// [--older-than <age>]`, `kask cache clean --older-than <age>`, and
+// `kask cache tree [--version <version>] [--json]`
+func (component *KuiComponent) Cache(context Context, args []string) error {
+	usage := newErrorf(UsageError, "usage: kask cache gc --max-size <size>, e.g. --max-size 2GB")
+	if len(args) > 0 && args[0] == "list" {
+		return component.printCacheList(context, args[1:])
+	}
+	if len(args) > 0 && args[0] == "clean" {
+		return component.cleanCache(context, args[1:])
+	}
+	if len(args) > 0 && args[0] == "tree" {
+		return component.printCacheTreeOf(context, args[1:])
+	}
+	if len(args) == 0 || args[0] != "gc" {
+		return usage
+	}
+
+	maxSize := ""
+	for idx := 1; idx < len(args); idx++ {
+		switch {
+		case args[idx] == "--max-size" && idx+1 < len(args):
+			maxSize = args[idx+1]
+			idx++
+		case strings.HasPrefix(args[idx], "--max-size="):
+			maxSize = strings.TrimPrefix(args[idx], "--max-size=")
+		default:
+			return usage
+		}
+	}
+	if maxSize == "" {
+		return usage
+	}
+	budget, err := parseSize(maxSize)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+
+	removed, freed, err := component.CacheGC(context, budget)
+	for _, version := range removed {
+		fmt.Printf("Removed Kui base %s\n", version)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Freed %s\n", FormatBytes(i18n.CurrentLocale(), freed))
+	return nil
+}
@@ -0,0 +1,65 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fakes a cached version, fetched at the given time, with a 1000 byte
+// archive, and an extract linked to the given blob, if any
+func (suite *KaskTestSuite) fakeCachedVersion(pluginDir string, version string, fetchedAt time.Time, blob string) {
+	cache := cacheLayoutOf(suite.pluginContext, pluginDir, version)
+	suite.Require().Nil(os.MkdirAll(cache.dir, 0700))
+	suite.Require().Nil(ioutil.WriteFile(cache.downloadedFile, make([]byte, 1000), 0644))
+	suite.Require().Nil(writeProvenance(cache.provenanceFile, Provenance{Version: version, FetchedAt: fetchedAt}))
+	if blob != "" {
+		extract := filepath.Join(pluginDir, "cache", "blobs", blob, "extract")
+		suite.Require().Nil(os.MkdirAll(extract, 0755))
+		suite.Require().Nil(ioutil.WriteFile(filepath.Join(extract, "kui"), make([]byte, 1000), 0644))
+		suite.Require().Nil(os.Symlink(extract, cache.extractedDir))
+	}
+}
+
+func (suite *KaskTestSuite) TestCacheGC() {
+	suite.skipUnlessLinux()
+	defer suite.isolate("http://127.0.0.1:0")()
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+
+	now := time.Now()
+	suite.fakeCachedVersion(pluginDir, suite.version, now.Add(-time.Hour), "")
+	suite.fakeCachedVersion(pluginDir, "1.0.0", now.Add(-3*time.Minute), "a")
+	suite.fakeCachedVersion(pluginDir, "2.0.0", now.Add(-2*time.Minute), "b")
+	suite.fakeCachedVersion(pluginDir, "3.0.0", now.Add(-time.Minute), "")
+	suite.Require().Nil(os.Symlink(filepath.Join(pluginDir, "cache", "blobs", "b", "extract"), filepath.Join(pluginDir, "cache-3.0.0", "extract")))
+
+	// some 6000 bytes in all: four archives, and two blobs
+	expectedFreed := diskUsage(filepath.Join(pluginDir, "cache-1.0.0")) + diskUsage(filepath.Join(pluginDir, "cache-2.0.0")) +
+		diskUsage(filepath.Join(pluginDir, "cache", "blobs", "a"))
+	removed, freed, err := suite.cmd.CacheGC(suite.pluginContext, 3500)
+	suite.Nil(err)
+	suite.Equal([]string{"1.0.0", "2.0.0"}, removed, "the oldest go first, but never the active version")
+	suite.Equal(expectedFreed, freed)
+
+	suite.DirExists(filepath.Join(pluginDir, "cache-"+suite.version))
+	suite.DirExists(filepath.Join(pluginDir, "cache-3.0.0"))
+	_, err = os.Stat(filepath.Join(pluginDir, "cache", "blobs", "a"))
+	suite.True(os.IsNotExist(err), "a blob no version uses should go")
+	suite.DirExists(filepath.Join(pluginDir, "cache", "blobs", "b"), "a blob still in use should stay")
+}
+
+func (suite *KaskTestSuite) TestCacheGCUsage() {
+	suite.Equal(ExitUsage, ExitCode(suite.cmd.Run(*suite.pluginContext, []string{"kask", "cache", "gc"})))
+	suite.Equal(ExitUsage, ExitCode(suite.cmd.Run(*suite.pluginContext, []string{"kask", "cache", "gc", "--max-size", "lots"})))
+}
+
+func (suite *KaskTestSuite) TestParseSize() {
+	for text, expected := range map[string]int64{"2GB": 2e9, "1.5 gb": 1.5e9, "500MB": 5e8, "2GiB": 2 << 30, "1024": 1024} {
+		size, err := parseSize(text)
+		suite.Nil(err, text)
+		suite.Equal(expected, size, text)
+	}
+	_, err := parseSize("2 parsecs")
+	suite.NotNil(err)
+}
@@ -0,0 +1,156 @@
+package kui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kui-shell/kask/i18n"
+)
+
+// how deep beneath the extract `cache tree` describes
+const treeDepth = 2
+
+// TreeEntry is one file, directory, or symlink of an extract
+type TreeEntry struct {
+	Name     string      `json:"name"`
+	Dir      bool        `json:"dir,omitempty"`
+	Size     int64       `json:"size"`
+	Link     string      `json:"link,omitempty"`
+	Children []TreeEntry `json:"children,omitempty"`
+}
+
+// CacheTree summarizes the extract of a cached Kui base
+type CacheTree struct {
+	Version           string      `json:"version"`
+	ExtractedDir      string      `json:"extractedDir"`
+	ResolvedDir       string      `json:"resolvedDir"`
+	RootCommand       string      `json:"rootCommand"`
+	RootCommandExists bool        `json:"rootCommandExists"`
+	Entries           []TreeEntry `json:"entries"`
+}
+
+// treeEntries describes the entries of dir, and of its directories,
+// down to the given depth; sizes are those of the whole subtree
+func treeEntries(dir string, depth int) ([]TreeEntry, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []TreeEntry{}
+	for _, info := range infos {
+		path := filepath.Join(dir, info.Name())
+		entry := TreeEntry{Name: info.Name()}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if entry.Link, err = os.Readlink(path); err != nil {
+				return nil, err
+			}
+		case info.IsDir():
+			entry.Dir = true
+			entry.Size = diskUsage(path)
+			if depth > 1 {
+				if entry.Children, err = treeEntries(path, depth-1); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// CacheTreeOf describes what was extracted of the given version
+func (component *KuiComponent) CacheTreeOf(context Context, version string) (CacheTree, error) {
+	status, err := component.CacheStatusOf(context, version)
+	if err != nil {
+		return CacheTree{}, err
+	}
+
+	// the extract may be a symlink, e.g. to a shared blob
+	resolved, err := filepath.EvalSymlinks(status.ExtractedDir)
+	if err != nil {
+		return CacheTree{}, newErrorf(ExtractError, "Kui base %s has not been extracted; try `kask prefetch`: %v", version, err)
+	}
+
+	tree := CacheTree{
+		Version:           version,
+		ExtractedDir:      status.ExtractedDir,
+		ResolvedDir:       resolved,
+		RootCommand:       status.Binary,
+		RootCommandExists: status.BinaryExists,
+	}
+	if rel, err := filepath.Rel(status.ExtractedDir, status.Binary); err == nil {
+		tree.RootCommand = filepath.ToSlash(rel)
+	}
+	if tree.Entries, err = treeEntries(resolved, treeDepth); err != nil {
+		return CacheTree{}, newError(ExtractError, err)
+	}
+	return tree, nil
+}
+
+func printTreeEntries(out io.Writer, entries []TreeEntry, indent string) {
+	locale := i18n.CurrentLocale()
+	for _, entry := range entries {
+		switch {
+		case entry.Link != "":
+			fmt.Fprintf(out, "%s%s -> %s\n", indent, entry.Name, entry.Link)
+		case entry.Dir:
+			fmt.Fprintf(out, "%s%v\t%v\n", indent, blue(entry.Name+"/"), gray(FormatBytes(locale, entry.Size)))
+			printTreeEntries(out, entry.Children, indent+"  ")
+		default:
+			fmt.Fprintf(out, "%s%s\t%v\n", indent, entry.Name, gray(FormatBytes(locale, entry.Size)))
+		}
+	}
+}
+
+func printCacheTree(out io.Writer, tree CacheTree, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tree)
+	}
+
+	fmt.Fprintf(out, "Kui base %s, extracted in %s\n", tree.Version, tree.ResolvedDir)
+	printTreeEntries(out, tree.Entries, "  ")
+
+	status := "found"
+	if !tree.RootCommandExists {
+		status = "missing"
+	}
+	fmt.Fprintf(out, "Root command: %s\t%v\n", tree.RootCommand, gray(status))
+	return nil
+}
+
+// printCacheTreeOf implements `kask cache tree [--version X] [--json]`
+func (component *KuiComponent) printCacheTreeOf(context Context, args []string) error {
+	usage := newErrorf(UsageError, "usage: kask cache tree [--version <version>] [--json]")
+	version := component.DistVersion(context)
+	asJSON := false
+	for idx := 0; idx < len(args); idx++ {
+		switch arg := args[idx]; {
+		case arg == "--json":
+			asJSON = true
+		case arg == "--version" && idx+1 < len(args):
+			version = args[idx+1]
+			idx++
+		case strings.HasPrefix(arg, "--version="):
+			version = strings.TrimPrefix(arg, "--version=")
+		default:
+			return usage
+		}
+	}
+
+	tree, err := component.CacheTreeOf(context, version)
+	if err != nil {
+		return err
+	}
+	return printCacheTree(os.Stdout, tree, asJSON)
+}
@@ -0,0 +1,58 @@
+package kui
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestCacheTree() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeZip(
+		fakeEntry{filepath.ToSlash(rootCommandPath(suite.pluginContext, PlatformKey())), fakeKuiScript, 0755},
+		fakeEntry{"Kui-base-linux-x64/resources/app.asar", "0123456789", 0644},
+		fakeEntry{"Kui-base-linux-x64/LICENSE", "license", 0644},
+	))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+
+	tree, err := suite.cmd.CacheTreeOf(suite.pluginContext, suite.version)
+	suite.Require().Nil(err)
+	suite.Equal("Kui-base-linux-x64/Kui", tree.RootCommand)
+	suite.True(tree.RootCommandExists)
+
+	suite.Require().Len(tree.Entries, 1)
+	root := tree.Entries[0]
+	suite.Equal("Kui-base-linux-x64", root.Name)
+	suite.True(root.Dir)
+	suite.Equal(int64(len(fakeKuiScript)+len("0123456789")+len("license")), root.Size)
+
+	var names []string
+	for _, child := range root.Children {
+		names = append(names, child.Name)
+	}
+	suite.Equal([]string{"Kui", "LICENSE", "resources"}, names)
+	suite.Equal(int64(10), root.Children[2].Size)
+	suite.Empty(root.Children[2].Children, "the tree should stop at two levels")
+
+	var out bytes.Buffer
+	suite.Nil(printCacheTree(&out, tree, true))
+	var decoded CacheTree
+	suite.Nil(json.Unmarshal(out.Bytes(), &decoded))
+	suite.Equal(tree, decoded)
+
+	out.Reset()
+	suite.Nil(printCacheTree(&out, tree, false))
+	suite.Contains(out.String(), "resources/")
+	suite.Contains(out.String(), "Root command: Kui-base-linux-x64/Kui")
+}
+
+func (suite *KaskTestSuite) TestCacheTreeNotExtracted() {
+	defer suite.isolate(unreachableURL())()
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "cache", "tree", "--version", "0.0.1"})
+	suite.Equal(ExitExtract, ExitCode(err))
+	suite.Equal(ExitUsage, ExitCode(suite.cmd.Run(*suite.pluginContext, []string{"kask", "cache", "tree", "--version"})))
+}
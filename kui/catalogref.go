@@ -0,0 +1,82 @@
+package kui
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// a catalog ref is a commit, e.g. 3f2c1ab, or a tag or snapshot name,
+// e.g. 2024-06-01 or snapshots/v2; as with git, no ".." and no
+// leading "-", lest it be taken for a flag
+var catalogRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// the longest ref we accept
+const maxCatalogRefLength = 128
+
+// catalogRef returns the snapshot of the plugin catalog to which
+// KASK_CATALOG_REF pins installs, if any
+func catalogRef(context Context) (string, error) {
+	ref := strings.TrimSpace(getenv(context, "KASK_CATALOG_REF"))
+	if ref == "" {
+		return "", nil
+	}
+	if len(ref) > maxCatalogRefLength || !catalogRefPattern.MatchString(ref) ||
+		strings.Contains(ref, "..") || strings.Contains(ref, "//") || strings.HasSuffix(ref, "/") {
+		return "", fmt.Errorf("invalid KASK_CATALOG_REF %q; use a commit, tag, or snapshot name, e.g. 3f2c1ab or 2024-06-01", ref)
+	}
+	return ref, nil
+}
+
+// catalogSnapshot returns the plugin versions of the snapshot of the
+// catalog of the given ref, which is <ref>.json beneath the url, or
+// path, of KASK_CATALOG_SNAPSHOTS, e.g.
+//
+//	{"plugins": {"plugin-foo": "1.2.3", "@kui-shell/plugin-s3": "9.1.0"}}
+func catalogSnapshot(context Context, ref string) (map[string]string, error) {
+	snapshots := getenv(context, "KASK_CATALOG_SNAPSHOTS")
+	if snapshots == "" {
+		return nil, newErrorf(UsageError, "KASK_CATALOG_REF %s needs KASK_CATALOG_SNAPSHOTS, the location of the catalog snapshots", ref)
+	}
+	location := strings.TrimSuffix(snapshots, "/") + "/" + ref + ".json"
+	body, err := readPluginManifest(context, location)
+	if err != nil {
+		return nil, newError(DownloadError, fmt.Errorf("unable to read the catalog snapshot %s: %w", location, err))
+	}
+	var snapshot struct {
+		Plugins map[string]string `json:"plugins"`
+	}
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		return nil, newError(UsageError, fmt.Errorf("unable to parse the catalog snapshot %s: %v", location, err))
+	}
+	return snapshot.Plugins, nil
+}
+
+// pinToCatalogRef pins each of the given plugins from the catalog to its
+// version in the snapshot of the given ref, e.g. plugin-foo to
+// plugin-foo@1.2.3, so that installs resolve the same plugin over time.
+// Local plugins, and those given a version, are left as they are.
+func pinToCatalogRef(context Context, ref string, plugins []string) ([]string, error) {
+	if ref == "" || len(plugins) == 0 {
+		return plugins, nil
+	}
+	snapshot, err := catalogSnapshot(context, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := make([]string, 0, len(plugins))
+	for _, plugin := range plugins {
+		if isLocalPlugin(plugin) || strings.LastIndex(plugin, "@") > 0 {
+			pinned = append(pinned, plugin)
+			continue
+		}
+		version, ok := snapshot[plugin]
+		if !ok || version == "" {
+			return nil, newErrorf(UsageError, "%s is not in the snapshot %s of the catalog", plugin, ref)
+		}
+		pinned = append(pinned, plugin+"@"+version)
+	}
+	return pinned, nil
+}
@@ -0,0 +1,52 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestCatalogRef() {
+	for _, ref := range []string{"3f2c1ab", "0123456789abcdef0123456789abcdef01234567", "2024-06-01", "snapshots/v2", "v1.2.3"} {
+		defer setenv("KASK_CATALOG_REF", ref)()
+		actual, err := catalogRef(suite.pluginContext)
+		suite.Nil(err, ref)
+		suite.Equal(ref, actual)
+	}
+	for _, ref := range []string{"--force", "a..b", "a b", "snapshots/", "a//b", "$(id)", strings.Repeat("a", 129)} {
+		defer setenv("KASK_CATALOG_REF", ref)()
+		_, err := catalogRef(suite.pluginContext)
+		suite.NotNil(err, ref)
+	}
+}
+
+func (suite *KaskTestSuite) TestCatalogRefPinsInstalls() {
+	suite.skipUnlessLinux()
+	snapshots := filepath.Join(suite.SaveDir, "snapshots")
+	suite.Require().Nil(os.MkdirAll(snapshots, 0700))
+	suite.Require().Nil(ioutil.WriteFile(filepath.Join(snapshots, "3f2c1ab.json"), []byte(`{"plugins": {"foo": "1.2.3", "@kui-shell/plugin-s3": "9.1.0"}}`), 0644))
+	defer setenv("KASK_CATALOG_SNAPSHOTS", snapshots)()
+	defer setenv("KASK_CATALOG_REF", "3f2c1ab")()
+
+	forwarded, err := suite.runFakeKui("install", "foo")
+	suite.Require().Nil(err)
+	suite.Equal([]string{"install", "foo@1.2.3"}, forwarded)
+
+	forwarded, err = suite.runFakeKui("install", "@kui-shell/plugin-s3")
+	suite.Require().Nil(err)
+	suite.Equal([]string{"install", "@kui-shell/plugin-s3@9.1.0"}, forwarded)
+
+	forwarded, err = suite.runFakeKui("install", "foo@2.0.0")
+	suite.Require().Nil(err)
+	suite.Equal([]string{"install", "foo@2.0.0"}, forwarded, "a version given is kept")
+
+	_, err = suite.runFakeKui("install", "bar")
+	suite.Equal(ExitUsage, ExitCode(err), "a plugin not in the snapshot cannot be pinned")
+}
+
+func (suite *KaskTestSuite) TestInvalidCatalogRef() {
+	defer setenv("KASK_CATALOG_REF", "--force")()
+	_, err := suite.runFakeKui("install", "foo")
+	suite.Equal(ExitUsage, ExitCode(err))
+}
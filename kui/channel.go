@@ -0,0 +1,35 @@
+package kui
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// released Kui bases, as ever
+	ChannelStable = "stable"
+	// pre-release Kui bases
+	ChannelBeta = "beta"
+)
+
+// distChannel returns the channel whose Kui bases we run, from
+// KASK_CHANNEL; by default, the stable one
+func distChannel(context Context) (string, error) {
+	channel := strings.ToLower(strings.TrimSpace(getenv(context, "KASK_CHANNEL")))
+	switch channel {
+	case "":
+		return ChannelStable, nil
+	case ChannelStable, ChannelBeta:
+		return channel, nil
+	}
+	return "", fmt.Errorf("invalid KASK_CHANNEL %q; use %s or %s", channel, ChannelStable, ChannelBeta)
+}
+
+// channelPrefix returns what precedes the version in the name of the
+// default dist host's bucket for the given channel, e.g. kui-beta-
+func channelPrefix(channel string) string {
+	if channel == ChannelStable {
+		return "kui-"
+	}
+	return "kui-" + channel + "-"
+}
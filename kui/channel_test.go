@@ -0,0 +1,87 @@
+package kui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestChannelDistLocation() {
+	// the default dist host
+	defer setenv("KUI_DIST", "")()
+	os.Unsetenv("KUI_DIST")
+
+	stable := GetDistLocation(suite.pluginContext, "1.0.0")
+	suite.Contains(stable, "/kui-1.0.0/")
+
+	defer setenv("KASK_CHANNEL", "beta")()
+	beta := GetDistLocation(suite.pluginContext, "1.0.0")
+	suite.Contains(beta, "/kui-beta-1.0.0/")
+	suite.NotEqual(stable, beta)
+
+	location, err := expandDistTemplate(suite.pluginContext, "https://mirror/{channel}/{version}/Kui{suffix}", "1.0.0", PlatformKey())
+	suite.Nil(err)
+	suite.True(strings.HasPrefix(location, "https://mirror/beta/1.0.0/"), location)
+
+	os.Setenv("KASK_CHANNEL", "Stable")
+	suite.Equal(stable, GetDistLocation(suite.pluginContext, "1.0.0"), "the channel is case-insensitive")
+}
+
+func (suite *KaskTestSuite) TestChannelLatestPointer() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stable/latest.txt":
+			w.Write([]byte("1.2.3"))
+		case "/beta/latest.txt":
+			w.Write([]byte("1.3.0-beta.1"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_LATEST", server.URL+"/{channel}/latest.txt")()
+
+	suite.Equal("1.2.3", suite.cmd.DistVersion(suite.pluginContext))
+	defer setenv("KASK_CHANNEL", "beta")()
+	suite.Equal("1.3.0-beta.1", suite.cmd.DistVersion(suite.pluginContext))
+}
+
+func (suite *KaskTestSuite) TestChannelInvalid() {
+	defer setenv("KASK_CHANNEL", "nightly")()
+
+	_, err := distChannel(suite.pluginContext)
+	suite.NotNil(err)
+	suite.Equal(ExitUsage, ExitCode(suite.cmd.Run(*suite.pluginContext, []string{"kask", "list"})))
+	_, err = distLocation(suite.pluginContext, "1.0.0")
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestChannelSwitchRefetches() {
+	suite.skipUnlessLinux()
+	gets, notModified := 0, 0
+	server := serveWithETag(makeFakeDist(), `"v1"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.Equal(1, gets)
+
+	// the beta 1.2.3 is not the stable one we have cached
+	defer setenv("KASK_CHANNEL", "beta")()
+	cmd, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.Equal(2, gets, "switching channels should fetch that channel's Kui base")
+	suite.FileExists(cmd.Path)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	suite.Equal(ChannelBeta, readProvenance(cacheLayoutOf(suite.pluginContext, pluginDir, "1.2.3").provenanceFile).Channel)
+
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.Equal(2, gets, "the beta cache should then serve")
+}
@@ -0,0 +1,124 @@
+package kui
+
+import (
+	stdcontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrChecksumMismatch is wrapped by the ChecksumError of a dist whose
+// digest is other than expected
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// a checksum file holds a digest and perhaps a file name; anything
+// bigger than this is not one
+const maxChecksumSize = 4096
+
+// sha256File returns the hex-encoded SHA-256 digest of the given file
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// parseChecksum accepts either a bare digest or the "<digest>  <file>"
+// format written by sha256sum
+func parseChecksum(text string) (string, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum")
+	}
+	digest := strings.ToLower(fields[0])
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+		return "", fmt.Errorf("malformed checksum %q", fields[0])
+	}
+	return digest, nil
+}
+
+// fetchChecksum fetches the "<url>.sha256" published alongside the
+// dist. Not every host publishes one, so a 404 yields "", nil.
+func fetchChecksum(ctx stdcontext.Context, context Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url+".sha256", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := doDistRequest(context, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("unexpected response fetching checksum for %s: %s", url, resp.Status)
+	}
+
+	decoded, _, err := decodedBody(resp)
+	if err != nil {
+		return "", err
+	}
+	defer decoded.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(decoded, maxChecksumSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) > maxChecksumSize {
+		return "", fmt.Errorf("checksum for %s is too large to be one", url)
+	}
+	return parseChecksum(string(body))
+}
+
+// expectedChecksum returns the digest that the dist at url must have,
+// or "" if we have no way of knowing. A digest pinned via
+// KASK_DIST_SHA256 takes precedence over the one published by the
+// host, since a compromised host could publish a matching checksum
+// for a bad archive.
+func expectedChecksum(ctx stdcontext.Context, context Context, url string) (string, error) {
+	if pinned, isPinned := lookupEnv(context, "KASK_DIST_SHA256"); isPinned {
+		digest, err := parseChecksum(pinned)
+		if err != nil {
+			return "", fmt.Errorf("invalid KASK_DIST_SHA256: %v", err)
+		}
+		return digest, nil
+	}
+	return fetchChecksum(ctx, context, url)
+}
+
+// verifyDist checks the downloaded dist against the expected checksum,
+// if there is one, returning its actual checksum. On mismatch, the
+// download is removed, so that we do not trust it on a subsequent run.
+func verifyDist(context Context, url string, downloadedFile string, expected string) (string, error) {
+	actual, err := sha256File(downloadedFile)
+	if err != nil {
+		return "", newError(ChecksumError, err)
+	}
+
+	if expected == "" {
+		context.logger().Debugf("no checksum available for %s", url)
+		return actual, nil
+	}
+	if actual != expected {
+		os.Remove(downloadedFile)
+		return "", newError(ChecksumError, fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, url, expected, actual))
+	}
+
+	context.logger().Debugf("verified checksum %s", actual)
+	return actual, nil
+}
@@ -0,0 +1,167 @@
+package kui
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+)
+
+func sha256Hex(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// a dist host that also publishes the given checksum, counting requests for it
+func serveDistWithChecksum(body []byte, checksum string, checksumRequests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			*checksumRequests++
+			w.Write([]byte(checksum + "  Kui" + GetDistOSSuffix(nil) + "\n"))
+			return
+		}
+		w.Write(body)
+	}))
+}
+
+func (suite *KaskTestSuite) TestRemoteChecksum() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksumRequests := 0
+	server := serveDistWithChecksum(dist, sha256Hex(dist), &checksumRequests)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Nil(err)
+	suite.Equal(1, checksumRequests)
+}
+
+func (suite *KaskTestSuite) TestRemoteChecksumMismatch() {
+	checksumRequests := 0
+	server := serveDistWithChecksum(makeFakeDist(), sha256Hex([]byte("something else")), &checksumRequests)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(ChecksumError, KindOf(err))
+	suite.Equal(ExitDownload, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestPinnedChecksum() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksumRequests := 0
+	server := serveDistWithChecksum(dist, sha256Hex([]byte("a compromised mirror")), &checksumRequests)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_SHA256", strings.ToUpper(sha256Hex(dist)))()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Nil(err)
+	suite.Equal(0, checksumRequests, "a pinned checksum should not be fetched")
+}
+
+func (suite *KaskTestSuite) TestPinnedChecksumMismatch() {
+	dist := makeFakeDist()
+	checksumRequests := 0
+	server := serveDistWithChecksum(dist, sha256Hex(dist), &checksumRequests)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_SHA256", sha256Hex([]byte("something else")))()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(ChecksumError, KindOf(err))
+	suite.Contains(err.Error(), "checksum mismatch")
+	suite.Equal(0, checksumRequests)
+}
+
+func (suite *KaskTestSuite) TestOversizedRemoteChecksum() {
+	// a valid digest, buried in more than any checksum file holds
+	dist := makeFakeDist()
+	checksumRequests := 0
+	server := serveDistWithChecksum(dist, sha256Hex(dist)+"  "+strings.Repeat("x", 1<<20), &checksumRequests)
+	defer server.Close()
+
+	_, err := fetchChecksum(context.Background(), suite.pluginContext, server.URL+"/Kui"+GetDistOSSuffix(suite.pluginContext))
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "too large")
+}
+
+func (suite *KaskTestSuite) TestParseChecksum() {
+	digest := sha256Hex([]byte("x"))
+	parsed, err := parseChecksum(digest + "  file.zip\n")
+	suite.Nil(err)
+	suite.Equal(digest, parsed)
+
+	_, err = parseChecksum("not-a-digest")
+	suite.NotNil(err)
+	_, err = parseChecksum("")
+	suite.NotNil(err)
+}
+
+// a dist host whose content and published checksum may be changed
+// between requests
+func serveDistCountingGets(body *[]byte, checksum *string, gets *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			w.Write([]byte(*checksum))
+			return
+		}
+		if r.Method == "HEAD" {
+			// the store looks fine; only fetches are counted
+			return
+		}
+		*gets++
+		w.Write(*body)
+	}))
+}
+
+func (suite *KaskTestSuite) TestRefreshWithUnchangedChecksum() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksum := sha256Hex(dist)
+	gets := 0
+	server := serveDistCountingGets(&dist, &checksum, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	suite.Equal(1, gets)
+
+	_, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext, true)
+	suite.Nil(err)
+	suite.Equal(1, gets, "an unchanged checksum should short-circuit the refresh")
+}
+
+func (suite *KaskTestSuite) TestRefreshWithChangedChecksum() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksum := sha256Hex(dist)
+	gets := 0
+	server := serveDistCountingGets(&dist, &checksum, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+
+	dist = makeZip(
+		fakeEntry{filepath.ToSlash(rootCommandPath(suite.pluginContext, PlatformKey())), fakeKuiScript, 0755},
+		fakeEntry{"Kui-base-linux-x64/new", "", 0644},
+	)
+	checksum = sha256Hex(dist)
+
+	_, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext, true)
+	suite.Nil(err)
+	suite.Equal(2, gets)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	provenance := readProvenance(filepath.Join(pluginDir, "cache-"+suite.version, "provenance.json"))
+	suite.Equal(checksum, provenance.SHA256)
+	suite.Equal(PlatformKey(), provenance.Platform)
+}
@@ -0,0 +1,60 @@
+package kui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// kubectl-foo-bar runs in the command context "foo-bar"
+	ContextModeFull = "full"
+	// kubectl-foo-bar runs "bar" in the command context "foo"
+	ContextModeFirst = "first"
+)
+
+var kubectlPrefix = regexp.MustCompile("^kubectl-")
+
+// contextMode returns how we split a multi-segment executable name,
+// from KASK_CONTEXT_MODE; by default, the whole remainder is the
+// command context
+func contextMode(context Context) (string, error) {
+	mode := strings.ToLower(getenv(context, "KASK_CONTEXT_MODE"))
+	switch mode {
+	case "":
+		return ContextModeFull, nil
+	case ContextModeFull, ContextModeFirst:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid KASK_CONTEXT_MODE %q; use %s or %s", mode, ContextModeFull, ContextModeFirst)
+}
+
+// inferCommandContext returns the Kui command context implied by the
+// basename of our executable, e.g. "foo" for "kubectl-foo", along with
+// any subcommand that, in ContextModeFirst, the rest of the name implies
+func inferCommandContext(base string, mode string) (string, []string) {
+	if !kubectlPrefix.MatchString(base) {
+		return defaultCommandContext, nil
+	}
+
+	name := kubectlPrefix.ReplaceAllString(base, "")
+	if name == "kask" {
+		return defaultCommandContext, nil
+	}
+
+	if mode == ContextModeFirst {
+		segments := strings.Split(name, "-")
+		return segments[0], segments[1:]
+	}
+	return name, nil
+}
+
+// invocationName returns how the user invoked us, given the basename of
+// our executable, e.g. "kubectl foo bar" for "kubectl-foo-bar", as
+// kubectl would run it
+func invocationName(base string) string {
+	if !kubectlPrefix.MatchString(base) {
+		return base
+	}
+	return "kubectl " + strings.Replace(kubectlPrefix.ReplaceAllString(base, ""), "-", " ", -1)
+}
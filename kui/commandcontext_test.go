@@ -0,0 +1,88 @@
+package kui
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestContextModeFull() {
+	context, subcommand := inferCommandContext("kubectl-foo-bar", ContextModeFull)
+	suite.Equal("foo-bar", context)
+	suite.Empty(subcommand)
+}
+
+func (suite *KaskTestSuite) TestContextModeFirst() {
+	context, subcommand := inferCommandContext("kubectl-foo-bar", ContextModeFirst)
+	suite.Equal("foo", context)
+	suite.Equal([]string{"bar"}, subcommand)
+
+	context, subcommand = inferCommandContext("kubectl-foo", ContextModeFirst)
+	suite.Equal("foo", context)
+	suite.Empty(subcommand)
+}
+
+func (suite *KaskTestSuite) TestContextModeDefault() {
+	mode, err := contextMode(suite.pluginContext)
+	suite.Nil(err)
+	suite.Equal(ContextModeFull, mode)
+
+	context, _ := inferCommandContext("kubectl-kask", mode)
+	suite.Equal(defaultCommandContext, context)
+	context, _ = inferCommandContext("kask", mode)
+	suite.Equal(defaultCommandContext, context)
+
+	defer setenv("KASK_CONTEXT_MODE", "middle")()
+	_, err = contextMode(suite.pluginContext)
+	suite.NotNil(err)
+	suite.Equal(UsageError, KindOf(suite.cmd.Run(*suite.pluginContext, []string{"kubectl-foo-bar", "list"})))
+}
+
+func (suite *KaskTestSuite) TestContextModeFirstReachesKui() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_CONTEXT_MODE", "first")()
+
+	args := filepath.Join(suite.SaveDir, "fake-kui-args")
+	env := filepath.Join(suite.SaveDir, "fake-kui-env")
+	defer setenv("FAKE_KUI_ARGS", args)()
+	defer setenv("FAKE_KUI_ENV", env)()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kubectl-foo-bar", "list"})
+	suite.Require().Nil(err)
+
+	forwarded, _ := ioutil.ReadFile(args)
+	suite.Equal("bar\nlist\n", string(forwarded))
+	environment, _ := ioutil.ReadFile(env)
+	suite.Contains(strings.Split(string(environment), "\n"), "KUI_COMMAND_CONTEXT=foo")
+}
+
+func (suite *KaskTestSuite) TestHelpReflectsInvocation() {
+	output := suite.captureStdout(func() {
+		suite.Nil(suite.cmd.Run(*suite.pluginContext, []string{"/usr/local/bin/kubectl-foo", "-h"}))
+	})
+	suite.Contains(output, "Usage: "+cyan("kubectl foo <command>"))
+	suite.Contains(output, "Kui command context "+cyan("foo"))
+	suite.NotContains(output, "kask <command>")
+
+	defer setenv("KASK_CONTEXT_MODE", "first")()
+	output = suite.captureStdout(func() {
+		suite.Nil(suite.cmd.Run(*suite.pluginContext, []string{"kubectl-foo-bar", "--help"}))
+	})
+	suite.Contains(output, "Usage: "+cyan("kubectl foo bar <command>"))
+	suite.Contains(output, "Kui command context "+cyan("foo bar"))
+
+	output = suite.captureStdout(func() {
+		suite.Nil(suite.cmd.Run(*suite.pluginContext, []string{"kask", "--help"}))
+	})
+	suite.Contains(output, "Usage: "+cyan("kask <command>"))
+	suite.NotContains(output, "Kui command context")
+}
+
+func (suite *KaskTestSuite) TestInvocationName() {
+	suite.Equal("kask", invocationName("kask"))
+	suite.Equal("kubectl foo", invocationName("kubectl-foo"))
+	suite.Equal("kubectl foo bar", invocationName("kubectl-foo-bar"))
+}
@@ -0,0 +1,140 @@
+package kui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// the columns of a table that a headless Kui prints, which are
+// separated by at least two spaces, since a command may contain one
+var tableColumns = regexp.MustCompile(`\s{2,}|\t`)
+
+// parseCommands parses the output of a headless Kui commands: a table
+// of command and description, perhaps beneath a header and perhaps
+// colored, or else a json array of commands, whose fields may carry
+// Kui's own names (the command, and its docs). A plugin that offers no
+// commands may say so in words, rather than with an empty list.
+func parseCommands(output []byte) []Command {
+	text := strings.TrimSpace(ansiEscape.ReplaceAllString(string(output), ""))
+	commands := []Command{}
+
+	if strings.HasPrefix(text, "[") {
+		var listed []struct {
+			Name        string `json:"name"`
+			Command     string `json:"command"`
+			Alias       string `json:"alias"`
+			Description string `json:"description"`
+			Docs        string `json:"docs"`
+			Usage       string `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(text), &listed); err == nil {
+			for _, command := range listed {
+				if command.Name == "" {
+					command.Name = command.Command
+				}
+				if command.Description == "" {
+					command.Description = command.Docs
+				}
+				if command.Name != "" {
+					commands = append(commands, Command{command.Name, command.Alias, command.Description, command.Usage})
+				}
+			}
+			return commands
+		}
+	}
+
+	header := true
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(strings.ToLower(line), "no commands") {
+			continue
+		}
+		columns := tableColumns.Split(line, 2)
+		if header {
+			header = false
+			switch strings.ToLower(columns[0]) {
+			case "command", "commands", "name":
+				continue
+			}
+		}
+		command := Command{Name: columns[0]}
+		if len(columns) > 1 {
+			command.Description = columns[1]
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// listCommands asks the given Kui, headlessly, which commands the given
+// plugin offers
+func listCommands(cmd *exec.Cmd, plugin string) ([]Command, error) {
+	list := cloneCommand(cmd)
+	list.Args = append(list.Args, "commands")
+	if plugin != "" {
+		list.Args = append(list.Args, plugin)
+	}
+	list.Env = append(list.Env, "KUI_HEADLESS=true")
+	var stdout bytes.Buffer
+	list.Stdout = &stdout
+	if err := list.Run(); err != nil {
+		return nil, newError(ChildError, fmt.Errorf("unable to list the commands of %s: %w", plugin, err))
+	}
+	return parseCommands(stdout.Bytes()), nil
+}
+
+// ListCommands returns the commands that the given plugin offers,
+// first fetching the Kui base if need be
+func (component *KuiComponent) ListCommands(context Context, plugin string) ([]Command, error) {
+	cmd, err := component.DownloadDistIfNecessary(context, false)
+	if err != nil {
+		return nil, err
+	}
+	return listCommands(cmd, plugin)
+}
+
+// parseCommandsArgs parses the arguments of `kask commands [plugin]
+// [--json]`, returning false for any others, which are Kui's to
+// interpret
+func parseCommandsArgs(args []string) (string, bool, bool) {
+	plugin := ""
+	asJSON := false
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			asJSON = true
+		case plugin == "" && !strings.HasPrefix(arg, "-"):
+			plugin = arg
+		default:
+			return "", false, false
+		}
+	}
+	return plugin, asJSON, true
+}
+
+// printCommands renders the given commands in the given format, by
+// default as a table
+func printCommands(out io.Writer, plugin string, commands []Command, format string) error {
+	return printFormatted(out, format, commands, func() error {
+		if len(commands) == 0 {
+			if plugin == "" {
+				fmt.Fprintln(out, "No commands")
+			} else {
+				fmt.Fprintf(out, "%s offers no commands\n", plugin)
+			}
+			return nil
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "%v\t%v\n", blue("COMMAND"), blue("DESCRIPTION"))
+		for _, command := range commands {
+			fmt.Fprintf(w, "%s\t%s\n", command.Name, command.Description)
+		}
+		return w.Flush()
+	})
+}
@@ -0,0 +1,96 @@
+package kui
+
+import (
+	"encoding/json"
+)
+
+// as from a headless Kui, which colors its table
+const fakeKuiCommands = "\x1b[0;34mCOMMAND\x1b[0m  \x1b[0;34mDESCRIPTION\x1b[0m\n" +
+	"\x1b[1ms3 ls\x1b[0m    List buckets\n" +
+	"\x1b[1ms3 cp\x1b[0m    Copy objects\n"
+
+func (suite *KaskTestSuite) TestParseCommands() {
+	suite.Equal([]Command{
+		{Name: "s3 ls", Description: "List buckets"},
+		{Name: "s3 cp", Description: "Copy objects"},
+	}, parseCommands([]byte(fakeKuiCommands)))
+
+	// as from a Kui that prints no header, nor color
+	suite.Equal([]Command{
+		{Name: "get", Description: "Get resources"},
+		{Name: "logs"},
+	}, parseCommands([]byte("get  Get resources\nlogs\n\n")))
+
+	// as from a Kui that lists its commands as json
+	suite.Equal([]Command{
+		{"s3 ls", "", "List buckets", "s3 ls [bucket]"},
+		{"s3 cp", "cp", "Copy objects", ""},
+	}, parseCommands([]byte(`[
+  {"command": "s3 ls", "docs": "List buckets", "usage": "s3 ls [bucket]"},
+  {"name": "s3 cp", "alias": "cp", "description": "Copy objects"}
+]`)))
+
+	// a plugin that offers none
+	suite.Empty(parseCommands([]byte("[]")))
+	suite.Empty(parseCommands([]byte("")))
+	suite.Empty(parseCommands([]byte("No commands found\n")))
+}
+
+func (suite *KaskTestSuite) TestListCommands() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("FAKE_KUI_COMMANDS", fakeKuiCommands)()
+
+	commands, err := suite.cmd.ListCommands(suite.pluginContext, "plugin-s3")
+	suite.Nil(err)
+	suite.Len(commands, 2)
+	suite.Equal("s3 ls", commands[0].Name)
+
+	var forwarded []string
+	output := suite.captureStdout(func() {
+		forwarded, err = suite.runFakeKui("commands", "plugin-s3", "--json")
+	})
+	suite.Require().Nil(err)
+	suite.Equal([]string{"commands", "plugin-s3"}, forwarded)
+	var printed []Command
+	suite.Require().Nil(json.Unmarshal([]byte(output), &printed))
+	suite.Equal(commands, printed)
+
+	output = suite.captureStdout(func() {
+		_, err = suite.runFakeKui("commands", "plugin-s3")
+	})
+	suite.Nil(err)
+	suite.Contains(output, "List buckets")
+}
+
+func (suite *KaskTestSuite) TestListCommandsOfPluginWithNone() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_COMMANDS", "[]")()
+
+	var err error
+	output := suite.captureStdout(func() {
+		_, err = suite.runFakeKui("commands", "plugin-quiet")
+	})
+	suite.Nil(err)
+	suite.Contains(output, "plugin-quiet offers no commands")
+
+	output = suite.captureStdout(func() {
+		_, err = suite.runFakeKui("commands", "plugin-quiet", "--json")
+	})
+	suite.Nil(err)
+	suite.JSONEq("[]", output)
+}
+
+func (suite *KaskTestSuite) TestParseCommandsArgs() {
+	plugin, asJSON, ok := parseCommandsArgs([]string{"plugin-s3", "--json"})
+	suite.Equal("plugin-s3", plugin)
+	suite.True(asJSON)
+	suite.True(ok)
+
+	_, _, ok = parseCommandsArgs([]string{"plugin-s3", "--ui"})
+	suite.False(ok)
+	_, _, ok = parseCommandsArgs([]string{"a", "b"})
+	suite.False(ok)
+}
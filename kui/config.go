@@ -0,0 +1,231 @@
+package kui
+
+import (
+	"bufio"
+	"bytes"
+	stdcontext "context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// extractConfigFlag removes any --config <file> or --config=<file>
+// preceding "--" from args, returning the remaining args and the file
+func extractConfigFlag(args []string) ([]string, string, error) {
+	rest := []string{}
+	config := ""
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		switch {
+		case arg == "--":
+			return append(rest, args[idx:]...), config, nil
+		case arg == "--config":
+			if idx+1 >= len(args) {
+				return nil, "", fmt.Errorf("--config requires a file")
+			}
+			config = args[idx+1]
+			idx++
+		case strings.HasPrefix(arg, "--config="):
+			config = strings.TrimPrefix(arg, "--config=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, config, nil
+}
+
+// parseConfig reads the KEY=value lines of a config file, e.g.
+//
+//	# run against our mirror
+//	KASK_DIST_URL_TEMPLATE=https://mirror.example.com/kui/{version}/Kui{suffix}
+//	KASK_INSTALL_RETRIES=2
+//
+// The keys are those of the environment variables that configure us.
+func parseConfig(contents []byte) (map[string]string, error) {
+	settings := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		idx := strings.Index(text, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		key := strings.TrimSpace(text[:idx])
+		if !strings.HasPrefix(key, "KASK_") && !strings.HasPrefix(key, "KUI_") {
+			return nil, fmt.Errorf("line %d: %s is not a kask setting", line, key)
+		}
+		settings[key] = strings.TrimSpace(text[idx+1:])
+	}
+	return settings, scanner.Err()
+}
+
+// loadConfig reads our settings from the config file named by the
+// --config flag, else by $KASK_CONFIG, else from <plugin dir>/config
+// if there is one. The settings travel with the context, rather than
+// through our environment; a setting in the environment wins over the
+// file.
+func loadConfig(context Context, flag string) (map[string]string, error) {
+	file := flag
+	if file == "" {
+		file = os.Getenv("KASK_CONFIG")
+	}
+	explicit := file != ""
+	if !explicit {
+		pluginDir, err := context.PluginDirectory()
+		if err != nil {
+			return nil, nil
+		}
+		file = filepath.Join(pluginDir, "config")
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read the config file: %v", err)
+	}
+	settings, err := parseConfig(contents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", file, err)
+	}
+	context.logger().Debugf("loaded the config file %s", file)
+	return settings, nil
+}
+
+// a Context may carry the settings of a config file, as MainContext does
+type configSource interface {
+	configSettings() map[string]string
+}
+
+// WithConfig returns a copy of the context that carries the given
+// settings, as if from a config file
+func (context MainContext) WithConfig(settings map[string]string) MainContext {
+	context.config = settings
+	return context
+}
+
+func (context MainContext) configSettings() map[string]string {
+	return context.config
+}
+
+// a Context may carry settings that win over both the environment and
+// the config file, e.g. those of our global flags, as MainContext does
+type overrideSource interface {
+	settingOverrides() map[string]string
+}
+
+// WithSetting returns a copy of the context in which the given setting
+// has the given value, whatever the environment says
+func (context MainContext) WithSetting(key string, value string) MainContext {
+	overrides := map[string]string{key: value}
+	for other, otherValue := range context.overrides {
+		if other != key {
+			overrides[other] = otherValue
+		}
+	}
+	context.overrides = overrides
+	return context
+}
+
+func (context MainContext) settingOverrides() map[string]string {
+	return context.overrides
+}
+
+// withSetting is WithSetting, for any Context that can carry one
+func withSetting(context Context, key string, value string) Context {
+	switch main := context.(type) {
+	case MainContext:
+		return main.WithSetting(key, value)
+	case *MainContext:
+		return main.WithSetting(key, value)
+	}
+	return context
+}
+
+// lookupEnv looks up one of our settings: in the context's overrides,
+// else in the environment, else in the context's config file
+func lookupEnv(context Context, key string) (string, bool) {
+	if source, ok := context.(overrideSource); ok {
+		if value, isSet := source.settingOverrides()[key]; isSet {
+			return value, true
+		}
+	}
+	if value, isSet := os.LookupEnv(key); isSet {
+		return value, true
+	}
+	if source, ok := context.(configSource); ok {
+		value, isSet := source.configSettings()[key]
+		return value, isSet
+	}
+	return "", false
+}
+
+// getenv is lookupEnv, for settings where unset and empty are the same
+func getenv(context Context, key string) string {
+	value, _ := lookupEnv(context, key)
+	return value
+}
+
+// withConfigEnvironment returns environ, in the form of os.Environ(),
+// with the context's overrides in place of what it says, and with
+// those settings of its config file that it lacks, so that a child
+// sees the settings we do
+func withConfigEnvironment(context Context, environ []string) []string {
+	var overrides map[string]string
+	if source, ok := context.(overrideSource); ok && len(source.settingOverrides()) > 0 {
+		overrides = source.settingOverrides()
+		kept := []string{}
+		for _, entry := range environ {
+			if _, overridden := overrides[strings.SplitN(entry, "=", 2)[0]]; !overridden {
+				kept = append(kept, entry)
+			}
+		}
+		environ = kept
+		for _, key := range sortedKeys(overrides) {
+			environ = append(environ, key+"="+overrides[key])
+		}
+	}
+
+	source, ok := context.(configSource)
+	if !ok {
+		return environ
+	}
+	for _, key := range sortedKeys(source.configSettings()) {
+		_, overridden := overrides[key]
+		if _, isSet := os.LookupEnv(key); !isSet && !overridden {
+			environ = append(environ, key+"="+source.configSettings()[key])
+		}
+	}
+	return environ
+}
+
+func sortedKeys(settings map[string]string) []string {
+	keys := []string{}
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// the key under which a request's context carries ours
+type contextKey struct{}
+
+// withContext returns ctx, carrying context, so that what sees only a
+// request, e.g. our transport, sees our settings too
+func withContext(ctx stdcontext.Context, context Context) stdcontext.Context {
+	return stdcontext.WithValue(ctx, contextKey{}, context)
+}
+
+// contextOf returns the Context that ctx carries, if any
+func contextOf(ctx stdcontext.Context) Context {
+	context, _ := ctx.Value(contextKey{}).(Context)
+	return context
+}
@@ -0,0 +1,83 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writes a config file that runs Kui in a fresh directory, returning
+// the file and the directory
+func (suite *KaskTestSuite) writeWorkdirConfig(name string) (string, string) {
+	workdir, _ := ioutil.TempDir(suite.SaveDir, "workdir")
+	workdir, _ = filepath.EvalSymlinks(workdir)
+	config := filepath.Join(suite.SaveDir, name)
+	suite.Require().Nil(ioutil.WriteFile(config, []byte("# "+name+"\nKASK_WORKDIR="+workdir+"\n"), 0644))
+	return config, workdir
+}
+
+func (suite *KaskTestSuite) TestConfigFlag() {
+	suite.skipUnlessLinux()
+	record := filepath.Join(suite.SaveDir, "fake-kui-pwd")
+	defer setenv("FAKE_KUI_PWD", record)()
+
+	for _, profile := range []string{"profile-a", "profile-b"} {
+		config, workdir := suite.writeWorkdirConfig(profile)
+		_, err := suite.runFakeKui("--config", config, "list")
+		suite.Nil(err)
+		pwd, _ := ioutil.ReadFile(record)
+		suite.Equal(workdir, strings.TrimSpace(string(pwd)), profile)
+
+		_, isSet := os.LookupEnv("KASK_WORKDIR")
+		suite.False(isSet, "the config should not outlive the run")
+	}
+}
+
+func (suite *KaskTestSuite) TestConfigFromEnvLosesToEnv() {
+	suite.skipUnlessLinux()
+	record := filepath.Join(suite.SaveDir, "fake-kui-pwd")
+	defer setenv("FAKE_KUI_PWD", record)()
+
+	config, _ := suite.writeWorkdirConfig("profile")
+	defer setenv("KASK_CONFIG", config)()
+	workdir, _ := ioutil.TempDir(suite.SaveDir, "workdir")
+	workdir, _ = filepath.EvalSymlinks(workdir)
+	defer setenv("KASK_WORKDIR", workdir)()
+
+	_, err := suite.runFakeKui("list")
+	suite.Nil(err)
+	pwd, _ := ioutil.ReadFile(record)
+	suite.Equal(workdir, strings.TrimSpace(string(pwd)))
+}
+
+func (suite *KaskTestSuite) TestMissingConfig() {
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "--config", filepath.Join(suite.SaveDir, "no-such-config"), "list"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestParseConfig() {
+	settings, err := parseConfig([]byte("# comment\n\nKASK_OFFLINE=1\n KUI_DIST = http://example.com \n"))
+	suite.Nil(err)
+	suite.Equal(map[string]string{"KASK_OFFLINE": "1", "KUI_DIST": "http://example.com"}, settings)
+
+	_, err = parseConfig([]byte("PATH=/tmp\n"))
+	suite.NotNil(err)
+	_, err = parseConfig([]byte("KASK_OFFLINE\n"))
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestConfigReachesTheChild() {
+	suite.skipUnlessLinux()
+	record := filepath.Join(suite.SaveDir, "fake-kui-env")
+	defer setenv("FAKE_KUI_ENV", record)()
+
+	config := filepath.Join(suite.SaveDir, "config")
+	suite.Require().Nil(ioutil.WriteFile(config, []byte("KUI_FROM_CONFIG=yes\n"), 0644))
+	_, err := suite.runFakeKui("--config", config, "list")
+	suite.Nil(err)
+	env, _ := ioutil.ReadFile(record)
+	suite.Contains(string(env), "KUI_FROM_CONFIG=yes", "the child should see the settings of the config file")
+	_, isSet := os.LookupEnv("KUI_FROM_CONFIG")
+	suite.False(isSet, "the config should not enter our environment")
+}
@@ -0,0 +1,65 @@
+package kui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kui-shell/kask/i18n"
+)
+
+// where confirmations are asked and answered; variables, so that tests
+// may play the part of a user at a terminal
+var (
+	promptInput  io.Reader = os.Stdin
+	promptOutput io.Writer = os.Stderr
+	interactive            = func() bool { return isTerminal(os.Stdin) && isTerminal(os.Stderr) }
+)
+
+// extractYesFlag removes any --yes preceding "--" from args, returning
+// the remaining args and whether it was there
+func extractYesFlag(args []string) ([]string, bool) {
+	rest := []string{}
+	yes := false
+	for idx, arg := range args {
+		switch {
+		case arg == "--":
+			return append(rest, args[idx:]...), yes
+		case arg == "--yes":
+			yes = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, yes
+}
+
+// confirmDownload asks, if a user is at a terminal, whether to go
+// ahead with a fresh download of the Kui base; it is hundreds of
+// megabytes, which may be unwelcome on a metered connection. Without
+// a terminal, or given --yes or KASK_ASSUME_YES, the answer is yes.
+func (component *KuiComponent) confirmDownload(context Context, version string, location string, size int64) error {
+	if _, assumeYes := lookupEnv(context, "KASK_ASSUME_YES"); assumeYes || component.assumeYes || !interactive() {
+		return nil
+	}
+
+	host := location
+	if u, err := url.Parse(location); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	what := fmt.Sprintf("Kui base %s", version)
+	if size >= 0 {
+		what += fmt.Sprintf(" (%s)", FormatBytes(i18n.CurrentLocale(), size))
+	}
+	fmt.Fprintf(promptOutput, "Download %s from %s? [Y/n] ", what, host)
+
+	answer, _ := bufio.NewReader(promptInput).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return nil
+	}
+	return newErrorf(UsageError, "the download of Kui base %s was declined; pass --yes, or set KASK_ASSUME_YES, to skip asking", version)
+}
@@ -0,0 +1,50 @@
+package kui
+
+import (
+	"bytes"
+	"strings"
+)
+
+// pretend that a user is at a terminal, answering prompts with answer
+func (suite *KaskTestSuite) atTerminal(answer string) (*bytes.Buffer, func()) {
+	previousInteractive, previousInput, previousOutput := interactive, promptInput, promptOutput
+	prompts := &bytes.Buffer{}
+	interactive = func() bool { return true }
+	promptInput = strings.NewReader(answer)
+	promptOutput = prompts
+	return prompts, func() {
+		interactive, promptInput, promptOutput = previousInteractive, previousInput, previousOutput
+	}
+}
+
+func (suite *KaskTestSuite) TestAssumeYesSkipsConfirmation() {
+	suite.skipUnlessLinux()
+	gets, notModified := 0, 0
+	server := serveWithETag(makeFakeDist(), `"v1"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_ASSUME_YES", "true")()
+
+	prompts, restore := suite.atTerminal("n\n")
+	defer restore()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Nil(err)
+	suite.Empty(prompts.String())
+	suite.Equal(1, gets)
+}
+
+func (suite *KaskTestSuite) TestDeclinedConfirmation() {
+	gets, notModified := 0, 0
+	server := serveWithETag(makeFakeDist(), `"v1"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	prompts, restore := suite.atTerminal("n\n")
+	defer restore()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(ExitUsage, ExitCode(err))
+	suite.Contains(prompts.String(), "Download Kui base "+suite.version)
+	suite.Equal(0, gets)
+}
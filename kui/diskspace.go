@@ -0,0 +1,62 @@
+package kui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/kui-shell/kask/i18n"
+)
+
+// ErrInsufficientDiskSpace is the failure of a download that would not
+// leave room to extract it
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// how much space, relative to the size of the archive, we need for
+// the archive and its extract together
+const defaultDiskSpaceFactor = 2.5
+
+// freeSpace returns the bytes available to us on the volume holding
+// dir, and whether it could tell; a var, so that tests may stub it
+var freeSpace = statfsFreeSpace
+
+// diskSpaceFactor returns the space we need per byte of archive, from
+// KASK_DISK_SPACE_FACTOR; 0 disables the check
+func diskSpaceFactor(context Context) (float64, error) {
+	value, isSet := lookupEnv(context, "KASK_DISK_SPACE_FACTOR")
+	if !isSet {
+		return defaultDiskSpaceFactor, nil
+	}
+	factor, err := strconv.ParseFloat(value, 64)
+	if err != nil || factor < 0 {
+		return 0, fmt.Errorf("invalid KASK_DISK_SPACE_FACTOR %q", value)
+	}
+	return factor, nil
+}
+
+// checkDiskSpace fails early if the volume holding dir lacks room for
+// the remaining bytes of an archive, of which we already have the
+// first received bytes, and for its extract. Without this, a big
+// download onto a nearly full volume only fails deep into extraction.
+func checkDiskSpace(context Context, dir string, remaining int64, received int64) error {
+	if remaining < 0 {
+		// the server did not say
+		return nil
+	}
+	size := received + remaining
+	factor, err := diskSpaceFactor(context)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	free, ok := freeSpace(dir)
+	if !ok || factor == 0 {
+		return nil
+	}
+
+	needed := int64(factor*float64(size)) - received
+	if needed > 0 && free < uint64(needed) {
+		locale := i18n.CurrentLocale()
+		return fmt.Errorf("%w in %s: need %s, have %s", ErrInsufficientDiskSpace, dir, FormatBytes(locale, needed), FormatBytes(locale, int64(free)))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package kui
+
+import (
+	"errors"
+)
+
+// stubFreeSpace makes the plugin-dir volume report the given free
+// bytes, returning a func that restores the real query
+func stubFreeSpace(free uint64) func() {
+	original := freeSpace
+	freeSpace = func(string) (uint64, bool) { return free, true }
+	return func() { freeSpace = original }
+}
+
+func (suite *KaskTestSuite) TestInsufficientDiskSpace() {
+	dist := makeFakeDist()
+	server := serveDist(dist)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer stubFreeSpace(uint64(len(dist)))()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().NotNil(err)
+	suite.True(errors.Is(err, ErrInsufficientDiskSpace))
+	suite.Contains(err.Error(), "insufficient disk space")
+	suite.False(retryable(err))
+}
+
+func (suite *KaskTestSuite) TestSufficientDiskSpace() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	server := serveDist(dist)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer stubFreeSpace(uint64(3 * len(dist)))()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Nil(err)
+}
+
+func (suite *KaskTestSuite) TestDiskSpaceFactor() {
+	defer stubFreeSpace(100)()
+	suite.NotNil(checkDiskSpace(suite.pluginContext, suite.SaveDir, 50, 0))
+	suite.Nil(checkDiskSpace(suite.pluginContext, suite.SaveDir, 20, 30), "a resumed download needs room only for the rest")
+	suite.Nil(checkDiskSpace(suite.pluginContext, suite.SaveDir, -1, 0), "an unknown length cannot be checked")
+
+	defer setenv("KASK_DISK_SPACE_FACTOR", "1")()
+	suite.Nil(checkDiskSpace(suite.pluginContext, suite.SaveDir, 50, 0))
+
+	defer setenv("KASK_DISK_SPACE_FACTOR", "0")()
+	suite.Nil(checkDiskSpace(suite.pluginContext, suite.SaveDir, 5000, 0), "a factor of 0 disables the check")
+
+	defer setenv("KASK_DISK_SPACE_FACTOR", "lots")()
+	suite.Equal(UsageError, KindOf(checkDiskSpace(suite.pluginContext, suite.SaveDir, 50, 0)))
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package kui
+
+import "syscall"
+
+func statfsFreeSpace(dir string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
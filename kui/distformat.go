@@ -0,0 +1,110 @@
+package kui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mholt/archiver"
+)
+
+// the archive formats that we can extract, by the names that
+// KASK_DIST_FORMAT accepts
+var distFormats = map[string]archiver.Walker{
+	"zip":     archiver.DefaultZip,
+	"tar.bz2": archiver.DefaultTarBz2,
+}
+
+// the names of distFormats, for messages
+const supportedDistFormats = "zip, tar.bz2"
+
+// ErrUnsupportedDistFormat is returned when the dist is not an archive
+// of the format we expected of it, e.g. because a mirror serves a
+// different one under the same name
+var ErrUnsupportedDistFormat = errors.New("unsupported archive format")
+
+// distFormatOverride returns the format named by KASK_DIST_FORMAT, if
+// any, in place of the one the dist's name implies
+func distFormatOverride(context Context) (archiver.Walker, bool, error) {
+	name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(getenv(context, "KASK_DIST_FORMAT")), "."))
+	if name == "" {
+		return nil, false, nil
+	}
+	format, ok := distFormats[name]
+	if !ok {
+		return nil, false, fmt.Errorf("invalid KASK_DIST_FORMAT %q; use one of %s", name, supportedDistFormats)
+	}
+	return format, true, nil
+}
+
+// formatName returns the name, as KASK_DIST_FORMAT has it, of format
+func formatName(format archiver.Walker) string {
+	for name, candidate := range distFormats {
+		if candidate == format {
+			return name
+		}
+	}
+	return fmt.Sprintf("%T", format)
+}
+
+// the leading bytes of the formats a dist host might plausibly serve
+var archiveSignatures = []struct {
+	name   string
+	offset int
+	magic  []byte
+}{
+	{"zip", 0, []byte("PK\x03\x04")},
+	{"zip", 0, []byte("PK\x05\x06")},
+	{"tar.bz2", 0, []byte("BZh")},
+	{"gzip", 0, []byte{0x1f, 0x8b}},
+	{"xz", 0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{"zstd", 0, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{"7z", 0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}},
+	{"rar", 0, []byte("Rar!")},
+	{"tar", 257, []byte("ustar")},
+}
+
+// detectArchiveFormat names the format of the given archive, from its
+// leading bytes: "html" for what is likely a mirror's error page, and
+// "unknown" if we cannot tell
+func detectArchiveFormat(archive string) string {
+	f, err := os.Open(archive)
+	if err != nil {
+		return "unknown"
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	for _, signature := range archiveSignatures {
+		if len(head) >= signature.offset+len(signature.magic) && bytes.Equal(head[signature.offset:signature.offset+len(signature.magic)], signature.magic) {
+			return signature.name
+		}
+	}
+	if trimmed := bytes.TrimSpace(head); bytes.HasPrefix(trimmed, []byte("<")) {
+		return "html"
+	}
+	return "unknown"
+}
+
+// explainFormatError replaces the failure to extract archive as
+// format with a friendlier one, if the archive is of another format
+// altogether; archiver's own message then says little of use
+func explainFormatError(url string, archive string, format archiver.Walker, err error) error {
+	expected := formatName(format)
+	detected := detectArchiveFormat(archive)
+	if detected == expected {
+		return err
+	}
+
+	if _, supported := distFormats[detected]; supported {
+		return fmt.Errorf("%w: the Kui base from %s is a %s archive, not %s as expected; if that is what the dist host serves, set KASK_DIST_FORMAT=%s (%v)",
+			ErrUnsupportedDistFormat, url, detected, expected, detected, err)
+	}
+	return fmt.Errorf("%w: the Kui base from %s is a %s file, not %s as expected, and kask supports only %s; check the dist host or mirror, or set KASK_DIST_FORMAT should it serve one of those (%v)",
+		ErrUnsupportedDistFormat, url, detected, expected, supportedDistFormats, err)
+}
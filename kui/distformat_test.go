@@ -0,0 +1,61 @@
+package kui
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) writeArchive(name string, contents []byte) string {
+	archive := filepath.Join(suite.SaveDir, name)
+	suite.Require().Nil(ioutil.WriteFile(archive, contents, 0644))
+	return archive
+}
+
+func (suite *KaskTestSuite) TestUnsupportedDistFormat() {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	w.Write([]byte("not a zip"))
+	w.Close()
+	archive := suite.writeArchive("Kui-base.tar.gz", gzipped.Bytes())
+	suite.Equal("gzip", detectArchiveFormat(archive))
+
+	dir, err := ioutil.TempDir(suite.SaveDir, "extract")
+	suite.Require().Nil(err)
+	_, err = extractDist(context.Background(), suite.pluginContext, "https://mirror.example.com/Kui-base-linux-x64.zip", archive, dir, ExtractOptions{})
+	suite.Equal(ExitExtract, ExitCode(err))
+	suite.True(errors.Is(err, ErrUnsupportedDistFormat))
+	suite.Contains(err.Error(), "is a gzip file, not zip as expected")
+	suite.Contains(err.Error(), "supports only zip, tar.bz2")
+	suite.Contains(err.Error(), "KASK_DIST_FORMAT")
+
+	page := suite.writeArchive("error-page.zip", []byte("\n<html><body>Service Unavailable</body></html>"))
+	suite.Equal("html", detectArchiveFormat(page))
+}
+
+func (suite *KaskTestSuite) TestMisnamedDistFormat() {
+	suite.skipUnlessLinux()
+	archive := suite.writeArchive("Kui-base.tar.bz2", makeFakeDist())
+	url := "https://mirror.example.com/Kui-base-linux-x64.tar.bz2"
+
+	dir, err := ioutil.TempDir(suite.SaveDir, "extract")
+	suite.Require().Nil(err)
+	_, err = extractDist(context.Background(), suite.pluginContext, url, archive, dir, ExtractOptions{})
+	suite.True(errors.Is(err, ErrUnsupportedDistFormat))
+	suite.Contains(err.Error(), "is a zip archive, not tar.bz2 as expected")
+	suite.Contains(err.Error(), "set KASK_DIST_FORMAT=zip")
+
+	defer setenv("KASK_DIST_FORMAT", "zip")()
+	_, err = extractDist(context.Background(), suite.pluginContext, url, archive, dir, ExtractOptions{})
+	suite.Nil(err)
+	suite.FileExists(GetRootCommand(suite.pluginContext, dir).Path)
+}
+
+func (suite *KaskTestSuite) TestInvalidDistFormat() {
+	defer setenv("KASK_DIST_FORMAT", "rar")()
+	_, err := GetExtractOptions(suite.pluginContext)
+	suite.NotNil(err)
+}
@@ -0,0 +1,87 @@
+package kui
+
+import (
+	stdcontext "context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// how long we allow for downloading and extracting the Kui base; it
+// is a few hundred megabytes, so be generous
+const defaultDownloadTimeout = 10 * time.Minute
+
+// downloadTimeout returns the limit on the download phase, which may
+// be overridden via KASK_DOWNLOAD_TIMEOUT, e.g. "30m"
+func downloadTimeout(context Context) (time.Duration, error) {
+	value, isSet := lookupEnv(context, "KASK_DOWNLOAD_TIMEOUT")
+	if !isSet {
+		return defaultDownloadTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid KASK_DOWNLOAD_TIMEOUT %q", value)
+	}
+	return timeout, nil
+}
+
+// notifyInterrupt, if set, arranges for cancel to be called upon an
+// interrupt, until the returned function is called. Start sets it, so
+// that the CLI cleans up after an interrupted download; a program that
+// embeds kask keeps its own handling of signals.
+var notifyInterrupt func(cancel func()) func()
+
+// interruptOnSignal is the CLI's notifyInterrupt: SIGINT or SIGTERM
+// cancels, rather than killing us outright
+func interruptOnSignal(cancel func()) func() {
+	interrupts := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(interrupts)
+		close(done)
+	}
+}
+
+// downloadContext returns the context governing the download phase.
+// It has no bearing on the Kui command that we run afterwards. An
+// interrupt, per notifyInterrupt, cancels it, so that we clean up after
+// the download as we would after any other failure.
+func downloadContext(context Context) (stdcontext.Context, stdcontext.CancelFunc, error) {
+	timeout, err := downloadTimeout(context)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), timeout)
+
+	stop := func() {}
+	if notifyInterrupt != nil {
+		stop = notifyInterrupt(cancel)
+	}
+	return ctx, func() {
+		stop()
+		cancel()
+	}, nil
+}
+
+// timedOut explains err, if it was caused by the download context's
+// deadline passing, or by an interrupt
+func timedOut(ctx stdcontext.Context, context Context, err error) error {
+	if ctx.Err() == stdcontext.Canceled {
+		return fmt.Errorf("the download of the Kui base was interrupted: %w", err)
+	}
+	if ctx.Err() != stdcontext.DeadlineExceeded {
+		return err
+	}
+	timeout, _ := downloadTimeout(context)
+	return fmt.Errorf("downloading the Kui base took longer than KASK_DOWNLOAD_TIMEOUT (%v): %w", timeout, stdcontext.DeadlineExceeded)
+}
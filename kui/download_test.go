@@ -0,0 +1,119 @@
+package kui
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func (suite *KaskTestSuite) TestDownloadTimeoutDefault() {
+	timeout, err := downloadTimeout(suite.pluginContext)
+	suite.Nil(err)
+	suite.Equal(defaultDownloadTimeout, timeout)
+}
+
+func (suite *KaskTestSuite) TestDownloadTimeoutInvalid() {
+	defer setenv("KASK_DOWNLOAD_TIMEOUT", "soon")()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "prefetch"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestDownloadTimesOut() {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DOWNLOAD_TIMEOUT", "200ms")()
+
+	start := time.Now()
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.True(errors.Is(err, context.DeadlineExceeded), "%v", err)
+	suite.Less(int64(time.Since(start)), int64(5*time.Second))
+}
+
+func (suite *KaskTestSuite) TestDownloadTimeoutSparesTheCommand() {
+	suite.skipUnlessLinux()
+	defer setenv("KASK_DOWNLOAD_TIMEOUT", "500ms")()
+
+	// the command may take longer than the download was allowed to
+	defer setenv("FAKE_KUI_SLEEP", "1")()
+
+	args, err := suite.runFakeKui("list")
+	suite.Nil(err)
+	suite.Equal([]string{"list"}, args)
+}
+
+// leftovers returns what a failed install left in the plugin
+// directory's caches, other than a partial download to resume
+func (suite *KaskTestSuite) leftovers(version string) []string {
+	pluginDir, err := suite.pluginContext.PluginDirectory()
+	suite.Require().Nil(err)
+	var found []string
+	for _, dir := range []string{filepath.Join(pluginDir, "cache-"+version), filepath.Join(pluginDir, "cache", "blobs")} {
+		entries, _ := ioutil.ReadDir(dir)
+		for _, entry := range entries {
+			if !strings.Contains(entry.Name(), ".part") {
+				found = append(found, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return found
+}
+
+func (suite *KaskTestSuite) TestFailedExtractionLeavesNoArtifacts() {
+	server := serveDist([]byte("not a zip"))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Equal(ExtractError, KindOf(err))
+	suite.Empty(suite.leftovers("1.2.3"))
+}
+
+func (suite *KaskTestSuite) TestInterruptedDownloadLeavesNoArtifacts() {
+	suite.skipUnlessLinux()
+	// as an interrupt would, as Start arranges
+	interrupts := make(chan func(), 1)
+	previous := notifyInterrupt
+	notifyInterrupt = func(cancel func()) func() {
+		select {
+		case interrupts <- cancel:
+		default:
+		}
+		return func() {}
+	}
+	defer func() { notifyInterrupt = previous }()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "GET" {
+			select {
+			case interrupt := <-interrupts:
+				interrupt()
+			default:
+			}
+			<-release
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().NotNil(err)
+	suite.Contains(err.Error(), "interrupted")
+	suite.Empty(suite.leftovers("1.2.3"))
+}
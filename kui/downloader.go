@@ -0,0 +1,69 @@
+package kui
+
+import (
+	"bytes"
+	stdcontext "context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// the external downloaders we know how to drive, by the name of their
+// executable, mapped to the arguments that fetch a url into a file, as
+// the given user agent; like our own client, they undo any
+// Content-Encoding
+var downloaders = map[string]func(url string, file string, agent string) []string{
+	"curl": func(url string, file string, agent string) []string {
+		return []string{"--fail", "--location", "--silent", "--show-error", "--compressed", "--user-agent", agent, "--output", file, url}
+	},
+	"aria2c": func(url string, file string, agent string) []string {
+		return []string{"--quiet", "--allow-overwrite=true", "--auto-file-renaming=false", "--split=8", "--max-connection-per-server=8", "--http-accept-gzip=true",
+			"--user-agent=" + agent, "--dir", filepath.Dir(file), "--out", filepath.Base(file), url}
+	},
+}
+
+// externalDownloader returns the path of the downloader named by
+// KASK_DOWNLOADER, e.g. aria2c or /usr/local/bin/curl, along with its
+// arguments; or "" to use our own client, as we do if the tool is not
+// installed. We cannot hold an external tool to KASK_ALLOWED_HOSTS
+// across redirects, nor to KASK_DIST_PIN, nor send it through
+// KASK_SOCKS_PROXY, so we also use our own client when any is set.
+func externalDownloader(context Context) (string, func(string, string) []string, error) {
+	tool := getenv(context, "KASK_DOWNLOADER")
+	if tool == "" || allowedHosts(context) != nil || getenv(context, "KASK_DIST_PIN") != "" || getenv(context, "KASK_SOCKS_PROXY") != "" {
+		return "", nil, nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(tool), ".exe")
+	argsOf, known := downloaders[name]
+	if !known {
+		return "", nil, newErrorf(UsageError, "unsupported KASK_DOWNLOADER %s; use curl or aria2c", tool)
+	}
+
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return "", nil, nil
+	}
+	agent := userAgent(context)
+	return path, func(url string, file string) []string { return argsOf(url, file, agent) }, nil
+}
+
+// fetchExternal has the given downloader fetch url into part
+func fetchExternal(ctx stdcontext.Context, tool string, args []string, url string, part string) error {
+	removePart(part)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, tool, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(part)
+		return fmt.Errorf("%s failed to fetch %s: %v %s", filepath.Base(tool), url, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if _, err := os.Stat(part); err != nil {
+		return fmt.Errorf("%s did not fetch %s: %v", filepath.Base(tool), url, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package kui
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// a stand-in for curl, which "downloads" $FAKE_DOWNLOADER_SOURCE to its
+// --output, and exits with $FAKE_DOWNLOADER_EXIT (default 0)
+const fakeCurlScript = `#!/bin/sh
+while [ $# -gt 0 ]; do
+  [ "$1" = --output ] && { out="$2"; shift; }
+  shift
+done
+[ -n "$FAKE_DOWNLOADER_EXIT" ] && { echo "curl: (22) failed" >&2; exit "$FAKE_DOWNLOADER_EXIT"; }
+cp "$FAKE_DOWNLOADER_SOURCE" "$out"
+`
+
+// installs the fake curl as KASK_DOWNLOADER, serving the given content
+func (suite *KaskTestSuite) withFakeCurl(content []byte) func() {
+	dir, err := ioutil.TempDir(suite.SaveDir, "downloader")
+	suite.Require().Nil(err)
+	tool := filepath.Join(dir, "curl")
+	suite.Require().Nil(ioutil.WriteFile(tool, []byte(fakeCurlScript), 0755))
+	source := filepath.Join(dir, "source")
+	suite.Require().Nil(ioutil.WriteFile(source, content, 0644))
+
+	restoreTool := setenv("KASK_DOWNLOADER", tool)
+	restoreSource := setenv("FAKE_DOWNLOADER_SOURCE", source)
+	return func() {
+		restoreSource()
+		restoreTool()
+	}
+}
+
+func (suite *KaskTestSuite) TestExternalDownloader() {
+	suite.skipUnlessLinux()
+	content := []byte("fetched by curl")
+	defer suite.withFakeCurl(content)()
+	gets := 0
+	server := serveDistCountingGets(&content, new(string), &gets)
+	defer server.Close()
+
+	file := filepath.Join(suite.SaveDir, "external")
+	updated, checksum, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, file+".validators", sha256Hex(content))
+	suite.Require().Nil(err)
+	suite.True(updated)
+	suite.Equal(sha256Hex(content), checksum)
+	suite.Equal(0, gets, "the download should have been left to curl")
+	fetched, _ := ioutil.ReadFile(file)
+	suite.Equal(content, fetched)
+
+	_, _, err = fetchDist(context.Background(), suite.pluginContext, server.URL, file, file+".validators", sha256Hex([]byte("other")))
+	suite.Equal(ChecksumError, KindOf(err), "an external download is still verified")
+}
+
+func (suite *KaskTestSuite) TestExternalDownloaderFailure() {
+	suite.skipUnlessLinux()
+	defer suite.withFakeCurl([]byte("content"))()
+	defer setenv("FAKE_DOWNLOADER_EXIT", "22")()
+
+	file := filepath.Join(suite.SaveDir, "external-failure")
+	_, _, err := fetchDist(context.Background(), suite.pluginContext, "http://example.com/dist", file, file+".validators", "")
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "curl failed")
+	_, err = os.Stat(file)
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *KaskTestSuite) TestMissingExternalDownloader() {
+	defer setenv("KASK_DOWNLOADER", filepath.Join(suite.SaveDir, "no-such-dir", "aria2c"))()
+	content := []byte("fetched by us")
+	gets := 0
+	server := serveDistCountingGets(&content, new(string), &gets)
+	defer server.Close()
+
+	file := filepath.Join(suite.SaveDir, "fallback")
+	_, _, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, file+".validators", "")
+	suite.Nil(err)
+	suite.Equal(1, gets)
+}
+
+func (suite *KaskTestSuite) TestUnsupportedExternalDownloader() {
+	defer setenv("KASK_DOWNLOADER", "wget")()
+	_, _, err := externalDownloader(suite.pluginContext)
+	suite.Equal(ExitUsage, ExitCode(err))
+}
@@ -0,0 +1,95 @@
+package kui
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+)
+
+// ErrHostNotAllowed is returned for a request to a host that is not in
+// KASK_ALLOWED_HOSTS
+var ErrHostNotAllowed = errors.New("host is not in KASK_ALLOWED_HOSTS")
+
+// allowedHosts returns the hosts that we may contact, from the
+// comma-separated KASK_ALLOWED_HOSTS; nil means any host. An entry
+// with a leading "." also allows any subdomain, e.g. ".example.com".
+func allowedHosts(context Context) []string {
+	var hosts []string
+	for _, host := range strings.Split(getenv(context, "KASK_ALLOWED_HOSTS"), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func hostAllowed(host string, allowed []string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range allowed {
+		if host == entry || (strings.HasPrefix(entry, ".") && (strings.HasSuffix(host, entry) || host == entry[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEgress refuses, with a PolicyError, to contact the host of the
+// given url if it is not allowed
+func checkEgress(context Context, rawurl string) error {
+	allowed := allowedHosts(context)
+	if allowed == nil {
+		return nil
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	if !hostAllowed(u.Hostname(), allowed) {
+		return newError(PolicyError, fmt.Errorf("%w: refusing to contact %s", ErrHostNotAllowed, u.Hostname()))
+	}
+	return nil
+}
+
+// the client for all of our requests; it applies the egress policy to
+// every hop of a redirect, not just the first, and dials through any
+// SOCKS5 proxy
+var httpClient = &http.Client{
+	Transport: newSocksTransport(http.DefaultTransport.(*http.Transport)),
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return checkEgress(contextOf(req.Context()), req.URL.String())
+	},
+}
+
+// userAgent identifies us to the servers we contact, e.g.
+// "kask/1.2.3 (linux/amd64)", unless KASK_USER_AGENT says otherwise;
+// some CDNs throttle Go's default
+func userAgent(context Context) string {
+	if agent := getenv(context, "KASK_USER_AGENT"); agent != "" {
+		return agent
+	}
+	return fmt.Sprintf("kask/%s (%s/%s)", GetVersion().String(), runtime.GOOS, runtime.GOARCH)
+}
+
+// doRequest sends req, if the egress policy allows it
+func doRequest(context Context, req *http.Request) (*http.Response, error) {
+	return doRequestWith(context, httpClient, req)
+}
+
+// doRequestWith is doRequest, using the given client; the request
+// carries the context, for its redirects and transport to consult
+func doRequestWith(context Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	if err := checkEgress(context, req.URL.String()); err != nil {
+		return nil, err
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", userAgent(context))
+	}
+	return client.Do(req.WithContext(withContext(req.Context(), context)))
+}
@@ -0,0 +1,92 @@
+package kui
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestHostAllowed() {
+	allowed := []string{"mirror.example.com", ".internal.example.com"}
+	suite.True(hostAllowed("mirror.example.com", allowed))
+	suite.True(hostAllowed("MIRROR.example.com", allowed))
+	suite.True(hostAllowed("a.internal.example.com", allowed))
+	suite.True(hostAllowed("internal.example.com", allowed))
+	suite.False(hostAllowed("example.com", allowed))
+	suite.False(hostAllowed("evilinternal.example.com", allowed))
+}
+
+func (suite *KaskTestSuite) TestEgressUnrestrictedByDefault() {
+	suite.Nil(checkEgress(suite.pluginContext, "https://anywhere.example.com/Kui.zip"))
+}
+
+func (suite *KaskTestSuite) TestDisallowedHostIsBlocked() {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_ALLOWED_HOSTS", "mirror.example.com")()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "list"})
+	suite.True(errors.Is(err, ErrHostNotAllowed), "%v", err)
+	suite.Equal(ExitPolicy, ExitCode(err))
+	suite.Equal(0, requests)
+}
+
+func (suite *KaskTestSuite) TestAllowedHostProceeds() {
+	suite.skipUnlessLinux()
+	defer setenv("KASK_ALLOWED_HOSTS", "127.0.0.1")()
+
+	args, err := suite.runFakeKui("list")
+	suite.Nil(err)
+	suite.Equal([]string{"list"}, args)
+}
+
+func (suite *KaskTestSuite) TestRedirectToDisallowedHostIsBlocked() {
+	elsewhere := serveDist(makeFakeDist())
+	defer elsewhere.Close()
+
+	// 127.0.0.1 is allowed, but it sends us to localhost, which is not
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(elsewhere.URL, "127.0.0.1", "localhost", 1)+r.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_ALLOWED_HOSTS", "127.0.0.1")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.True(errors.Is(err, ErrHostNotAllowed), "%v", err)
+	suite.Equal(ExitPolicy, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestUserAgent() {
+	suite.skipUnlessLinux()
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(makeFakeDist())
+	}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	suite.Require().NotEmpty(agents)
+	expected := "kask/" + GetVersion().String() + " (" + runtime.GOOS + "/" + runtime.GOARCH + ")"
+	for _, agent := range agents {
+		suite.Equal(expected, agent)
+	}
+}
+
+func (suite *KaskTestSuite) TestUserAgentOverride() {
+	defer setenv("KASK_USER_AGENT", "acme-installer/2")()
+	suite.Equal("acme-installer/2", userAgent(suite.pluginContext))
+}
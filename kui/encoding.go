@@ -0,0 +1,54 @@
+package kui
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Checksums, whether published by the dist host or pinned, apply to the
+// dist itself: to the bytes of the archive, after undoing any
+// Content-Encoding that the host put on top of it. We ask the host for
+// the identity encoding, so that Go's transport never decodes a
+// response behind our back (which it does only when it asked for gzip
+// itself, and never for a Range request, so that a resumed download
+// would see other bytes than a fresh one); but a misconfigured mirror
+// may gzip the archive regardless, and that we undo explicitly.
+
+// askForIdentity asks, via Accept-Encoding, that the response to req
+// not be encoded
+func askForIdentity(req *http.Request) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+}
+
+// contentEncoding returns the Content-Encoding of resp, in lower case;
+// "" for the identity encoding
+func contentEncoding(resp *http.Response) string {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "identity" {
+		return ""
+	}
+	return encoding
+}
+
+// decodedBody returns the body of resp with its Content-Encoding, if
+// any, undone; and whether there was one, in which case the
+// Content-Length of resp is not that of what we return
+func decodedBody(resp *http.Response) (io.ReadCloser, bool, error) {
+	switch encoding := contentEncoding(resp); encoding {
+	case "":
+		return resp.Body, false, nil
+	case "gzip", "x-gzip":
+		body, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, true, fmt.Errorf("unable to decode the gzip response from %s: %v", resp.Request.URL, err)
+		}
+		return body, true, nil
+	default:
+		return nil, true, fmt.Errorf("unsupported Content-Encoding %q from %s", encoding, resp.Request.URL)
+	}
+}
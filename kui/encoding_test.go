@@ -0,0 +1,83 @@
+package kui
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+)
+
+func gzipped(body []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(body)
+	w.Close()
+	return buf.Bytes()
+}
+
+// a misconfigured dist host, which gzips the dist whatever was asked
+// for, recording the Accept-Encoding of the fetch
+func serveGzippedDist(body []byte, checksum string, acceptEncoding *string) *httptest.Server {
+	encoded := gzipped(body)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			w.Write([]byte(checksum + "  Kui" + GetDistOSSuffix(nil) + "\n"))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.Method == "HEAD" {
+			return
+		}
+		*acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Write(encoded)
+	}))
+}
+
+func (suite *KaskTestSuite) TestGzipEncodedDist() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	acceptEncoding := ""
+	server := serveGzippedDist(dist, sha256Hex(dist), &acceptEncoding)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	cmd, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	suite.FileExists(cmd.Path)
+	suite.Equal("identity", acceptEncoding)
+
+	// the checksum is that of the decoded archive
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	provenance := readProvenance(filepath.Join(pluginDir, "cache-"+suite.version, "provenance.json"))
+	suite.Equal(sha256Hex(dist), provenance.SHA256)
+}
+
+func (suite *KaskTestSuite) TestGzipEncodedDistChecksumOfEncodedBytes() {
+	dist := makeFakeDist()
+	acceptEncoding := ""
+	server := serveGzippedDist(dist, sha256Hex(gzipped(dist)), &acceptEncoding)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(ChecksumError, KindOf(err))
+}
+
+func (suite *KaskTestSuite) TestUnsupportedContentEncoding() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte("not really brotli"))
+	}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().NotNil(err)
+	suite.Contains(err.Error(), "Content-Encoding")
+}
@@ -0,0 +1,53 @@
+package kui
+
+import (
+	"strings"
+)
+
+// the parent environment variables that a Kui child started with
+// KASK_CLEAN_ENV still sees; a trailing "*" matches any suffix
+var cleanEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "LANG", "LANGUAGE", "LC_*", "TZ",
+	"TMPDIR", "TMP", "TEMP",
+	"DISPLAY", "WAYLAND_DISPLAY", "XAUTHORITY", "XDG_*", "DBUS_SESSION_BUS_ADDRESS",
+	"KUBECONFIG",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"SYSTEMROOT", "WINDIR", "COMSPEC", "PATHEXT", "APPDATA", "LOCALAPPDATA", "USERPROFILE",
+}
+
+// cleanEnv returns whether the Kui child should be started from a
+// curated environment, rather than the whole of ours
+func cleanEnv(context Context) bool {
+	_, clean := lookupEnv(context, "KASK_CLEAN_ENV")
+	return clean
+}
+
+func allowedInCleanEnv(name string) bool {
+	for _, allowed := range cleanEnvAllowlist {
+		if strings.HasSuffix(allowed, "*") {
+			prefix := strings.TrimSuffix(allowed, "*")
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(name, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// childEnvironment returns the part of the given environment, in the
+// form of os.Environ(), that the Kui child inherits
+func childEnvironment(context Context, environ []string) []string {
+	if !cleanEnv(context) {
+		return environ
+	}
+
+	var env []string
+	for _, variable := range environ {
+		if allowedInCleanEnv(strings.SplitN(variable, "=", 2)[0]) {
+			env = append(env, variable)
+		}
+	}
+	return env
+}
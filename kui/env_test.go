@@ -0,0 +1,43 @@
+package kui
+
+import (
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestChildEnvironmentByDefault() {
+	environ := []string{"PATH=/bin", "AWS_SECRET_ACCESS_KEY=hunter2"}
+	suite.Equal(environ, childEnvironment(suite.pluginContext, environ))
+}
+
+func (suite *KaskTestSuite) TestCleanChildEnvironment() {
+	defer setenv("KASK_CLEAN_ENV", "true")()
+
+	env := childEnvironment(suite.pluginContext, []string{
+		"PATH=/bin",
+		"KUBECONFIG=/home/me/.kube/config",
+		"LC_ALL=fr_FR.UTF-8",
+		"AWS_SECRET_ACCESS_KEY=hunter2",
+		"GITHUB_TOKEN=abc",
+	})
+	suite.Equal([]string{"PATH=/bin", "KUBECONFIG=/home/me/.kube/config", "LC_ALL=fr_FR.UTF-8"}, env)
+}
+
+func (suite *KaskTestSuite) TestCleanEnvironmentReachesTheChild() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_CLEAN_ENV", "true")()
+	defer setenv("KASK_TEST_SECRET", "hunter2")()
+
+	command, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+
+	names := map[string]bool{}
+	for _, variable := range command.Env {
+		names[strings.SplitN(variable, "=", 2)[0]] = true
+	}
+	suite.False(names["KASK_TEST_SECRET"], "unlisted variables should not reach Kui")
+	suite.True(names["HOME"])
+	suite.True(names["KUI_BIN_DIR"], "the variables kask sets should still reach Kui")
+}
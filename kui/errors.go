@@ -0,0 +1,180 @@
+package kui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// exit codes returned by kask; automation may branch on these, so
+// they must remain stable across releases
+const (
+	ExitOK          = 0
+	ExitFailure     = 1
+	ExitUsage       = 2
+	ExitDownload    = 3
+	ExitExtract     = 4
+	ExitChild       = 5
+	ExitOfflineMiss = 6
+	ExitPolicy      = 7
+)
+
+// ErrorKind classifies a failure, so that Start can map it to an exit code
+type ErrorKind int
+
+const (
+	UnknownError ErrorKind = iota
+	UsageError
+	DownloadError
+	ExtractError
+	ChildError
+	OfflineMissError
+	ChecksumError
+	PolicyError
+)
+
+func (kind ErrorKind) String() string {
+	switch kind {
+	case UsageError:
+		return "usage"
+	case DownloadError:
+		return "download"
+	case ExtractError:
+		return "extract"
+	case ChildError:
+		return "child"
+	case OfflineMissError:
+		return "offline"
+	case ChecksumError:
+		return "checksum"
+	case PolicyError:
+		return "policy"
+	default:
+		return "unknown"
+	}
+}
+
+// ExitCode is the process exit code associated with this kind of failure
+func (kind ErrorKind) ExitCode() int {
+	switch kind {
+	case UsageError:
+		return ExitUsage
+	case DownloadError, ChecksumError:
+		return ExitDownload
+	case ExtractError:
+		return ExitExtract
+	case ChildError:
+		return ExitChild
+	case OfflineMissError:
+		return ExitOfflineMiss
+	case PolicyError:
+		return ExitPolicy
+	default:
+		return ExitFailure
+	}
+}
+
+// KaskError is a failure tagged with the ErrorKind that caused it
+type KaskError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *KaskError) Error() string {
+	if e.Err == nil {
+		return e.Kind.String() + " error"
+	}
+	return e.Err.Error()
+}
+
+func (e *KaskError) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	// the code that first classified a failure knows best what kind it
+	// is, e.g. a policy violation or checksum mismatch during a download
+	var kaskErr *KaskError
+	if errors.As(err, &kaskErr) {
+		return err
+	}
+	return &KaskError{Kind: kind, Err: err}
+}
+
+func newErrorf(kind ErrorKind, format string, args ...interface{}) error {
+	return &KaskError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// KindOf returns the ErrorKind of the outermost KaskError in err's
+// chain, or UnknownError if there is none
+func KindOf(err error) ErrorKind {
+	var kaskErr *KaskError
+	if errors.As(err, &kaskErr) {
+		return kaskErr.Kind
+	}
+	return UnknownError
+}
+
+// ExitCode maps the error returned by Run to a process exit code
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ChildExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return KindOf(err).ExitCode()
+}
+
+// ChildExitError is the failure of a Kui command that we waited on for
+// the user, whose exit code is then ours
+type ChildExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ChildExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ChildExitError) Unwrap() error {
+	return e.Err
+}
+
+// preservingExitCode returns err, carrying the exit code of the Kui
+// command that caused it, if it got as far as exiting
+func preservingExitCode(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return &ChildExitError{exitErr.ExitCode(), err}
+	}
+	return err
+}
+
+// jsonOutput returns whether the user asked for machine-readable output
+func jsonOutput(context Context) bool {
+	return strings.EqualFold(getenv(context, "KASK_OUTPUT"), "json")
+}
+
+// the shape of a failure, as reported with KASK_OUTPUT=json
+type jsonError struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+	Kind  string `json:"kind"`
+}
+
+// writeJSONError reports err to out as a single JSON object
+func writeJSONError(out io.Writer, err error) error {
+	return json.NewEncoder(out).Encode(jsonError{
+		Error: err.Error(),
+		Code:  ExitCode(err),
+		Kind:  KindOf(err).String(),
+	})
+}
@@ -0,0 +1,130 @@
+package kui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	log "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func (suite *KaskTestSuite) TestExitCodeOfEachErrorKind() {
+	suite.Equal(ExitOK, ExitCode(nil))
+	suite.Equal(ExitFailure, ExitCode(errors.New("unclassified")))
+	suite.Equal(ExitUsage, ExitCode(newErrorf(UsageError, "oops")))
+	suite.Equal(ExitDownload, ExitCode(newErrorf(DownloadError, "oops")))
+	suite.Equal(ExitExtract, ExitCode(newErrorf(ExtractError, "oops")))
+	suite.Equal(ExitChild, ExitCode(newErrorf(ChildError, "oops")))
+	suite.Equal(ExitOfflineMiss, ExitCode(newErrorf(OfflineMissError, "oops")))
+	suite.Equal(ExitPolicy, ExitCode(newErrorf(PolicyError, "oops")))
+}
+
+func (suite *KaskTestSuite) TestUsageErrorExitCode() {
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask"})
+	suite.Equal(ExitUsage, ExitCode(err))
+
+	err = suite.cmd.Run(*suite.pluginContext, []string{"kask", "--help"})
+	suite.Equal(ExitOK, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestDownloadFailureExitCode() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "list"})
+	suite.Equal(ExitDownload, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestExtractFailureExitCode() {
+	server := serveDist([]byte("this is not a zip file"))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "list"})
+	suite.Equal(ExitExtract, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestChildFailureExitCode() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("FAKE_KUI_EXIT", "1")()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "list"})
+	suite.Equal(ExitChild, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestOfflineMissExitCode() {
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_OFFLINE", "true")()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "list"})
+	suite.Equal(ExitOfflineMiss, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestJSONErrorOutput() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "list"})
+	suite.Require().NotNil(err)
+
+	var out bytes.Buffer
+	suite.Nil(writeJSONError(&out, err))
+
+	var reported map[string]interface{}
+	suite.Nil(json.Unmarshal(out.Bytes(), &reported))
+	suite.Len(reported, 3)
+	suite.Equal("download", reported["kind"])
+	suite.Equal(float64(ExitDownload), reported["code"])
+	suite.Contains(reported["error"], "404")
+}
+
+func (suite *KaskTestSuite) TestJSONOutputMode() {
+	suite.False(jsonOutput(suite.pluginContext))
+	defer setenv("KASK_OUTPUT", "json")()
+	suite.True(jsonOutput(suite.pluginContext))
+}
+
+func (suite *KaskTestSuite) TestFailureIsLoggedOnce() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	context := *suite.pluginContext
+	context._logger = log.New(core).Sugar()
+
+	err := suite.cmd.Run(context, []string{"kask", "list"})
+	suite.Require().NotNil(err)
+	suite.Equal(0, logs.FilterLevelExact(zapcore.ErrorLevel).Len(), "Run should leave the logging to its caller")
+
+	var stderr bytes.Buffer
+	report(context, err, &stderr)
+	errors := logs.FilterLevelExact(zapcore.ErrorLevel).All()
+	suite.Require().Len(errors, 1)
+	suite.Equal("download", errors[0].ContextMap()["kind"])
+	suite.Empty(stderr.String())
+}
+
+func (suite *KaskTestSuite) TestForwardedCommandExitCode() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_EXIT", "42")()
+	defer setenv("FAKE_KUI_SLEEP", "1")()
+
+	start := time.Now()
+	_, err := suite.runFakeKui("somecmd", "--flag")
+	suite.GreaterOrEqual(int64(time.Since(start)), int64(time.Second), "a forwarded command should be waited for")
+	suite.Equal(42, ExitCode(err))
+	suite.Equal(ChildError, KindOf(err))
+}
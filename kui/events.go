@@ -0,0 +1,222 @@
+package kui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kui-shell/kask/i18n"
+)
+
+// Phase names a stage of kask's work
+type Phase string
+
+const (
+	PhaseResolving   Phase = "resolving"
+	PhaseDownloading Phase = "downloading"
+	PhaseExtracting  Phase = "extracting"
+	PhaseLinking     Phase = "linking"
+	PhaseRunning     Phase = "running"
+	PhaseDone        Phase = "done"
+	PhaseError       Phase = "error"
+)
+
+// Event is one of the events below, each of which marks the start of,
+// or progress in, a phase
+type Event interface {
+	Phase() Phase
+}
+
+// ResolvingEvent: we are looking for the given version of the Kui
+// base, first in the cache, and then at url
+type ResolvingEvent struct {
+	Version string
+	URL     string
+}
+
+// UpToDateEvent: a refresh found the cached Kui base of the given
+// version to be what url still serves, and so fetched nothing
+type UpToDateEvent struct {
+	Version string
+	URL     string
+}
+
+// DownloadingEvent: of the Kui base, Bytes of Total (-1 if unknown)
+// have arrived; the last of them is Complete, and says how long the
+// download took
+type DownloadingEvent struct {
+	Version  string
+	URL      string
+	Bytes    int64
+	Total    int64
+	Complete bool
+	Elapsed  time.Duration
+}
+
+// ExtractingEvent: of the Kui base, Entries have been extracted; the
+// last of them is Complete
+type ExtractingEvent struct {
+	Version  string
+	Entries  int
+	Complete bool
+}
+
+// LinkingEvent: the kubectl plugin at Link now runs kask
+type LinkingEvent struct {
+	Link string
+}
+
+// RunningEvent: we have run Kui with the given arguments; a Detached
+// Kui window may outlive us
+type RunningEvent struct {
+	Args     []string
+	Detached bool
+}
+
+// DoneEvent: kask's work is done
+type DoneEvent struct{}
+
+// ErrorEvent: kask's work failed
+type ErrorEvent struct {
+	Err error
+}
+
+func (ResolvingEvent) Phase() Phase   { return PhaseResolving }
+func (UpToDateEvent) Phase() Phase    { return PhaseResolving }
+func (DownloadingEvent) Phase() Phase { return PhaseDownloading }
+func (ExtractingEvent) Phase() Phase  { return PhaseExtracting }
+func (LinkingEvent) Phase() Phase     { return PhaseLinking }
+func (RunningEvent) Phase() Phase     { return PhaseRunning }
+func (DoneEvent) Phase() Phase        { return PhaseDone }
+func (ErrorEvent) Phase() Phase       { return PhaseError }
+
+// EventHandler receives the events of kask's work, e.g. so that a UI
+// embedding kask can show its progress, rather than parsing its logs.
+// Events arrive from the goroutines doing the work: several at once,
+// when plugins are installed concurrently (see InstallPlugins), so a
+// handler must be safe for concurrent use, and should return promptly.
+type EventHandler interface {
+	HandleEvent(event Event)
+}
+
+// EventHandlerFunc adapts a func to an EventHandler
+type EventHandlerFunc func(event Event)
+
+func (f EventHandlerFunc) HandleEvent(event Event) {
+	f(event)
+}
+
+// a Context may offer an EventHandler, as MainContext does
+type eventSource interface {
+	Events() EventHandler
+}
+
+// WithEventHandler returns a copy of the context that sends events to
+// the given handler
+func (context MainContext) WithEventHandler(handler EventHandler) MainContext {
+	context.events = handler
+	return context
+}
+
+// Events returns the context's EventHandler, if any
+func (context MainContext) Events() EventHandler {
+	return context.events
+}
+
+// emit sends the event to the context's EventHandler, if it has one
+func emit(context Context, event Event) {
+	if source, ok := context.(eventSource); ok {
+		if handler := source.Events(); handler != nil {
+			handler.HandleEvent(event)
+		}
+	}
+}
+
+// terminalEvents is the CLI's EventHandler, which renders downloads and
+// extractions on out, if a human is likely to be watching
+type terminalEvents struct {
+	out io.Writer
+
+	sync.Mutex
+	extracting     func(int)
+	doneExtracting func()
+}
+
+func newTerminalEvents() *terminalEvents {
+	return &terminalEvents{out: os.Stderr}
+}
+
+func (t *terminalEvents) HandleEvent(event Event) {
+	if f, ok := t.out.(*os.File); !ok || !isTerminal(f) {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+
+	switch e := event.(type) {
+	case UpToDateEvent:
+		fmt.Fprintf(t.out, "%v\n", gray(fmt.Sprintf("Kui base %s is already up to date", e.Version)))
+	case DownloadingEvent:
+		if e.Complete {
+			locale := i18n.CurrentLocale()
+			fmt.Fprintf(t.out, "%v %s (%s)\n", gray("Downloaded Kui base:"), FormatBytes(locale, e.Bytes), FormatRate(locale, e.Bytes, e.Elapsed))
+		}
+	case ExtractingEvent:
+		if t.extracting == nil {
+			t.extracting, t.doneExtracting = extractionProgress(t.out)
+		}
+		t.extracting(e.Entries)
+		if e.Complete {
+			t.doneExtracting()
+			t.extracting, t.doneExtracting = nil, nil
+		}
+	}
+}
+
+// the key, in a context.Context, of the func that a fetch of the dist
+// tells of its progress
+type downloadProgressKey struct{}
+
+// withDownloadProgress returns ctx, carrying a func that a fetch of the
+// dist calls, now and then, with how many of how many bytes (-1 if
+// unknown) have arrived
+func withDownloadProgress(ctx context.Context, progress func(bytes int64, total int64)) context.Context {
+	return context.WithValue(ctx, downloadProgressKey{}, progress)
+}
+
+// progressReader reads a response body, telling of its progress, at
+// most once per progressInterval
+type progressReader struct {
+	io.Reader
+	bytes    int64
+	total    int64
+	last     time.Time
+	progress func(int64, int64)
+}
+
+// readerWithProgress returns body, wrapped so as to tell of its
+// progress, if ctx wants to hear of it; offset bytes are already in hand
+func readerWithProgress(ctx context.Context, body io.Reader, offset int64, length int64) io.Reader {
+	progress, ok := ctx.Value(downloadProgressKey{}).(func(int64, int64))
+	if !ok {
+		return body
+	}
+	total := int64(-1)
+	if length >= 0 {
+		total = offset + length
+	}
+	return &progressReader{Reader: body, bytes: offset, total: total, progress: progress}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.bytes += int64(n)
+	if time.Since(r.last) >= progressInterval {
+		r.progress(r.bytes, r.total)
+		r.last = time.Now()
+	}
+	return n, err
+}
@@ -0,0 +1,130 @@
+package kui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// an EventHandler that keeps what it is sent
+type eventRecorder struct {
+	sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) HandleEvent(event Event) {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, event)
+}
+
+// phases returns the phases of the recorded events, without repeats
+func (r *eventRecorder) phases() []Phase {
+	r.Lock()
+	defer r.Unlock()
+	phases := []Phase{}
+	for _, event := range r.events {
+		if len(phases) == 0 || phases[len(phases)-1] != event.Phase() {
+			phases = append(phases, event.Phase())
+		}
+	}
+	return phases
+}
+
+// last returns the last recorded event of the given phase
+func (r *eventRecorder) last(phase Phase) Event {
+	r.Lock()
+	defer r.Unlock()
+	for idx := len(r.events) - 1; idx >= 0; idx-- {
+		if r.events[idx].Phase() == phase {
+			return r.events[idx]
+		}
+	}
+	return nil
+}
+
+func (suite *KaskTestSuite) TestDownloadEvents() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	server := serveDist(dist)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	recorder := &eventRecorder{}
+	context := suite.pluginContext.WithEventHandler(recorder)
+	_, err := suite.cmd.DownloadDistIfNecessary(context, false)
+	suite.Require().Nil(err)
+	suite.Equal([]Phase{PhaseResolving, PhaseDownloading, PhaseLinking, PhaseExtracting}, recorder.phases())
+
+	downloaded := recorder.last(PhaseDownloading).(DownloadingEvent)
+	suite.True(downloaded.Complete)
+	suite.Equal(int64(len(dist)), downloaded.Bytes)
+	extracted := recorder.last(PhaseExtracting).(ExtractingEvent)
+	suite.True(extracted.Complete)
+	suite.Equal(1, extracted.Entries)
+
+	// nothing to do, once cached
+	recorder = &eventRecorder{}
+	_, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext.WithEventHandler(recorder), false)
+	suite.Require().Nil(err)
+	suite.Equal([]Phase{PhaseResolving}, recorder.phases())
+	suite.Equal(ResolvingEvent{suite.version, GetDistLocation(suite.pluginContext, suite.version)}, recorder.last(PhaseResolving))
+}
+
+func (suite *KaskTestSuite) TestRunEvents() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("FAKE_KUI_ARGS", filepath.Join(suite.SaveDir, "fake-kui-args"))()
+
+	recorder := &eventRecorder{}
+	suite.Require().Nil(suite.cmd.Run(suite.pluginContext.WithEventHandler(recorder), []string{"kask", "config", "--force"}))
+	suite.Equal([]Phase{PhaseResolving, PhaseDownloading, PhaseLinking, PhaseExtracting, PhaseRunning, PhaseDone}, recorder.phases())
+	running := recorder.last(PhaseRunning).(RunningEvent)
+	suite.Equal("config", running.Args[len(running.Args)-2])
+}
+
+func (suite *KaskTestSuite) TestRunErrorEvent() {
+	defer suite.isolate(unreachableURL())()
+
+	recorder := &eventRecorder{}
+	err := suite.cmd.Run(suite.pluginContext.WithEventHandler(recorder), []string{"kask", "list"})
+	suite.Require().NotNil(err)
+	suite.Equal([]Phase{PhaseResolving, PhaseError}, recorder.phases())
+	suite.True(errors.Is(recorder.last(PhaseError).(ErrorEvent).Err, err))
+}
+
+func (suite *KaskTestSuite) TestTerminalEventsNeedATerminal() {
+	out, err := os.Create(filepath.Join(suite.SaveDir, "not-a-terminal"))
+	suite.Require().Nil(err)
+	defer out.Close()
+
+	handler := &terminalEvents{out: out}
+	handler.HandleEvent(DownloadingEvent{Bytes: 10, Total: 10, Complete: true})
+	handler.HandleEvent(ExtractingEvent{Entries: 1, Complete: true})
+	info, err := out.Stat()
+	suite.Require().Nil(err)
+	suite.Zero(info.Size())
+}
+
+func (suite *KaskTestSuite) TestUpToDateEvent() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	checksum := sha256Hex(dist)
+	gets := 0
+	server := serveDistCountingGets(&dist, &checksum, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+
+	recorder := &eventRecorder{}
+	output := suite.captureStdout(func() {
+		_, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext.WithEventHandler(recorder), true)
+	})
+	suite.Require().Nil(err)
+	suite.Empty(output, "stdout is for what the command reports, not for how it got there")
+	suite.Equal(UpToDateEvent{suite.version, GetDistLocation(suite.pluginContext, suite.version)}, recorder.last(PhaseResolving))
+}
@@ -0,0 +1,380 @@
+package kui
+
+import (
+	"archive/tar"
+	"archive/zip"
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/mholt/archiver"
+)
+
+// ExtractOptions tune how the Kui base is unpacked
+type ExtractOptions struct {
+	// Include, if non-empty, is a glob over slash-separated archive
+	// paths; only entries that match it, or that lie beneath a
+	// directory that matches it, are extracted
+	Include string
+
+	// Progress, if non-nil, is called after each entry is extracted,
+	// with the number of entries extracted so far
+	Progress func(extracted int)
+
+	// Workers, if more than one, is how many entries of a zip archive
+	// are extracted at once, in order of name; otherwise, entries are
+	// extracted one by one, in archive order, as a tar stream must be.
+	// Either order is fixed by the archive, so identical archives
+	// extract identically, and BuildManifest sorts what they extract.
+	Workers int
+
+	// FileMode, if non-zero, masks the modes that the archive gives its
+	// files, e.g. 0750 to withhold them from others; the owner's
+	// permissions are never masked, lest the Kui base be unusable
+	FileMode os.FileMode
+}
+
+// the archive format of the dist at the given url, unless
+// KASK_DIST_FORMAT says otherwise
+func distFormat(context Context, url string) archiver.Walker {
+	if format, ok, _ := distFormatOverride(context); ok {
+		return format
+	}
+	if strings.HasSuffix(url, ".tar.bz2") {
+		return archiver.DefaultTarBz2
+	}
+	return archiver.DefaultZip
+}
+
+// GetExtractOptions returns the extraction options requested via the
+// environment; by default, the entire archive is extracted
+func GetExtractOptions(context Context) (ExtractOptions, error) {
+	options := ExtractOptions{Include: getenv(context, "KASK_EXTRACT_INCLUDE")}
+	if _, err := path.Match(options.Include, ""); err != nil {
+		return options, fmt.Errorf("invalid KASK_EXTRACT_INCLUDE %q: %v", options.Include, err)
+	}
+	workers, err := extractWorkers(context)
+	if err != nil {
+		return options, err
+	}
+	options.Workers = workers
+	if options.FileMode, err = fileModeMask(context); err != nil {
+		return options, err
+	}
+	if _, _, err := distFormatOverride(context); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// fileModeMask returns the mask, from the octal KASK_FILE_MODE, applied
+// to the modes of extracted files; 0 if unset, leaving them as the
+// archive has them
+func fileModeMask(context Context) (os.FileMode, error) {
+	value := getenv(context, "KASK_FILE_MODE")
+	if value == "" {
+		return 0, nil
+	}
+	mask, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mask > 0777 {
+		return 0, fmt.Errorf("invalid KASK_FILE_MODE %q: expected an octal mode, e.g. 0750", value)
+	}
+	return os.FileMode(mask), nil
+}
+
+// masked returns perm, masked by the given KASK_FILE_MODE, if any
+func masked(perm os.FileMode, mask os.FileMode) os.FileMode {
+	if mask == 0 {
+		return perm
+	}
+	return perm & (mask | 0700)
+}
+
+// includes returns whether the archive entry with the given name
+// should be extracted
+func (options ExtractOptions) includes(name string) bool {
+	if options.Include == "" {
+		return true
+	}
+	for name = strings.Trim(path.Clean(name), "/"); name != "." && name != "/"; name = path.Dir(name) {
+		if matched, _ := path.Match(options.Include, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// the path of the given entry within its archive
+func entryName(f archiver.File) string {
+	switch header := f.Header.(type) {
+	case zip.FileHeader:
+		return header.Name
+	case *tar.Header:
+		return header.Name
+	default:
+		return f.Name()
+	}
+}
+
+// Unarchive extracts the archive, of the given format, into
+// destination. We walk the entries ourselves, rather than using
+// archiver's Unarchive, so that file modes and symlinks survive; the
+// Kui base relies on both (executables on linux, and the framework
+// symlinks inside the app bundle on macOS).
+func Unarchive(format archiver.Walker, archive string, destination string, options ExtractOptions) error {
+	return UnarchiveContext(stdcontext.Background(), format, archive, destination, options)
+}
+
+// UnarchiveContext is Unarchive, stopping between entries if ctx is done
+func UnarchiveContext(ctx stdcontext.Context, format archiver.Walker, archive string, destination string, options ExtractOptions) error {
+	_, err := unarchive(ctx, format, archive, destination, options)
+	return err
+}
+
+// unarchive does the work of UnarchiveContext, returning the name of
+// the implementation that succeeded. Some producers write archives
+// that archiver cannot read, e.g. zips with bzip2-compressed entries;
+// if archiver fails, we retry with the standard library's readers.
+func unarchive(ctx stdcontext.Context, format archiver.Walker, archive string, destination string, options ExtractOptions) (string, error) {
+	if _, isZip := format.(*archiver.Zip); isZip && options.Workers > 1 {
+		if name, ok, err := unzipParallel(ctx, archive, destination, options); ok {
+			return name, err
+		}
+	}
+
+	extracted := 0
+	extract := func(f archiver.File) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := entryName(f)
+		if !options.includes(name) {
+			return nil
+		}
+		if err := writeEntry(f, destination, filepath.FromSlash(name), options.FileMode); err != nil {
+			return err
+		}
+		extracted++
+		if options.Progress != nil {
+			options.Progress(extracted)
+		}
+		return nil
+	}
+
+	// archiver flattens the errors we return into strings, so keep
+	// hold of the original for our callers
+	var entryErr error
+
+	err := walkArchive(format, archive, func(f archiver.File) error {
+		entryErr = extract(f)
+		return entryErr
+	})
+
+	if entryErr != nil {
+		return "", entryErr
+	}
+	if err == nil {
+		return "archiver", nil
+	}
+
+	// the archive itself, rather than one of its entries, defeated archiver
+	fallback, ok := stdlibWalker(format)
+	if !ok {
+		return "", err
+	}
+	extracted = 0
+	if fallbackErr := fallback.walk(archive, extract); fallbackErr != nil {
+		return "", fmt.Errorf("%v (and with %s: %w)", err, fallback.name, fallbackErr)
+	}
+	return fallback.name, nil
+}
+
+// walkArchive is format.Walk, except that a panic within archiver
+// (as it does, e.g., for zip entries of an unsupported compression
+// method) is returned as an error
+func walkArchive(format archiver.Walker, archive string, walkFn archiver.WalkFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unable to read %s: %v", archive, r)
+		}
+	}()
+	return format.Walk(archive, walkFn)
+}
+
+// ErrUnsafeArchivePath is returned for an archive entry that would be
+// written, or would link, outside of the extraction directory (aka
+// "Zip Slip")
+var ErrUnsafeArchivePath = errors.New("archive entry escapes the extraction directory")
+
+// within returns whether path lies in or at dir
+func within(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveExisting resolves the symlinks of path, as far as it exists
+func resolveExisting(path string) (string, error) {
+	rest := ""
+	for existing := path; ; {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path, nil
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+// checkEntryPath verifies that extracting the entry with the given
+// name to target, and (if it is a link) pointing it at linkname, stays
+// within destination; not only as the paths are written, but also as
+// they resolve through the symlinks already extracted
+func checkEntryPath(destination string, name string, target string, linkname string, isSymlink bool) error {
+	if filepath.IsAbs(name) || !within(destination, target) {
+		return fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+
+	root, err := resolveExisting(destination)
+	if err != nil {
+		return err
+	}
+	parent := root
+	if filepath.Clean(target) != filepath.Clean(destination) {
+		if parent, err = resolveExisting(filepath.Dir(target)); err != nil {
+			return err
+		}
+		if !within(root, parent) {
+			return fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+		}
+	}
+	if linkname == "" {
+		return nil
+	}
+
+	var written, resolved string
+	if isSymlink {
+		if filepath.IsAbs(linkname) {
+			return fmt.Errorf("%w: %s -> %s", ErrUnsafeArchivePath, name, linkname)
+		}
+		written = filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))
+		resolved, err = resolveExisting(filepath.Join(parent, filepath.FromSlash(linkname)))
+	} else {
+		written = filepath.Join(destination, filepath.FromSlash(linkname))
+		resolved, err = resolveExisting(written)
+	}
+	if err != nil {
+		return err
+	}
+	if !within(destination, written) || !within(root, resolved) {
+		return fmt.Errorf("%w: %s -> %s", ErrUnsafeArchivePath, name, linkname)
+	}
+	return nil
+}
+
+// writeEntry writes the given archive entry to name, relative to
+// destination, with the mode the archive gives it, masked by mask
+func writeEntry(f archiver.File, destination string, name string, mask os.FileMode) error {
+	target := filepath.Join(destination, name)
+	if err := checkEntryPath(destination, name, target, "", false); err != nil {
+		return err
+	}
+	if f.IsDir() {
+		return os.MkdirAll(target, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	if header, ok := f.Header.(*tar.Header); ok {
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			if err := checkEntryPath(destination, name, target, header.Linkname, true); err != nil {
+				return err
+			}
+			return replaceWithSymlink(header.Linkname, target)
+		case tar.TypeLink:
+			if err := checkEntryPath(destination, name, target, header.Linkname, false); err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Link(filepath.Join(destination, filepath.FromSlash(header.Linkname)), target)
+		}
+	} else if f.Mode()&os.ModeSymlink != 0 {
+		// zip stores the target of a symlink as the content of the entry
+		linkname, err := ioutil.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if err := checkEntryPath(destination, name, target, string(linkname), true); err != nil {
+			return err
+		}
+		return replaceWithSymlink(string(linkname), target)
+	}
+
+	perm := masked(f.Mode().Perm(), mask)
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, f); err != nil {
+		return err
+	}
+
+	// the mode passed to OpenFile is subject to umask, and is not
+	// applied at all if the file already existed
+	if err := out.Chmod(perm); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
+	return nil
+}
+
+func replaceWithSymlink(linkname string, target string) error {
+	os.Remove(target)
+	return os.Symlink(linkname, target)
+}
+
+// ensureExecutable verifies that the given file exists and, on
+// platforms that have such a thing, that it is executable, repairing
+// its mode if need be
+func ensureExecutable(file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("Kui base is missing its executable: %v", err)
+	}
+	if runtime.GOOS == "windows" || info.Mode()&0111 != 0 {
+		return nil
+	}
+	return MakeExecutable(file)
+}
+
+// repairExecutable restores the execute bits of a cached Kui executable,
+// which copying the cache between machines, or restoring it from a
+// backup, may have dropped; it reports whether a repair was needed
+func repairExecutable(file string) (bool, error) {
+	if runtime.GOOS == "windows" {
+		return false, nil
+	}
+	info, err := os.Stat(file)
+	if err != nil || info.Mode()&0111 != 0 {
+		// a missing executable is for the run itself to report
+		return false, nil
+	}
+	return true, MakeExecutable(file)
+}
@@ -0,0 +1,179 @@
+package kui
+
+import (
+	"archive/zip"
+	"compress/bzip2"
+	stdcontext "context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mholt/archiver"
+)
+
+// the most workers we use by default; beyond this, extraction is
+// bound by the disk rather than by decompression
+const maxDefaultExtractWorkers = 8
+
+// extractWorkers returns how many entries we extract at once, from
+// KASK_EXTRACT_WORKERS; by default, one per CPU, up to
+// maxDefaultExtractWorkers
+func extractWorkers(context Context) (int, error) {
+	value, isSet := lookupEnv(context, "KASK_EXTRACT_WORKERS")
+	if !isSet {
+		workers := runtime.NumCPU()
+		if workers > maxDefaultExtractWorkers {
+			workers = maxDefaultExtractWorkers
+		}
+		return workers, nil
+	}
+	workers, err := strconv.Atoi(value)
+	if err != nil || workers < 1 {
+		return 0, fmt.Errorf("invalid KASK_EXTRACT_WORKERS %q", value)
+	}
+	return workers, nil
+}
+
+// unzipParallel extracts the zip archive using options.Workers
+// workers; unlike a tar stream, the entries of a zip may be
+// decompressed independently. Directories are created up front, so
+// that no worker races another to create a parent; and symlinks are
+// created last, so that no file is written through one. Each kind is
+// handled in order of name, whatever the order of the archive, so that
+// identical archives extract identically. It returns false, having
+// done nothing, if the archive cannot be opened as a zip.
+func unzipParallel(ctx stdcontext.Context, archive string, destination string, options ExtractOptions) (string, bool, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return "", false, nil
+	}
+	defer zr.Close()
+
+	zr.RegisterDecompressor(zipBzip2, func(r io.Reader) io.ReadCloser {
+		return ioutil.NopCloser(bzip2.NewReader(r))
+	})
+
+	start := time.Now()
+
+	// a later entry of the same name replaces an earlier one
+	latest := map[string]int{}
+	for idx, zf := range zr.File {
+		latest[filepath.Clean(zf.Name)] = idx
+	}
+	var entries []*zip.File
+	for idx, zf := range zr.File {
+		if latest[filepath.Clean(zf.Name)] == idx && options.includes(zf.Name) {
+			entries = append(entries, zf)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	var files []*zip.File
+	var symlinks []*zip.File
+	for _, zf := range entries {
+		if zf.FileInfo().IsDir() {
+			if err := writeZipEntry(zf, destination, options.FileMode); err != nil {
+				return "", true, err
+			}
+		} else if zf.Mode()&os.ModeSymlink != 0 {
+			symlinks = append(symlinks, zf)
+		} else {
+			target := filepath.Join(destination, filepath.FromSlash(zf.Name))
+			if err := checkEntryPath(destination, zf.Name, target, "", false); err != nil {
+				return "", true, err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", true, err
+			}
+			files = append(files, zf)
+		}
+	}
+
+	var mutex sync.Mutex
+	extracted := 0
+	var busy time.Duration
+	var firstErr error
+	done := func(took time.Duration, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		busy += took
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		extracted++
+		if options.Progress != nil {
+			options.Progress(extracted)
+		}
+	}
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return firstErr != nil
+	}
+
+	work := make(chan *zip.File)
+	var wg sync.WaitGroup
+	for worker := 0; worker < options.Workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for zf := range work {
+				began := time.Now()
+				err := ctx.Err()
+				if err == nil {
+					err = writeZipEntry(zf, destination, options.FileMode)
+				}
+				done(time.Since(began), err)
+			}
+		}()
+	}
+	for _, zf := range files {
+		if failed() {
+			break
+		}
+		work <- zf
+	}
+	close(work)
+	wg.Wait()
+	if firstErr != nil {
+		return "", true, firstErr
+	}
+
+	for _, zf := range symlinks {
+		if err := ctx.Err(); err != nil {
+			return "", true, err
+		}
+		err := writeZipEntry(zf, destination, options.FileMode)
+		done(0, err)
+		if err != nil {
+			return "", true, err
+		}
+	}
+
+	// how much longer one worker would have taken, roughly
+	elapsed := time.Since(start)
+	speedup := 1.0
+	if elapsed > 0 {
+		speedup = float64(busy) / float64(elapsed)
+	}
+	return fmt.Sprintf("archive/zip with %d workers, %.1fx speedup", options.Workers, speedup), true, nil
+}
+
+func writeZipEntry(zf *zip.File, destination string, mask os.FileMode) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return writeEntry(archiver.File{FileInfo: zf.FileInfo(), Header: zf.FileHeader, ReadCloser: rc}, destination, filepath.FromSlash(zf.Name), mask)
+}
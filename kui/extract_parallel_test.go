@@ -0,0 +1,69 @@
+package kui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mholt/archiver"
+)
+
+func (suite *KaskTestSuite) TestUnarchiveParallel() {
+	suite.skipUnlessLinux()
+	var entries []fakeEntry
+	for dir := 0; dir < 8; dir++ {
+		for file := 0; file < 16; file++ {
+			name := fmt.Sprintf("base/dir%d/sub%d/file%d", dir, file%3, file)
+			entries = append(entries, fakeEntry{name, name, os.FileMode(0644 | (file%2)*0111)})
+		}
+	}
+	entries = append(entries, fakeEntry{"base/link", "dir0", os.ModeSymlink | 0777})
+	archive := suite.writeZip(entries...)
+
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+	extracted := 0
+	name, err := unarchive(context.Background(), archiver.DefaultZip, archive, destination, ExtractOptions{Workers: 4, Progress: func(n int) { extracted = n }})
+	suite.Require().Nil(err)
+	suite.Contains(name, "4 workers")
+	suite.Equal(len(entries), extracted)
+
+	for _, entry := range entries[:len(entries)-1] {
+		target := filepath.Join(destination, filepath.FromSlash(entry.name))
+		body, err := ioutil.ReadFile(target)
+		suite.Require().Nil(err)
+		suite.Equal(entry.body, string(body))
+		info, _ := os.Stat(target)
+		suite.Equal(entry.mode.Perm(), info.Mode().Perm())
+	}
+	linkname, err := os.Readlink(filepath.Join(destination, "base", "link"))
+	suite.Nil(err)
+	suite.Equal("dir0", linkname)
+}
+
+func (suite *KaskTestSuite) TestUnarchiveParallelRejectsZipSlip() {
+	archive := suite.writeZip(fakeEntry{"base/ok", "", 0644}, fakeEntry{"../evil", "pwned", 0644})
+	parent, _ := ioutil.TempDir(suite.SaveDir, "slip")
+
+	_, err := unarchive(context.Background(), archiver.DefaultZip, archive, filepath.Join(parent, "extract"), ExtractOptions{Workers: 4})
+	suite.True(errors.Is(err, ErrUnsafeArchivePath))
+	_, err = os.Stat(filepath.Join(parent, "evil"))
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *KaskTestSuite) TestExtractWorkers() {
+	workers, err := extractWorkers(suite.pluginContext)
+	suite.Nil(err)
+	suite.True(workers >= 1 && workers <= maxDefaultExtractWorkers)
+
+	defer setenv("KASK_EXTRACT_WORKERS", "3")()
+	workers, err = extractWorkers(suite.pluginContext)
+	suite.Nil(err)
+	suite.Equal(3, workers)
+
+	defer setenv("KASK_EXTRACT_WORKERS", "0")()
+	_, err = extractWorkers(suite.pluginContext)
+	suite.NotNil(err)
+}
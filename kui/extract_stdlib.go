@@ -0,0 +1,83 @@
+package kui
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/mholt/archiver"
+)
+
+// the zip compression method number of bzip2, which archive/zip does
+// not support out of the box
+const zipBzip2 = 12
+
+// a reader of archives built only from the standard library, used when
+// archiver cannot cope with an archive
+type stdlibArchive struct {
+	name string
+	walk func(archive string, walkFn archiver.WalkFunc) error
+}
+
+// stdlibWalker returns the standard library reader for the given
+// archiver format, if we have one
+func stdlibWalker(format archiver.Walker) (stdlibArchive, bool) {
+	switch format.(type) {
+	case *archiver.Zip:
+		return stdlibArchive{"archive/zip", walkZip}, true
+	case *archiver.TarBz2:
+		return stdlibArchive{"compress/bzip2+archive/tar", walkTarBz2}, true
+	default:
+		return stdlibArchive{}, false
+	}
+}
+
+func walkZip(archive string, walkFn archiver.WalkFunc) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	zr.RegisterDecompressor(zipBzip2, func(r io.Reader) io.ReadCloser {
+		return ioutil.NopCloser(bzip2.NewReader(r))
+	})
+
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = walkFn(archiver.File{FileInfo: zf.FileInfo(), Header: zf.FileHeader, ReadCloser: rc})
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTarBz2(archive string, walkFn archiver.WalkFunc) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(bzip2.NewReader(f))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := walkFn(archiver.File{FileInfo: header.FileInfo(), Header: header, ReadCloser: ioutil.NopCloser(tr)}); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package kui
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/mholt/archiver"
+)
+
+// writes a zip whose entries are bzip2-compressed, as e.g. 7-Zip can
+// produce; archiver cannot read these
+func (suite *KaskTestSuite) writeBzip2Zip(entries ...fakeEntry) string {
+	f, err := ioutil.TempFile(suite.SaveDir, "archive-*.zip")
+	suite.Require().Nil(err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	w.RegisterCompressor(zipBzip2, func(out io.Writer) (io.WriteCloser, error) {
+		return bzip2.NewWriter(out, nil)
+	})
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zipBzip2}
+		header.SetMode(entry.mode)
+		out, err := w.CreateHeader(header)
+		suite.Require().Nil(err)
+		out.Write([]byte(entry.body))
+	}
+	suite.Require().Nil(w.Close())
+	return f.Name()
+}
+
+func (suite *KaskTestSuite) TestUnarchivePrefersArchiver() {
+	archive := suite.writeZip(fakeEntry{"base/one", "1", 0644})
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+
+	extractor, err := unarchive(context.Background(), archiver.DefaultZip, archive, destination, ExtractOptions{})
+	suite.Nil(err)
+	suite.Equal("archiver", extractor)
+}
+
+func (suite *KaskTestSuite) TestUnarchiveFallsBackToStdlib() {
+	archive := suite.writeBzip2Zip(
+		fakeEntry{"base/one", "1", 0644},
+		fakeEntry{"base/bin/run", "#!/bin/sh\n", 0755},
+	)
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+
+	extractor, err := unarchive(context.Background(), archiver.DefaultZip, archive, destination, ExtractOptions{})
+	suite.Require().Nil(err)
+	suite.Equal("archive/zip", extractor)
+
+	body, err := ioutil.ReadFile(filepath.Join(destination, "base", "one"))
+	suite.Nil(err)
+	suite.Equal("1", string(body))
+
+	info, err := os.Stat(filepath.Join(destination, "base", "bin", "run"))
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0755), info.Mode().Perm())
+}
+
+func (suite *KaskTestSuite) TestUnarchiveFallbackStillChecksPaths() {
+	archive := suite.writeBzip2Zip(fakeEntry{"../escape", "x", 0644})
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+
+	err := Unarchive(archiver.DefaultZip, archive, destination, ExtractOptions{})
+	suite.True(errors.Is(err, ErrUnsafeArchivePath), "expected an unsafe path error, got %v", err)
+}
+
+func (suite *KaskTestSuite) TestUnarchiveCorruptArchive() {
+	archive := filepath.Join(suite.SaveDir, "corrupt.zip")
+	suite.Require().Nil(ioutil.WriteFile(archive, []byte("this is not a zip file"), 0644))
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+
+	err := Unarchive(archiver.DefaultZip, archive, destination, ExtractOptions{})
+	suite.NotNil(err)
+}
@@ -0,0 +1,256 @@
+package kui
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/mholt/archiver"
+)
+
+// writes a zip with the given entries into the suite's scratch space
+func (suite *KaskTestSuite) writeZip(entries ...fakeEntry) string {
+	f, err := ioutil.TempFile(suite.SaveDir, "archive-*.zip")
+	suite.Require().Nil(err)
+	defer f.Close()
+	_, err = f.Write(makeZip(entries...))
+	suite.Require().Nil(err)
+	return f.Name()
+}
+
+func (suite *KaskTestSuite) TestUnarchiveSubset() {
+	archive := suite.writeZip(
+		fakeEntry{"base/keep/one", "1", 0644},
+		fakeEntry{"base/keep/nested/two", "2", 0644},
+		fakeEntry{"base/drop/three", "3", 0644},
+		fakeEntry{"base/top", "4", 0644},
+	)
+
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+	err := Unarchive(archiver.DefaultZip, archive, destination, ExtractOptions{Include: "base/keep"})
+	suite.Nil(err)
+
+	suite.FileExists(filepath.Join(destination, "base", "keep", "one"))
+	suite.FileExists(filepath.Join(destination, "base", "keep", "nested", "two"))
+	_, err = os.Stat(filepath.Join(destination, "base", "drop"))
+	suite.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(destination, "base", "top"))
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *KaskTestSuite) TestUnarchiveSubsetWithGlob() {
+	archive := suite.writeZip(
+		fakeEntry{"base/a.txt", "a", 0644},
+		fakeEntry{"base/b.bin", "b", 0644},
+	)
+
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+	err := Unarchive(archiver.DefaultZip, archive, destination, ExtractOptions{Include: "*/*.txt"})
+	suite.Nil(err)
+
+	suite.FileExists(filepath.Join(destination, "base", "a.txt"))
+	_, err = os.Stat(filepath.Join(destination, "base", "b.bin"))
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *KaskTestSuite) TestInvalidExtractInclude() {
+	defer setenv("KASK_EXTRACT_INCLUDE", "[")()
+	_, err := GetExtractOptions(suite.pluginContext)
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestUnarchivePreservesModesAndSymlinks() {
+	suite.skipUnlessLinux()
+	archive := suite.writeZip(
+		fakeEntry{"base/bin/tool", "#!/bin/sh\n", 0755},
+		fakeEntry{"base/lib/libkui.so.1", "library", 0644},
+		fakeEntry{"base/lib/libkui.so", "libkui.so.1", os.ModeSymlink | 0777},
+	)
+
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+	suite.Require().Nil(Unarchive(archiver.DefaultZip, archive, destination, ExtractOptions{}))
+
+	tool, err := os.Stat(filepath.Join(destination, "base", "bin", "tool"))
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0755), tool.Mode().Perm())
+
+	library, err := os.Stat(filepath.Join(destination, "base", "lib", "libkui.so.1"))
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0644), library.Mode().Perm())
+
+	linkname, err := os.Readlink(filepath.Join(destination, "base", "lib", "libkui.so"))
+	suite.Nil(err)
+	suite.Equal("libkui.so.1", linkname)
+}
+
+func (suite *KaskTestSuite) TestRootCommandMadeExecutable() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeZip(fakeEntry{filepath.ToSlash(rootCommandPath(suite.pluginContext, PlatformKey())), fakeKuiScript, 0644}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	cmd, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	info, err := os.Stat(cmd.Path)
+	suite.Nil(err)
+	suite.NotZero(info.Mode() & 0100)
+}
+
+func (suite *KaskTestSuite) TestOnlyTheRootCommandMadeExecutable() {
+	suite.skipUnlessLinux()
+	root := filepath.ToSlash(rootCommandPath(suite.pluginContext, PlatformKey()))
+	data := path.Join(path.Dir(root), "resources", "app.asar")
+	server := serveDist(makeZip(fakeEntry{root, fakeKuiScript, 0644}, fakeEntry{data, "data", 0644}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	cmd, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	info, err := os.Stat(cmd.Path)
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0755), info.Mode().Perm())
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	info, err = os.Stat(filepath.Join(pluginDir, "cache-"+suite.version, "extract", filepath.FromSlash(data)))
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0644), info.Mode().Perm(), "a data file should keep its mode")
+}
+
+func (suite *KaskTestSuite) TestFileModeMask() {
+	suite.skipUnlessLinux()
+	root := filepath.ToSlash(rootCommandPath(suite.pluginContext, PlatformKey()))
+	data := path.Join(path.Dir(root), "resources", "app.asar")
+	server := serveDist(makeZip(fakeEntry{root, fakeKuiScript, 0777}, fakeEntry{data, "data", 0666}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_FILE_MODE", "0750")()
+
+	cmd, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	info, err := os.Stat(cmd.Path)
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0750), info.Mode().Perm())
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	info, err = os.Stat(filepath.Join(pluginDir, "cache-"+suite.version, "extract", filepath.FromSlash(data)))
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0640), info.Mode().Perm())
+}
+
+func (suite *KaskTestSuite) TestInvalidFileModeMask() {
+	suite.Equal(os.FileMode(0640), masked(0666, 0750))
+	suite.Equal(os.FileMode(0705), masked(0777, 0005), "the owner's permissions are never masked")
+	suite.Equal(os.FileMode(0777), masked(0777, 0))
+
+	for _, value := range []string{"rwx", "0999", "01777"} {
+		restore := setenv("KASK_FILE_MODE", value)
+		_, err := GetExtractOptions(suite.pluginContext)
+		suite.NotNil(err, value)
+		restore()
+	}
+}
+
+func (suite *KaskTestSuite) TestCachedRootCommandRepaired() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	cmd, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+
+	// as if restored from a backup that drops the execute bit
+	suite.Require().Nil(os.Chmod(cmd.Path, 0644))
+
+	cmd, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	info, err := os.Stat(cmd.Path)
+	suite.Nil(err)
+	suite.Equal(os.FileMode(0755), info.Mode().Perm())
+}
+
+func (suite *KaskTestSuite) TestMissingRootCommand() {
+	server := serveDist(makeZip(fakeEntry{"unexpected/layout", "", 0644}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(ExitExtract, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) assertUnsafe(entries ...fakeEntry) {
+	archive := suite.writeZip(entries...)
+	parent, _ := ioutil.TempDir(suite.SaveDir, "slip")
+	destination := filepath.Join(parent, "extract")
+
+	err := Unarchive(archiver.DefaultZip, archive, destination, ExtractOptions{})
+	suite.True(errors.Is(err, ErrUnsafeArchivePath), "expected an unsafe path error, got %v", err)
+	_, err = os.Stat(filepath.Join(parent, "evil"))
+	suite.True(os.IsNotExist(err), "nothing may be written outside the extract directory")
+}
+
+func (suite *KaskTestSuite) TestUnarchiveRejectsZipSlip() {
+	suite.assertUnsafe(fakeEntry{"../evil", "pwned", 0644})
+	suite.assertUnsafe(fakeEntry{"base/../../evil", "pwned", 0644})
+	suite.assertUnsafe(fakeEntry{"base/link", "../../evil", os.ModeSymlink | 0777})
+	suite.assertUnsafe(fakeEntry{"base/link", "/etc/passwd", os.ModeSymlink | 0777})
+}
+
+func (suite *KaskTestSuite) TestUnarchiveRejectsChainedSymlinks() {
+	suite.skipUnlessLinux()
+	// each link stays inside, as written, but a/b lands at b, and so
+	// points at the parent of the extract directory
+	entries := []fakeEntry{
+		{"a", ".", os.ModeSymlink | 0777},
+		{"a/b", "..", os.ModeSymlink | 0777},
+		{"b/evil", "pwned", 0644},
+	}
+	for _, workers := range []int{1, 4} {
+		archive := suite.writeZip(entries...)
+		parent, _ := ioutil.TempDir(suite.SaveDir, "slip")
+		destination := filepath.Join(parent, "extract")
+
+		err := Unarchive(archiver.DefaultZip, archive, destination, ExtractOptions{Workers: workers})
+		suite.True(errors.Is(err, ErrUnsafeArchivePath), "expected an unsafe path error, got %v", err)
+		_, err = os.Stat(filepath.Join(parent, "evil"))
+		suite.True(os.IsNotExist(err), "nothing may be written outside the extract directory")
+	}
+}
+
+func (suite *KaskTestSuite) TestUnarchiveAllowsInternalSymlinks() {
+	suite.skipUnlessLinux()
+	archive := suite.writeZip(
+		fakeEntry{"base/Versions/A/lib", "lib", 0644},
+		fakeEntry{"base/Current", "Versions/A", os.ModeSymlink | 0777},
+		fakeEntry{"base/nested/up", "../Versions", os.ModeSymlink | 0777},
+	)
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+	suite.Nil(Unarchive(archiver.DefaultZip, archive, destination, ExtractOptions{}))
+}
+
+func (suite *KaskTestSuite) TestZipSlipDist() {
+	server := serveDist(makeZip(fakeEntry{"../../evil", "pwned", 0644}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(ExitExtract, ExitCode(err))
+	suite.True(errors.Is(err, ErrUnsafeArchivePath))
+}
+
+func (suite *KaskTestSuite) TestUnarchiveReportsProgress() {
+	archive := suite.writeZip(
+		fakeEntry{"base/one", "1", 0644},
+		fakeEntry{"base/two", "2", 0644},
+		fakeEntry{"skipped/three", "3", 0644},
+		fakeEntry{"base/four", "4", 0644},
+	)
+	destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+
+	var calls []int
+	options := ExtractOptions{Include: "base", Progress: func(extracted int) { calls = append(calls, extracted) }}
+	suite.Nil(Unarchive(archiver.DefaultZip, archive, destination, options))
+	suite.Equal([]int{1, 2, 3}, calls)
+}
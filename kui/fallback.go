@@ -0,0 +1,118 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// the versions index is a list of versions; anything bigger than this
+// is not one
+const maxDistVersionsSize = 64 * 1024
+
+// whether, if the dist host lacks the requested Kui base, we may run
+// the latest earlier version listed in the KASK_DIST_VERSIONS index
+func versionFallbackAllowed(context Context) bool {
+	_, allowed := lookupEnv(context, "KASK_ALLOW_VERSION_FALLBACK")
+	return allowed
+}
+
+// distNotFound returns whether err says that the dist host does not
+// have the dist at all, e.g. because it was pulled
+func distNotFound(err error) bool {
+	var statusErr *HTTPStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// earlierDistVersion returns the latest version, earlier than the given
+// one, in the versions index at KASK_DIST_VERSIONS
+func earlierDistVersion(context Context, version string) (string, error) {
+	location := getenv(context, "KASK_DIST_VERSIONS")
+	if location == "" {
+		return "", fmt.Errorf("KASK_ALLOW_VERSION_FALLBACK is set, but KASK_DIST_VERSIONS does not name a versions index")
+	}
+
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), distLatestTimeout)
+	defer cancel()
+
+	var body []byte
+	err := withRetries(ctx, context, func() error {
+		var err error
+		body, err = readDistVersions(ctx, context, location)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(body) > maxDistVersionsSize {
+		return "", fmt.Errorf("versions index %s is too large", location)
+	}
+	versions, err := parseDistVersions(body)
+	if err != nil {
+		return "", err
+	}
+
+	earlier := ""
+	for _, candidate := range versions {
+		if newerVersion(version, candidate) && (earlier == "" || newerVersion(candidate, earlier)) {
+			earlier = candidate
+		}
+	}
+	if earlier == "" {
+		return "", fmt.Errorf("versions index %s has no version earlier than %s", location, version)
+	}
+	return earlier, nil
+}
+
+func readDistVersions(ctx stdcontext.Context, context Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doRequest(context, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPStatusError{url, resp.StatusCode, resp.Status}
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxDistVersionsSize+1))
+}
+
+// parseDistVersions reads a versions index: a json array of versions,
+// an object such as {"versions": [...]}, or one version per line
+func parseDistVersions(body []byte) ([]string, error) {
+	text := strings.TrimSpace(string(body))
+
+	var versions []string
+	switch {
+	case strings.HasPrefix(text, "["):
+		if err := json.Unmarshal(body, &versions); err != nil {
+			return nil, fmt.Errorf("unable to parse the versions index: %v", err)
+		}
+	case strings.HasPrefix(text, "{"):
+		var index struct {
+			Versions []string `json:"versions"`
+		}
+		if err := json.Unmarshal(body, &index); err != nil {
+			return nil, fmt.Errorf("unable to parse the versions index: %v", err)
+		}
+		versions = index.Versions
+	default:
+		versions = strings.Fields(text)
+	}
+
+	for idx, version := range versions {
+		versions[idx] = strings.TrimSpace(version)
+		if !distVersionPattern.MatchString(versions[idx]) {
+			return nil, fmt.Errorf("invalid version %q in the versions index", version)
+		}
+	}
+	return versions, nil
+}
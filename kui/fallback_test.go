@@ -0,0 +1,74 @@
+package kui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// a dist host that has only the given versions, serving the fake dist
+// at /<version>/dist.zip, and listing them all (and more) in its
+// versions index
+func serveDistVersions(available ...string) *httptest.Server {
+	dist := makeFakeDist()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/versions.json" {
+			w.Write([]byte(`{"versions": ["1.0.0", "1.9.0", "1.10.0", "2.0.0", "2.1.0"]}`))
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		for _, version := range available {
+			if r.URL.Path == "/"+version+"/dist.zip" {
+				w.Write(dist)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+func (suite *KaskTestSuite) TestVersionFallback() {
+	suite.skipUnlessLinux()
+	server := serveDistVersions("1.9.0", "1.10.0")
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_URL_TEMPLATE", server.URL+"/{version}/dist.zip")()
+	defer setenv("KASK_DIST_VERSIONS", server.URL+"/versions.json")()
+
+	// without the fallback, a missing version is a failure
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "2.0.0", false)
+	suite.Equal(ExitDownload, ExitCode(err))
+
+	defer setenv("KASK_ALLOW_VERSION_FALLBACK", "true")()
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "2.0.0", false)
+	suite.Require().Nil(err)
+	status, err := suite.cmd.CacheStatusOf(suite.pluginContext, "1.10.0")
+	suite.Require().Nil(err)
+	suite.True(status.SuccessExists, "the latest earlier version should be installed")
+}
+
+func (suite *KaskTestSuite) TestNoEarlierVersion() {
+	server := serveDistVersions()
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_URL_TEMPLATE", server.URL+"/{version}/dist.zip")()
+	defer setenv("KASK_DIST_VERSIONS", server.URL+"/versions.json")()
+	defer setenv("KASK_ALLOW_VERSION_FALLBACK", "true")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "0.9.0", false)
+	suite.Equal(ExitDownload, ExitCode(err))
+	suite.Contains(err.Error(), "0.9.0")
+}
+
+func (suite *KaskTestSuite) TestParseDistVersions() {
+	for _, body := range []string{`["1.0.0", "1.1.0"]`, `{"versions": ["1.0.0", "1.1.0"]}`, "1.0.0\n1.1.0\n"} {
+		versions, err := parseDistVersions([]byte(body))
+		suite.Nil(err, body)
+		suite.Equal([]string{"1.0.0", "1.1.0"}, versions, body)
+	}
+	_, err := parseDistVersions([]byte(`["../1.0.0"]`))
+	suite.NotNil(err)
+}
@@ -0,0 +1,129 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// Validators are the HTTP cache validators of a previously fetched
+// resource; sending them back lets the server answer 304 Not Modified
+// rather than resending the content
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+func (v Validators) empty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// createFile opens the file that a download is written to; a variable,
+// so that tests may simulate failing filesystems
+var createFile = func(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
+
+// HTTPStatusError is returned for a response with an unexpected status
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected response fetching %s: %s", e.URL, e.Status)
+}
+
+// ConditionalGet fetches url into filepath, unless the server reports
+// that the content is unchanged from that described by previous. It
+// returns whether filepath was (re)written, along with the validators
+// of the current content. The file is left untouched when unchanged.
+func ConditionalGet(context Context, url string, filepath string, previous Validators) (bool, Validators, error) {
+	return ConditionalGetContext(stdcontext.Background(), context, url, filepath, previous)
+}
+
+// ConditionalGetContext is ConditionalGet, abandoning the request if
+// ctx is done before it completes
+func ConditionalGetContext(ctx stdcontext.Context, context Context, url string, filepath string, previous Validators) (bool, Validators, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, previous, err
+	}
+	if previous.ETag != "" {
+		req.Header.Set("If-None-Match", previous.ETag)
+	}
+	if previous.LastModified != "" {
+		req.Header.Set("If-Modified-Since", previous.LastModified)
+	}
+
+	resp, err := doRequest(context, req)
+	if err != nil {
+		return false, previous, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && !previous.empty() {
+		return false, previous, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, previous, &HTTPStatusError{url, resp.StatusCode, resp.Status}
+	}
+
+	if err := writeBody(filepath, resp); err != nil {
+		os.Remove(filepath)
+		return false, previous, err
+	}
+
+	current := Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	return true, current, nil
+}
+
+// writeBody writes the body of resp to filepath. Some filesystems
+// report write errors only on close, and a short copy may otherwise go
+// unnoticed, so we check both.
+func writeBody(filepath string, resp *http.Response) error {
+	out, err := createFile(filepath)
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(out, resp.Body)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return fmt.Errorf("short download of %s: expected %d bytes, got %d", resp.Request.URL, resp.ContentLength, written)
+	}
+	return nil
+}
+
+// readValidators returns the validators stored in the given file; a
+// missing or unreadable file yields empty validators, which forces an
+// unconditional fetch
+func readValidators(filepath string) Validators {
+	var validators Validators
+	if bytes, err := ioutil.ReadFile(filepath); err == nil {
+		json.Unmarshal(bytes, &validators)
+	}
+	return validators
+}
+
+func writeValidators(filepath string, validators Validators) error {
+	bytes, err := json.Marshal(validators)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath, bytes, 0644)
+}
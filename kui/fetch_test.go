@@ -0,0 +1,204 @@
+package kui
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// a dist host that honors If-None-Match for a single, fixed ETag
+func serveWithETag(body []byte, etag string, gets *int, notModified *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "HEAD" {
+			// the store looks fine; only fetches are counted
+			w.Header().Set("ETag", etag)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			*notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		*gets++
+		w.Header().Set("ETag", etag)
+		w.Write(body)
+	}))
+}
+
+func (suite *KaskTestSuite) TestConditionalGet() {
+	gets, notModified := 0, 0
+	server := serveWithETag([]byte("content"), `"v1"`, &gets, &notModified)
+	defer server.Close()
+
+	file := filepath.Join(suite.SaveDir, "conditional")
+	updated, validators, err := ConditionalGet(suite.pluginContext, server.URL, file, Validators{})
+	suite.Nil(err)
+	suite.True(updated)
+	suite.Equal(`"v1"`, validators.ETag)
+
+	os.Remove(file)
+	updated, validators, err = ConditionalGet(suite.pluginContext, server.URL, file, validators)
+	suite.Nil(err)
+	suite.False(updated)
+	suite.Equal(`"v1"`, validators.ETag)
+	_, err = os.Stat(file)
+	suite.True(os.IsNotExist(err), "an unchanged resource should not be written")
+
+	suite.Equal(1, gets)
+	suite.Equal(1, notModified)
+}
+
+func (suite *KaskTestSuite) TestRefreshOfUnchangedDist() {
+	suite.skipUnlessLinux()
+	gets, notModified := 0, 0
+	server := serveWithETag(makeFakeDist(), `"v1"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	suite.Equal(1, gets)
+
+	// drop a marker into the extract, which a re-extraction would remove
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	marker := filepath.Join(pluginDir, "cache-"+suite.version, "extract", "marker")
+	suite.Require().Nil(ioutil.WriteFile(marker, []byte{}, 0644))
+
+	_, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext, true)
+	suite.Nil(err)
+	suite.Equal(1, gets)
+	suite.Equal(1, notModified)
+	suite.FileExists(marker)
+}
+
+func (suite *KaskTestSuite) TestAlwaysExtract() {
+	suite.skipUnlessLinux()
+	gets, notModified := 0, 0
+	server := serveWithETag(makeFakeDist(), `"v1"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+
+	// drop a marker into the extract, which a re-extraction would remove
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	cacheDir := filepath.Join(pluginDir, "cache-"+suite.version)
+	marker := filepath.Join(cacheDir, "extract", "marker")
+	suite.Require().Nil(ioutil.WriteFile(marker, []byte{}, 0644))
+
+	defer setenv("KASK_ALWAYS_EXTRACT", "true")()
+	cmd, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	suite.Equal(1, gets, "the archive should be reused")
+	suite.Equal(0, notModified)
+	suite.FileExists(cmd.Path)
+	suite.FileExists(filepath.Join(cacheDir, "success"))
+	_, err = os.Stat(marker)
+	suite.True(os.IsNotExist(err), "the extract should be redone")
+}
+
+func (suite *KaskTestSuite) TestAlwaysExtractWithoutTheDistHost() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	marker := filepath.Join(pluginDir, "cache-"+suite.version, "extract", "marker")
+	suite.Require().Nil(ioutil.WriteFile(marker, []byte{}, 0644))
+	server.Close()
+
+	// the archive is verified against the checksum we recorded of it
+	defer setenv("KASK_ALWAYS_EXTRACT", "true")()
+	cmd, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	suite.FileExists(cmd.Path)
+	_, err = os.Stat(marker)
+	suite.True(os.IsNotExist(err), "the extract should be redone")
+}
+
+func (suite *KaskTestSuite) TestAlwaysExtractFailureKeepsTheArchive() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	server.Close()
+
+	// an archive that verifies, but lacks the root command
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	cache := cacheLayoutOf(suite.pluginContext, pluginDir, suite.version)
+	suite.Require().Nil(ioutil.WriteFile(cache.downloadedFile, makeZip(fakeEntry{"unexpected/layout", "", 0644}), 0644))
+	checksum, err := sha256File(cache.downloadedFile)
+	suite.Require().Nil(err)
+	provenance := readProvenance(cache.provenanceFile)
+	provenance.SHA256 = checksum
+	suite.Require().Nil(writeProvenance(cache.provenanceFile, provenance))
+
+	defer setenv("KASK_ALWAYS_EXTRACT", "true")()
+	_, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(ExitExtract, ExitCode(err))
+	suite.FileExists(cache.downloadedFile, "the archive should survive its failed re-extraction")
+	suite.Equal(checksum, readProvenance(cache.provenanceFile).SHA256)
+	_, err = os.Stat(cache.extractedDir)
+	suite.True(os.IsNotExist(err), "the failed extract should be cleared")
+}
+
+// a file whose writes succeed, but whose close reports a failure, as on
+// some network filesystems
+type failsOnClose struct {
+	*os.File
+}
+
+func (f failsOnClose) Close() error {
+	f.File.Close()
+	return errors.New("input/output error")
+}
+
+func (suite *KaskTestSuite) TestDownloadReportsCloseErrors() {
+	server := serveDist([]byte("content"))
+	defer server.Close()
+
+	previous := createFile
+	createFile = func(name string) (io.WriteCloser, error) {
+		f, err := os.Create(name)
+		return failsOnClose{f}, err
+	}
+	defer func() { createFile = previous }()
+
+	file := filepath.Join(suite.SaveDir, "fails-on-close")
+	_, _, err := ConditionalGet(suite.pluginContext, server.URL, file, Validators{})
+	suite.NotNil(err)
+	_, err = os.Stat(file)
+	suite.True(os.IsNotExist(err), "a failed download should not be left behind")
+}
+
+func (suite *KaskTestSuite) TestDownloadReportsTruncation() {
+	// promise more than we send, then hang up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("only ten.."))
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+
+	file := filepath.Join(suite.SaveDir, "truncated")
+	_, _, err := ConditionalGet(suite.pluginContext, server.URL, file, Validators{})
+	suite.NotNil(err)
+	_, err = os.Stat(file)
+	suite.True(os.IsNotExist(err))
+}
@@ -0,0 +1,108 @@
+package kui
+
+import (
+	"strings"
+
+	log "go.uber.org/zap"
+)
+
+// globalFlags are the flags that apply to kask as a whole, rather than
+// to any one command
+type globalFlags struct {
+	// no progress reporting, nor notice of a newer kask
+	quiet bool
+	// debug logging, as with $DEBUG
+	verbose bool
+	// report failures as json, as with KASK_OUTPUT=json
+	json bool
+	// never download, as with KASK_OFFLINE
+	offline bool
+}
+
+// the flags of ours that take a value, which may precede the command;
+// every one of them, lest its value pass for the command
+var valuedFlags = map[string]bool{"--workdir": true, "--format": true, "--plugin-dir": true, "--config": true}
+
+// isCommandToken returns whether arg names what to do: a command, or
+// one of the flags that stands in for one
+func isCommandToken(arg string) bool {
+	return !strings.HasPrefix(arg, "-") || arg == "--no-exec" || arg == "-h" || arg == "--help"
+}
+
+// extractGlobalFlags removes the global flags preceding "--" from args,
+// returning the remaining args and the flags. Anywhere before "--",
+// --quiet, --verbose and --offline are ours; --json is ours only before
+// the command, as after it, it asks the command for json.
+func extractGlobalFlags(args []string) ([]string, globalFlags) {
+	rest := []string{}
+	var flags globalFlags
+	beforeCommand := true
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		switch {
+		case arg == "--":
+			return append(rest, args[idx:]...), flags
+		case arg == "--quiet":
+			flags.quiet = true
+		case arg == "--verbose":
+			flags.verbose = true
+		case arg == "--offline":
+			flags.offline = true
+		case arg == "--json" && beforeCommand:
+			flags.json = true
+		default:
+			if valuedFlags[arg] && idx+1 < len(args) {
+				rest = append(rest, arg)
+				idx++
+				arg = args[idx]
+			} else if isCommandToken(arg) {
+				beforeCommand = false
+			}
+			rest = append(rest, arg)
+		}
+	}
+	return rest, flags
+}
+
+// hoistCommand moves the command to the front of args, ahead of any
+// flags that precede it, so that we may dispatch on args[0]
+func hoistCommand(args []string) []string {
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		switch {
+		case arg == "--":
+			return args
+		case valuedFlags[arg]:
+			idx++
+		case isCommandToken(arg):
+			if idx == 0 {
+				return args
+			}
+			hoisted := append([]string{arg}, args[:idx]...)
+			return append(hoisted, args[idx+1:]...)
+		}
+	}
+	return args
+}
+
+// apply puts the flags into effect for the rest of the run, via the
+// context, so that they apply only to this run
+func (flags globalFlags) apply(context *MainContext) {
+	if flags.verbose {
+		if logger, err := log.NewDevelopment(); err == nil {
+			context._logger = logger.Sugar()
+		}
+	}
+	if flags.quiet {
+		if _, terminal := context.events.(*terminalEvents); terminal {
+			context.events = nil
+		}
+		*context = context.WithSetting("KASK_UPDATE_CHECK", "off")
+	}
+	if flags.json {
+		*context = context.WithSetting("KASK_OUTPUT", "json")
+	}
+	if flags.offline {
+		*context = context.WithSetting("KASK_OFFLINE", "true")
+	}
+}
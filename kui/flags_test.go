@@ -0,0 +1,92 @@
+package kui
+
+import (
+	"os"
+)
+
+func (suite *KaskTestSuite) TestExtractGlobalFlags() {
+	rest, flags := extractGlobalFlags([]string{"--quiet", "list", "--verbose", "--json"})
+	suite.Equal([]string{"list", "--json"}, rest, "a --json after the command is the command's")
+	suite.Equal(globalFlags{quiet: true, verbose: true}, flags)
+
+	rest, flags = extractGlobalFlags([]string{"--json", "search", "foo"})
+	suite.Equal([]string{"search", "foo"}, rest)
+	suite.Equal(globalFlags{json: true}, flags)
+
+	rest, flags = extractGlobalFlags([]string{"--workdir", "--json", "--offline", "list", "--", "--quiet"})
+	suite.Equal([]string{"--workdir", "--json", "list", "--", "--quiet"}, rest, "the value of --workdir is not a flag")
+	suite.Equal(globalFlags{offline: true}, flags)
+
+	for _, flag := range []string{"--workdir", "--format", "--plugin-dir", "--config"} {
+		rest, flags = extractGlobalFlags([]string{flag, "x", "--json", "list"})
+		suite.Equal([]string{flag, "x", "list"}, rest, flag)
+		suite.Equal(globalFlags{json: true}, flags, "the value of %s is not the command", flag)
+	}
+}
+
+func (suite *KaskTestSuite) TestHoistCommand() {
+	suite.Equal([]string{"list", "--workdir", "/x", "--ui"}, hoistCommand([]string{"--workdir", "/x", "list", "--ui"}))
+	suite.Equal([]string{"commands", "--format", "json", "foo"}, hoistCommand([]string{"--format", "json", "commands", "foo"}))
+	suite.Equal([]string{"--no-exec", "--workdir", "/x"}, hoistCommand([]string{"--workdir", "/x", "--no-exec"}))
+	suite.Equal([]string{"list", "--plugin-dir", "/x", "--config", "/y"}, hoistCommand([]string{"--plugin-dir", "/x", "--config", "/y", "list"}))
+	suite.Equal([]string{"list"}, hoistCommand([]string{"list"}))
+	suite.Equal([]string{"--ui", "--", "list"}, hoistCommand([]string{"--ui", "--", "list"}), "what follows -- is Kui's")
+}
+
+func (suite *KaskTestSuite) TestFlagsBeforeCommand() {
+	suite.skipUnlessLinux()
+
+	forwarded, err := suite.runFakeKui("--quiet", "somecmd", "arg")
+	suite.Nil(err)
+	suite.Equal([]string{"somecmd", "arg"}, forwarded)
+
+	forwarded, err = suite.runFakeKui("somecmd", "arg", "--quiet")
+	suite.Nil(err)
+	suite.Equal([]string{"somecmd", "arg"}, forwarded)
+	_, updateCheck := os.LookupEnv("KASK_UPDATE_CHECK")
+	suite.False(updateCheck, "the flags should apply only to their run")
+
+	workdir := suite.SaveDir
+	forwarded, err = suite.runFakeKui("--workdir", workdir, "--quiet", "somecmd", "--", "--quiet")
+	suite.Nil(err)
+	suite.Equal([]string{"somecmd", "--quiet"}, forwarded)
+}
+
+func (suite *KaskTestSuite) TestOfflineFlag() {
+	defer suite.isolate(unreachableURL())()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "--offline", "list"})
+	suite.Equal(ExitOfflineMiss, ExitCode(err))
+
+	err = suite.cmd.Run(*suite.pluginContext, []string{"kask", "list", "--offline"})
+	suite.Equal(ExitOfflineMiss, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestFlagsStayInTheContext() {
+	context := *suite.pluginContext
+	globalFlags{quiet: true, json: true, offline: true}.apply(&context)
+	suite.True(offlineMode(context))
+	suite.True(jsonOutput(context))
+	suite.Equal("off", getenv(context, "KASK_UPDATE_CHECK"))
+	for _, key := range []string{"KASK_OFFLINE", "KASK_OUTPUT", "KASK_UPDATE_CHECK"} {
+		_, isSet := os.LookupEnv(key)
+		suite.False(isSet, "%s should not reach our environment", key)
+	}
+
+	environ := withConfigEnvironment(context, []string{"KASK_OFFLINE=false", "PATH=/bin"})
+	suite.Contains(environ, "KASK_OFFLINE=true", "a child should see the flags as we do")
+	suite.NotContains(environ, "KASK_OFFLINE=false")
+	suite.Contains(environ, "PATH=/bin")
+}
+
+func (suite *KaskTestSuite) TestJSONFlag() {
+	defer suite.isolate(unreachableURL())()
+
+	result, err := suite.pluginContext.Execute([]string{"kask", "--json", "--offline", "list"})
+	suite.NotNil(err)
+	suite.True(result.reportJSON)
+	suite.False(jsonOutput(suite.pluginContext), "--json should apply only to its run")
+
+	result, _ = suite.pluginContext.Execute([]string{"kask", "--offline", "search", "--json"})
+	suite.False(result.reportJSON)
+}
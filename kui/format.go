@@ -0,0 +1,97 @@
+package kui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kui-shell/kask/i18n"
+)
+
+// how numbers and byte units are written in a given locale
+type numberFormat struct {
+	decimal string
+	units   []string
+}
+
+var (
+	englishFormat = numberFormat{".", []string{"B", "KB", "MB", "GB", "TB"}}
+	commaFormat   = numberFormat{",", []string{"B", "KB", "MB", "GB", "TB"}}
+	frenchFormat  = numberFormat{",", []string{"o", "Ko", "Mo", "Go", "To"}}
+)
+
+// numberFormatFor returns the number format of the given locale, e.g.
+// "de_DE" or "fr-FR"; unknown locales get the English format
+func numberFormatFor(locale string) numberFormat {
+	switch strings.ToLower(i18n.LangOfLocale(locale)) {
+	case "fr":
+		return frenchFormat
+	case "de", "es", "it", "pt":
+		return commaFormat
+	default:
+		return englishFormat
+	}
+}
+
+// FormatBytes renders a byte count for humans, in the given locale,
+// e.g. "1.2 GB" in en_US and "1,2 Go" in fr_FR
+func FormatBytes(locale string, n int64) string {
+	format := numberFormatFor(locale)
+
+	value := float64(n)
+	unit := 0
+	for value >= 1000 && unit < len(format.units)-1 {
+		value /= 1000
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", n, format.units[0])
+	}
+	number := strings.Replace(fmt.Sprintf("%.1f", value), ".", format.decimal, 1)
+	return number + " " + format.units[unit]
+}
+
+// FormatRate renders a transfer rate for humans, in the given locale
+func FormatRate(locale string, n int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		elapsed = time.Millisecond
+	}
+	return FormatBytes(locale, int64(float64(n)/elapsed.Seconds())) + "/s"
+}
+
+// isTerminal returns whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// how often we redraw the extraction progress line
+const progressInterval = 100 * time.Millisecond
+
+// extractionProgress returns an ExtractOptions.Progress that counts
+// the entries extracted on out, along with a func that finishes the line
+func extractionProgress(out io.Writer) (func(int), func()) {
+	var last time.Time
+	count := 0
+	draw := func() {
+		fmt.Fprintf(out, "\r%v %d files", gray("Extracting Kui base:"), count)
+	}
+
+	progress := func(extracted int) {
+		count = extracted
+		if time.Since(last) >= progressInterval {
+			draw()
+			last = time.Now()
+		}
+	}
+	done := func() {
+		if count > 0 {
+			draw()
+			fmt.Fprintln(out)
+		}
+	}
+	return progress, done
+}
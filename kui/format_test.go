@@ -0,0 +1,45 @@
+package kui
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+func (suite *KaskTestSuite) TestFormatBytesEnglish() {
+	suite.Equal("512 B", FormatBytes("en_US", 512))
+	suite.Equal("1.5 KB", FormatBytes("en_US", 1500))
+	suite.Equal("123.4 MB", FormatBytes("en_US", 123400000))
+	suite.Equal("1.2 GB", FormatBytes("en_US", 1200000000))
+}
+
+func (suite *KaskTestSuite) TestFormatBytesFrench() {
+	suite.Equal("512 o", FormatBytes("fr_FR", 512))
+	suite.Equal("123,4 Mo", FormatBytes("fr_FR", 123400000))
+	suite.Equal("1,2 Go", FormatBytes("fr_FR", 1200000000))
+}
+
+func (suite *KaskTestSuite) TestFormatBytesGerman() {
+	suite.Equal("1,2 GB", FormatBytes("de_DE", 1200000000))
+}
+
+func (suite *KaskTestSuite) TestFormatBytesUnknownLocale() {
+	suite.Equal("1.2 GB", FormatBytes("", 1200000000))
+}
+
+func (suite *KaskTestSuite) TestFormatRate() {
+	suite.Equal("2.5 MB/s", FormatRate("en_US", 5000000, 2*time.Second))
+	suite.Equal("2,5 Mo/s", FormatRate("fr_FR", 5000000, 2*time.Second))
+}
+
+func (suite *KaskTestSuite) TestExtractionProgress() {
+	var out bytes.Buffer
+	progress, done := extractionProgress(&out)
+	progress(1)
+	progress(2)
+	done()
+
+	// the first entry is drawn immediately, the last when we finish
+	suite.True(strings.HasPrefix(out.String(), "\r"+gray("Extracting Kui base:")+" 1 files"))
+	suite.True(strings.HasSuffix(out.String(), " 2 files\n"))
+}
@@ -0,0 +1,43 @@
+package kui
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// kask returns as soon as the Kui window is launched
+	GUIDetached = "detached"
+	// kask waits for the Kui window to be closed
+	GUIForeground = "foreground"
+)
+
+// the Kui commands that do nothing but open a window
+var guiCommands = map[string]bool{"shell": true}
+
+// guiLaunch returns whether the given kask arguments explicitly ask for
+// a Kui window: a command that only opens one, or any given --ui
+func guiLaunch(kaskArgs []string) bool {
+	if guiCommands[kaskArgs[0]] {
+		return true
+	}
+	for _, arg := range kaskArgs[1:] {
+		if arg == "--ui" {
+			return true
+		}
+	}
+	return false
+}
+
+// guiExecStyle returns how to launch Kui with a window, per
+// KASK_GUI_MODE; by default, as ever, we launch it and return
+func guiExecStyle(context Context) (ExecStyle, error) {
+	switch mode := strings.ToLower(getenv(context, "KASK_GUI_MODE")); mode {
+	case "", GUIDetached:
+		return ExecWithStart, nil
+	case GUIForeground:
+		return ExecWithRun, nil
+	default:
+		return ExecWithStart, fmt.Errorf("invalid KASK_GUI_MODE %q; expected %s or %s", mode, GUIForeground, GUIDetached)
+	}
+}
@@ -0,0 +1,60 @@
+package kui
+
+import (
+	"os/exec"
+	"time"
+)
+
+// a fake Kui window, which stays open for a second
+func (suite *KaskTestSuite) fakeGUICommand() *exec.Cmd {
+	cmd := exec.Command("sh", "-c", fakeKuiScript, "fake-kui")
+	cmd.Env = []string{"FAKE_KUI_SLEEP=1"}
+	return cmd
+}
+
+func (suite *KaskTestSuite) TestGUIModeDetachedByDefault() {
+	suite.skipUnlessLinux()
+	style, err := guiExecStyle(suite.pluginContext)
+	suite.Require().Nil(err)
+	suite.Equal(ExecStyle(ExecWithStart), style)
+
+	start := time.Now()
+	suite.Nil(suite.cmd.invokeRun(suite.pluginContext, suite.fakeGUICommand(), []string{"shell"}, style))
+	suite.Less(int64(time.Since(start)), int64(500*time.Millisecond), "a detached launch should not wait for the window")
+}
+
+func (suite *KaskTestSuite) TestGUIModeForeground() {
+	suite.skipUnlessLinux()
+	defer setenv("KASK_GUI_MODE", "foreground")()
+	style, err := guiExecStyle(suite.pluginContext)
+	suite.Require().Nil(err)
+	suite.Equal(ExecStyle(ExecWithRun), style)
+
+	start := time.Now()
+	suite.Nil(suite.cmd.invokeRun(suite.pluginContext, suite.fakeGUICommand(), []string{"shell"}, style))
+	suite.GreaterOrEqual(int64(time.Since(start)), int64(time.Second), "a foreground launch should wait for the window")
+}
+
+func (suite *KaskTestSuite) TestGUIModeInvalid() {
+	defer setenv("KASK_GUI_MODE", "sideways")()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "shell"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestGUILaunchIsDetached() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_EXIT", "42")()
+	defer setenv("FAKE_KUI_SLEEP", "1")()
+
+	start := time.Now()
+	_, err := suite.runFakeKui("somecmd", "--ui")
+	suite.Nil(err)
+	suite.Less(int64(time.Since(start)), int64(time.Second), "an explicit window launch should not be waited for")
+}
+
+func (suite *KaskTestSuite) TestGUILaunch() {
+	suite.True(guiLaunch([]string{"shell"}))
+	suite.True(guiLaunch([]string{"get", "pods", "--ui"}))
+	suite.False(guiLaunch([]string{"get", "pods"}))
+}
@@ -0,0 +1,58 @@
+package kui
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// the Kui commands that run without a window, unless given --ui
+var defaultHeadlessCommands = []string{"install", "uninstall", "list", "version", "commands"}
+
+var headlessCommandToken = regexp.MustCompile("^[A-Za-z0-9][A-Za-z0-9_-]*$")
+
+// headlessCommands returns the built-in headless commands, plus any
+// added, comma-separated, via KASK_HEADLESS_COMMANDS
+func headlessCommands(context Context) (map[string]bool, error) {
+	commands := map[string]bool{}
+	for _, command := range defaultHeadlessCommands {
+		commands[command] = true
+	}
+
+	for _, command := range strings.Split(getenv(context, "KASK_HEADLESS_COMMANDS"), ",") {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			continue
+		}
+		if !headlessCommandToken.MatchString(command) {
+			return nil, fmt.Errorf("invalid command %q in KASK_HEADLESS_COMMANDS", command)
+		}
+		commands[command] = true
+	}
+	return commands, nil
+}
+
+// runsHeadless returns whether the given kask arguments name one of
+// the headless commands, and do not ask for the UI
+func runsHeadless(kaskArgs []string, commands map[string]bool) bool {
+	return commands[kaskArgs[0]] && (len(kaskArgs) == 1 || kaskArgs[1] != "--ui")
+}
+
+// uiRequested returns whether the given kask arguments ask for the UI
+// of what would otherwise be a headless command
+func uiRequested(kaskArgs []string, commands map[string]bool) bool {
+	return commands[kaskArgs[0]] && !runsHeadless(kaskArgs, commands)
+}
+
+// hasDisplay returns whether there is a display on which to open a Kui
+// window; a variable, so that tests may fake its absence. Only on X11
+// and Wayland can we tell; elsewhere, we assume there is one.
+var hasDisplay = func() bool {
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
@@ -0,0 +1,47 @@
+package kui
+
+import "net/http"
+
+func (suite *KaskTestSuite) TestHeadlessDefaults() {
+	commands, err := headlessCommands(suite.pluginContext)
+	suite.Require().Nil(err)
+	suite.True(runsHeadless([]string{"list"}, commands))
+	suite.False(runsHeadless([]string{"list", "--ui"}, commands))
+	suite.False(runsHeadless([]string{"shell"}, commands))
+}
+
+func (suite *KaskTestSuite) TestHeadlessCommandsFromEnv() {
+	defer setenv("KASK_HEADLESS_COMMANDS", "get, describe")()
+
+	commands, err := headlessCommands(suite.pluginContext)
+	suite.Require().Nil(err)
+	suite.True(runsHeadless([]string{"get", "pods"}, commands))
+	suite.True(runsHeadless([]string{"describe"}, commands))
+	suite.True(runsHeadless([]string{"version"}, commands), "the defaults still apply")
+}
+
+func (suite *KaskTestSuite) TestHeadlessCommandsRejectsNonTokens() {
+	defer setenv("KASK_HEADLESS_COMMANDS", "get,rm -rf")()
+
+	_, err := headlessCommands(suite.pluginContext)
+	suite.NotNil(err)
+
+	err = suite.cmd.Run(*suite.pluginContext, []string{"kask", "get"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestUIWithoutDisplay() {
+	previous := hasDisplay
+	hasDisplay = func() bool { return false }
+	defer func() { hasDisplay = previous }()
+
+	gets := 0
+	server := serveDistAfterFailures(0, http.StatusOK, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "list", "--ui"})
+	suite.Equal(ExitUsage, ExitCode(err))
+	suite.Contains(err.Error(), "--ui requested but no display available")
+	suite.Equal(0, gets, "nothing should be downloaded for a window that cannot open")
+}
@@ -0,0 +1,149 @@
+package kui
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// a stand-in for the Kui executable; it echoes its arguments, records
+// them one per line in $FAKE_KUI_ARGS (if set), records its working
+// directory in $FAKE_KUI_PWD (if set), its environment in
+// $FAKE_KUI_ENV (if set), sleeps for $FAKE_KUI_SLEEP
+// seconds (if set), answers list with $FAKE_KUI_LIST (if set), version with
+// $FAKE_KUI_VERSION (if set), and exits with $FAKE_KUI_EXIT (default 0), or with
+// 1 if any argument is $FAKE_KUI_FAIL_ON
+const fakeKuiScript = `#!/bin/sh
+[ -n "$FAKE_KUI_ARGS" ] && printf '%s\n' "$@" > "$FAKE_KUI_ARGS"
+[ -n "$FAKE_KUI_PWD" ] && pwd > "$FAKE_KUI_PWD"
+[ -n "$FAKE_KUI_ENV" ] && env > "$FAKE_KUI_ENV"
+[ -n "$FAKE_KUI_SLEEP" ] && sleep "$FAKE_KUI_SLEEP"
+[ "$1" = list ] && [ -n "$FAKE_KUI_LIST" ] && { printf '%s\n' "$FAKE_KUI_LIST"; exit 0; }
+[ "$1" = version ] && [ -n "$FAKE_KUI_VERSION" ] && { printf '%s\n' "$FAKE_KUI_VERSION"; exit 0; }
+[ "$1" = commands ] && [ -n "$FAKE_KUI_COMMANDS" ] && { printf '%s\n' "$FAKE_KUI_COMMANDS"; exit 0; }
+for arg in "$@"; do
+  [ -n "$FAKE_KUI_FAIL_ON" ] && [ "$arg" = "$FAKE_KUI_FAIL_ON" ] && exit 1
+done
+echo "$@"
+exit ${FAKE_KUI_EXIT:-0}
+`
+
+type fakeEntry struct {
+	name string
+	body string
+	mode os.FileMode
+}
+
+// makeZip returns the bytes of a zip archive containing the given entries
+func makeZip(entries ...fakeEntry) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		header.SetMode(entry.mode)
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			panic(err)
+		}
+		f.Write([]byte(entry.body))
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// makeFakeDist returns a zip archive laid out like a linux Kui base build,
+// with fakeKuiScript as the root command
+func makeFakeDist() []byte {
+	return makeZip(fakeEntry{filepath.ToSlash(rootCommandPath(nil, PlatformKey())), fakeKuiScript, 0755})
+}
+
+// serveDist starts a server that responds to every request with the
+// given body, as if it were the dist host; like most hosts, it does not
+// publish checksums
+func serveDist(body []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+}
+
+// setenv sets an environment variable, returning a func that restores
+// its previous value
+func setenv(key string, value string) func() {
+	previous, wasSet := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if wasSet {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// isolate points HOME (and thus the plugin directory) at a fresh
+// temporary directory and KUI_DIST at the given host, returning a func
+// that undoes both
+func (suite *KaskTestSuite) isolate(distHost string) func() {
+	home, err := ioutil.TempDir(suite.SaveDir, "home")
+	suite.Require().Nil(err)
+	restoreHome := setenv("HOME", home)
+	restoreDist := setenv("KUI_DIST", distHost)
+	return func() {
+		restoreDist()
+		restoreHome()
+	}
+}
+
+// runFakeKui runs kask with the given arguments against the fake dist,
+// returning the arguments that the fake Kui received
+func (suite *KaskTestSuite) runFakeKui(args ...string) ([]string, error) {
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	record := filepath.Join(suite.SaveDir, "fake-kui-args")
+	os.Remove(record)
+	defer setenv("FAKE_KUI_ARGS", record)()
+
+	err := suite.cmd.Run(*suite.pluginContext, append([]string{"kask"}, args...))
+
+	recorded, _ := ioutil.ReadFile(record)
+	return strings.Split(strings.TrimSuffix(string(recorded), "\n"), "\n"), err
+}
+
+// captureStdout returns what fn writes to os.Stdout, including what
+// any child it runs writes there
+func (suite *KaskTestSuite) captureStdout(fn func()) string {
+	stdout := os.Stdout
+	capture, err := ioutil.TempFile(suite.SaveDir, "stdout")
+	suite.Require().Nil(err)
+	os.Stdout = capture
+	defer func() {
+		os.Stdout = stdout
+	}()
+	fn()
+	capture.Close()
+
+	output, _ := ioutil.ReadFile(capture.Name())
+	return string(output)
+}
+
+// the fake dist is a zip holding a shell script, which matches what
+// we fetch and run only on linux
+func (suite *KaskTestSuite) skipUnlessLinux() {
+	if runtime.GOOS != "linux" {
+		suite.T().Skip("the fake dist only models the linux build")
+	}
+}
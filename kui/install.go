@@ -0,0 +1,269 @@
+package kui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// InstallResult is the outcome of installing a single plugin
+type InstallResult struct {
+	Plugin string
+	Err    error
+}
+
+// cloneCommand returns a fresh, unstarted copy of cmd; an exec.Cmd may
+// only be run once
+func cloneCommand(cmd *exec.Cmd) *exec.Cmd {
+	clone := exec.Command(cmd.Path)
+	clone.Args = append([]string{}, cmd.Args...)
+	clone.Env = append([]string{}, cmd.Env...)
+	clone.Dir = cmd.Dir
+	return clone
+}
+
+// the flags of install that take a value, which is then no plugin
+var installValuedFlags = map[string]bool{"--registry": true}
+
+// parseInstallArgs separates the plugin names given to install from
+// its flags, with their values, and from our own --keep-going flag
+func parseInstallArgs(args []string) ([]string, []string, bool) {
+	var plugins []string
+	var flags []string
+	keepGoing := false
+	for idx := 0; idx < len(args); idx++ {
+		switch arg := args[idx]; {
+		case arg == "--keep-going":
+			keepGoing = true
+		case (installValuedFlags[arg] || valuedFlags[arg]) && idx+1 < len(args):
+			flags = append(flags, arg, args[idx+1])
+			idx++
+		case strings.HasPrefix(arg, "-"):
+			flags = append(flags, arg)
+		default:
+			plugins = append(plugins, arg)
+		}
+	}
+	return plugins, flags, keepGoing
+}
+
+// isLocalPlugin reports whether the given install argument names a
+// local artifact, e.g. ./my-plugin.tgz or file:///tmp/my-plugin.tgz,
+// rather than a plugin from the catalog. Catalog names may contain a
+// slash, e.g. @kui-shell/plugin-foo, but never start with one.
+func isLocalPlugin(arg string) bool {
+	return strings.HasPrefix(arg, "file://") ||
+		strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../") ||
+		strings.HasPrefix(arg, "."+string(filepath.Separator)) || strings.HasPrefix(arg, ".."+string(filepath.Separator)) ||
+		filepath.IsAbs(arg) ||
+		strings.HasSuffix(arg, ".tgz") || strings.HasSuffix(arg, ".tar.gz")
+}
+
+// resolveLocalPlugin returns the absolute path of the given local
+// artifact; Kui may run elsewhere than we do
+func resolveLocalPlugin(arg string) (string, error) {
+	path := arg
+	if strings.HasPrefix(arg, "file://") {
+		u, err := url.Parse(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid plugin url %s: %v", arg, err)
+		}
+		path = filepath.FromSlash(u.Path)
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no such plugin %s", arg)
+	}
+	return path, nil
+}
+
+// extractForceFlag removes our own --force flag from the given install
+// flags, returning the rest and whether it was there
+func extractForceFlag(flags []string) ([]string, bool) {
+	var rest []string
+	force := false
+	for _, flag := range flags {
+		if flag == "--force" {
+			force = true
+		} else {
+			rest = append(rest, flag)
+		}
+	}
+	return rest, force
+}
+
+// installedPlugins asks Kui, headlessly, which plugins are installed
+func installedPlugins(cmd *exec.Cmd) (map[string]bool, error) {
+	plugins, err := listPlugins(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return installedNames(plugins), nil
+}
+
+// parseInstalledPlugins returns the names of the installed plugins in
+// the output of a Kui list
+func parseInstalledPlugins(output []byte) (map[string]bool, error) {
+	plugins, err := parsePlugins(output)
+	if err != nil {
+		return nil, err
+	}
+	return installedNames(plugins), nil
+}
+
+func installedNames(plugins []Plugin) map[string]bool {
+	installed := map[string]bool{}
+	for _, plugin := range plugins {
+		if plugin.Installed {
+			installed[plugin.Name] = true
+		}
+	}
+	return installed
+}
+
+// skipInstalled separates the given plugins into those we have yet to
+// install, and those that are already installed. If we cannot tell,
+// we install them all.
+func (component *KuiComponent) skipInstalled(context Context, cmd *exec.Cmd, plugins []string) ([]string, []string) {
+	installed, err := installedPlugins(cmd)
+	if err != nil {
+		context.logger().Debugf("unable to list installed plugins %v", err)
+		return plugins, nil
+	}
+
+	var remaining []string
+	var skipped []string
+	for _, plugin := range plugins {
+		if installed[plugin] {
+			skipped = append(skipped, plugin)
+		} else {
+			remaining = append(remaining, plugin)
+		}
+	}
+	return remaining, skipped
+}
+
+// installConcurrency returns how many plugins InstallPlugins may
+// install at once, per KASK_INSTALL_CONCURRENCY; by default, one
+func installConcurrency(context Context) (int, error) {
+	value, isSet := lookupEnv(context, "KASK_INSTALL_CONCURRENCY")
+	if !isSet {
+		return 1, nil
+	}
+	concurrency, err := strconv.Atoi(value)
+	if err != nil || concurrency < 1 {
+		return 0, fmt.Errorf("invalid KASK_INSTALL_CONCURRENCY %q", value)
+	}
+	return concurrency, nil
+}
+
+// InstallPlugins installs the given plugins, passing extraArgs along
+// to each install, up to KASK_INSTALL_CONCURRENCY of them at once. The
+// Kui base that cmd runs is already in place, so the installs share it
+// rather than each resolving it anew. Unless keepGoing, no install
+// starts after the first failure. The results are those of the
+// installs attempted, in the order of plugins, and the returned error
+// combines those of every failed install.
+//
+// Concurrent installs are each a Kui process of their own, into Kui's
+// one plugin directory, just as are the installs of a user running
+// several `kask install`s at once; that Kui copes with those is what
+// lets a bundle opt into them. What kask itself shares between the
+// installs is guarded here: the output of each, which is held until
+// that install is done, and then printed whole, beneath its plugin's
+// name, so that the installs' output does not interleave; and the
+// result, and the events, which invokeRun takes care of.
+func (component *KuiComponent) InstallPlugins(context Context, cmd *exec.Cmd, plugins []string, extraArgs []string, style ExecStyle, keepGoing bool) ([]InstallResult, error) {
+	concurrency, err := installConcurrency(context)
+	if err != nil {
+		return nil, newError(UsageError, err)
+	}
+
+	outcomes := make([]*InstallResult, len(plugins))
+	var lock sync.Mutex
+	next, failed := 0, false
+	captured := concurrency > 1 && len(plugins) > 1
+
+	var workers sync.WaitGroup
+	for worker := 0; worker < concurrency && worker < len(plugins); worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				lock.Lock()
+				if next == len(plugins) || (failed && !keepGoing) {
+					lock.Unlock()
+					return
+				}
+				idx := next
+				next++
+				lock.Unlock()
+
+				args := append([]string{"install", plugins[idx]}, extraArgs...)
+				install := cloneCommand(cmd)
+				var stdout, stderr bytes.Buffer
+				if captured {
+					install.Stdout, install.Stderr = &stdout, &stderr
+				}
+				err := component.invokeRun(context, install, args, style)
+
+				lock.Lock()
+				if captured {
+					fmt.Printf("%v\n", blue("==> "+plugins[idx]))
+					os.Stdout.Write(stdout.Bytes())
+					os.Stderr.Write(stderr.Bytes())
+				}
+				outcomes[idx] = &InstallResult{plugins[idx], err}
+				failed = failed || err != nil
+				lock.Unlock()
+			}
+		}()
+	}
+	workers.Wait()
+
+	var results []InstallResult
+	var errs error
+	for _, outcome := range outcomes {
+		if outcome == nil {
+			continue
+		}
+		results = append(results, *outcome)
+		if outcome.Err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%s: %v", outcome.Plugin, outcome.Err))
+		}
+	}
+
+	return results, newError(ChildError, errs)
+}
+
+func printInstallSummary(out io.Writer, results []InstallResult) {
+	var succeeded []string
+	var failed []string
+	for _, result := range results {
+		if result.Err == nil {
+			succeeded = append(succeeded, result.Plugin)
+		} else {
+			failed = append(failed, result.Plugin)
+		}
+	}
+
+	if len(succeeded) > 0 {
+		fmt.Fprintf(out, "%v %s\n", blue("Installed:"), strings.Join(succeeded, ", "))
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(out, "%v %s\n", yellow("Failed:"), strings.Join(failed, ", "))
+	}
+}
@@ -0,0 +1,199 @@
+package kui
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+func (suite *KaskTestSuite) fakeKuiCommand() *exec.Cmd {
+	cmd := exec.Command("sh", "-c", fakeKuiScript, "fake-kui")
+	cmd.Env = []string{"FAKE_KUI_FAIL_ON=bad"}
+	return cmd
+}
+
+func (suite *KaskTestSuite) TestInstallPluginsKeepGoing() {
+	suite.skipUnlessLinux()
+	plugins := []string{"good1", "bad", "good2"}
+
+	results, err := suite.cmd.InstallPlugins(suite.pluginContext, suite.fakeKuiCommand(), plugins, nil, ExecWithRun, true)
+	suite.Equal(ExitChild, ExitCode(err))
+	suite.Len(multierr.Errors(errors.Unwrap(err)), 1)
+	suite.Contains(err.Error(), "bad")
+
+	suite.Len(results, 3)
+	suite.Nil(results[0].Err)
+	suite.NotNil(results[1].Err)
+	suite.Nil(results[2].Err)
+
+	var out bytes.Buffer
+	printInstallSummary(&out, results)
+	suite.Equal(blue("Installed:")+" good1, good2\n"+yellow("Failed:")+" bad\n", out.String())
+}
+
+func (suite *KaskTestSuite) TestInstallPluginsStopsAtFirstFailure() {
+	suite.skipUnlessLinux()
+	plugins := []string{"good1", "bad", "good2"}
+
+	results, err := suite.cmd.InstallPlugins(suite.pluginContext, suite.fakeKuiCommand(), plugins, nil, ExecWithRun, false)
+	suite.Equal(ExitChild, ExitCode(err))
+	suite.Len(results, 2)
+}
+
+func (suite *KaskTestSuite) TestInstallPluginsAllSucceed() {
+	suite.skipUnlessLinux()
+	results, err := suite.cmd.InstallPlugins(suite.pluginContext, suite.fakeKuiCommand(), []string{"a", "b"}, nil, ExecWithRun, false)
+	suite.Nil(err)
+	suite.Len(results, 2)
+}
+
+// an install that records how many installs are running as it starts,
+// and takes long enough for the others to overlap it
+const countingInstallScript = `touch "$RUNNING/$2"
+ls "$RUNNING" | wc -l >> "$RUNNING.counts"
+sleep 0.3
+rm "$RUNNING/$2"
+[ "$2" != bad ]`
+
+func (suite *KaskTestSuite) TestInstallPluginsConcurrently() {
+	suite.skipUnlessLinux()
+	running := filepath.Join(suite.SaveDir, "running")
+	suite.Require().Nil(os.MkdirAll(running, 0755))
+	os.Remove(running + ".counts")
+	cmd := exec.Command("sh", "-c", countingInstallScript, "counting-install")
+	cmd.Env = []string{"RUNNING=" + running, "PATH=" + os.Getenv("PATH")}
+	defer setenv("KASK_INSTALL_CONCURRENCY", "2")()
+
+	plugins := []string{"a", "b", "bad", "c", "d"}
+	results, err := suite.cmd.InstallPlugins(suite.pluginContext, cmd, plugins, nil, ExecWithRun, true)
+	suite.Equal(ExitChild, ExitCode(err))
+	suite.Len(multierr.Errors(errors.Unwrap(err)), 1)
+
+	suite.Require().Len(results, 5)
+	for idx, result := range results {
+		suite.Equal(plugins[idx], result.Plugin, "the results should be in the order of the plugins")
+		suite.Equal(result.Plugin == "bad", result.Err != nil, result.Plugin)
+	}
+
+	counts, err := ioutil.ReadFile(running + ".counts")
+	suite.Require().Nil(err)
+	most := 0
+	for _, count := range strings.Fields(string(counts)) {
+		if n := toInt(count); n > most {
+			most = n
+		}
+	}
+	suite.Equal(2, most, "two installs, and no more, should run at once")
+}
+
+func (suite *KaskTestSuite) TestConcurrentInstallsDoNotInterleave() {
+	suite.skipUnlessLinux()
+	cmd := exec.Command("sh", "-c", `echo "$2 begins"; sleep 0.2; echo "$2 ends"`, "chatty-install")
+	defer setenv("KASK_INSTALL_CONCURRENCY", "2")()
+
+	var err error
+	output := suite.captureStdout(func() {
+		_, err = suite.cmd.InstallPlugins(suite.pluginContext, cmd, []string{"a", "b"}, nil, ExecWithRun, false)
+	})
+	suite.Require().Nil(err)
+
+	for _, plugin := range []string{"a", "b"} {
+		suite.Contains(output, blue("==> "+plugin)+"\n"+plugin+" begins\n"+plugin+" ends\n", "each install's output should be printed whole")
+	}
+}
+
+func (suite *KaskTestSuite) TestInstallConcurrency() {
+	concurrency, err := installConcurrency(suite.pluginContext)
+	suite.Nil(err)
+	suite.Equal(1, concurrency, "installs should be serial by default")
+
+	defer setenv("KASK_INSTALL_CONCURRENCY", "0")()
+	_, err = installConcurrency(suite.pluginContext)
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestParseInstallArgs() {
+	plugins, flags, keepGoing := parseInstallArgs([]string{"a", "--keep-going", "b", "--ui"})
+	suite.Equal([]string{"a", "b"}, plugins)
+	suite.Equal([]string{"--ui"}, flags)
+	suite.True(keepGoing)
+
+	plugins, flags, _ = parseInstallArgs([]string{"--registry", "https://npm.example.com", "a", "--registry=https://npm.example.com", "b"})
+	suite.Equal([]string{"a", "b"}, plugins, "the value of a flag is no plugin")
+	suite.Equal([]string{"--registry", "https://npm.example.com", "--registry=https://npm.example.com"}, flags)
+}
+
+func (suite *KaskTestSuite) TestInstallAlreadyInstalled() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_LIST", `[{"name": "foo"}, {"name": "bar"}]`)()
+
+	forwarded, err := suite.runFakeKui("install", "foo")
+	suite.Nil(err)
+	suite.Equal([]string{"list"}, forwarded, "an installed plugin should not be installed again")
+}
+
+func (suite *KaskTestSuite) TestInstallForce() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_LIST", `[{"name": "foo"}]`)()
+
+	forwarded, err := suite.runFakeKui("install", "foo", "--force")
+	suite.Nil(err)
+	suite.Equal([]string{"install", "foo"}, forwarded)
+}
+
+func (suite *KaskTestSuite) TestInstallSkipsOnlyInstalled() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_LIST", `[{"name": "foo"}]`)()
+
+	forwarded, err := suite.runFakeKui("install", "foo", "baz")
+	suite.Nil(err)
+	suite.Equal([]string{"install", "baz"}, forwarded)
+}
+
+func (suite *KaskTestSuite) TestInstallLocalPlugin() {
+	suite.skipUnlessLinux()
+	artifact := filepath.Join(suite.SaveDir, "my-plugin.tgz")
+	suite.Require().Nil(ioutil.WriteFile(artifact, []byte("plugin"), 0644))
+	cwd, _ := os.Getwd()
+	relative, err := filepath.Rel(cwd, artifact)
+	suite.Require().Nil(err)
+
+	for _, arg := range []string{"./" + relative, "file://" + artifact} {
+		forwarded, err := suite.runFakeKui("install", arg)
+		suite.Nil(err)
+		suite.Equal([]string{"install", artifact}, forwarded, arg)
+	}
+
+	_, err = suite.runFakeKui("install", "./no-such-plugin.tgz")
+	suite.Equal(ExitUsage, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestIsLocalPlugin() {
+	suite.True(isLocalPlugin("./my-plugin"))
+	suite.True(isLocalPlugin("/tmp/my-plugin.tar.gz"))
+	suite.True(isLocalPlugin("my-plugin.tgz"))
+	suite.True(isLocalPlugin("file:///tmp/my-plugin.tgz"))
+	suite.False(isLocalPlugin("foo"))
+	suite.False(isLocalPlugin("@kui-shell/plugin-foo"))
+}
+
+func (suite *KaskTestSuite) TestParseInstalledPlugins() {
+	installed, err := parseInstalledPlugins([]byte(`[{"name":"a"},{"name":"b"}]`))
+	suite.Nil(err)
+	suite.Equal(map[string]bool{"a": true, "b": true}, installed)
+	installed, err = parseInstalledPlugins([]byte("a  1.0.0\nb  2.0.0\n\n"))
+	suite.Nil(err)
+	suite.Equal(map[string]bool{"a": true, "b": true}, installed)
+	installed, err = parseInstalledPlugins([]byte("NAME  VERSION\na  1.0.0\n"))
+	suite.Nil(err)
+	suite.Equal(map[string]bool{"a": true}, installed, "the header is no plugin")
+
+	_, err = parseInstalledPlugins([]byte("Error: unable to read the plugin registry\n"))
+	suite.NotNil(err, "an error is no list of plugins")
+}
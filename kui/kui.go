@@ -1,21 +1,26 @@
 package kui
 
 import (
+	"bytes"
+	stdcontext "context"
+	"errors"
 	"fmt"
-	"github.com/mholt/archiver"
 	. "github.com/kui-shell/kask/i18n"
+	"github.com/kui-shell/kask/retry"
 	log "go.uber.org/zap"
 	baselog "log"
 	"io"
-	"net/http"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"regexp"
-	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type KrewComponent interface {
@@ -23,6 +28,20 @@ type KrewComponent interface {
 }
 
 type KuiComponent struct {
+	// what the current Run has done, for Execute
+	result Result
+
+	// whether --yes was given, to skip any confirmation
+	assumeYes bool
+
+	// the global flags given, e.g. --quiet
+	globals globalFlags
+
+	// guards result, which concurrent installs share
+	resultLock sync.Mutex
+
+	// the telemetry reports in flight, until flushed
+	telemetry *telemetryReports
 }
 
 type Context interface {
@@ -43,15 +62,31 @@ type MainContext struct {
 	commit string
 	date string
 	_logger *log.SugaredLogger
+
+	// overrides the default, e.g. from --plugin-dir
+	pluginDir string
+
+	// receives the events of our work, if non-nil
+	events EventHandler
+
+	// the settings of our config file, if any
+	config map[string]string
+
+	// the settings that win over the environment, e.g. from --offline
+	overrides map[string]string
 }
 func (context MainContext) PluginDirectory() (string, error) {
+	if context.pluginDir != "" {
+		return context.pluginDir, nil
+	}
 	home, err := os.UserHomeDir()
-	if err == nil {
-		return filepath.Join(home, ".kask"), nil
-	} else {
-		handleError(context, err)
+	if err != nil {
 		return "", err
 	}
+	if !usableHome(home) {
+		return fallbackPluginDir(home)
+	}
+	return filepath.Join(home, ".kask"), nil
 }
 func (context MainContext) logger() *log.SugaredLogger {
 	return context._logger
@@ -65,21 +100,52 @@ func initLogger()(*log.Logger, error) {
 	}
 }
 func initDefault(version string, commit string, date string)(MainContext) {
-	logger, err := initLogger()
-	if err != nil {
-		baselog.Fatalf("can't initialize zap logger: %v", err)
+	return NewMainContext(version, commit, date, nil).WithEventHandler(newTerminalEvents())
+}
+
+// NewMainContext returns a context that logs to the given logger; e.g.
+// so that a host embedding kask can route our logging into its
+// own. A nil logger means the default, as chosen by $DEBUG.
+func NewMainContext(version string, commit string, date string, logger *log.SugaredLogger) MainContext {
+	if logger == nil {
+		defaultLogger, err := initLogger()
+		if err != nil {
+			baselog.Fatalf("can't initialize zap logger: %v", err)
+		}
+		logger = defaultLogger.Sugar()
 	}
-	_logger := logger.Sugar()
-	return MainContext{ version, commit, date, _logger }
+	return MainContext{ version, commit, date, logger, "", nil, nil, nil }
 }
 
 func Start(version string, commit string, date string) {
-	runner := KuiComponent{}
+	notifyInterrupt = interruptOnSignal
 	context := initDefault(version, commit, date)
-	runner.Run(context, os.Args)
+	result, err := context.Execute(os.Args)
+	if result.reportJSON {
+		context = context.WithSetting("KASK_OUTPUT", "json")
+	}
+	report(context, err, os.Stderr)
+	os.Exit(result.ExitCode)
+}
+
+// report describes the failure, if any, that Run returned. This is the
+// one place that failures are logged; everything beneath Run wraps
+// and returns its errors, rather than logging them, so that each
+// failure is reported exactly once.
+func report(context Context, err error, stderr io.Writer) {
+	if err == nil {
+		return
+	}
+	handleError(context, err)
+	if jsonOutput(context) {
+		writeJSONError(stderr, err)
+	}
 }
 
 func (component *KuiComponent) init() {
+	component.result = Result{}
+	component.assumeYes = false
+	component.globals = globalFlags{}
 }
 
 func blue(str string) string {
@@ -101,189 +167,750 @@ const (
 )
 type ExecStyle int
 
-func (component *KuiComponent) Run(context MainContext, args []string) {
+func (component *KuiComponent) Run(context MainContext, args []string) (err error) {
 	component.init()
+	defer component.flushTelemetry()
+	defer func() {
+		if err != nil {
+			emit(context, ErrorEvent{err})
+		} else {
+			emit(context, DoneEvent{})
+		}
+	}()
+
+	rest, globals := extractGlobalFlags(args[1:])
+	args = append(args[:1:1], rest...)
+	component.globals = globals
+	globals.apply(&context)
+
+	if len(args) > 1 {
+		rest, pluginDir, err := extractPluginDirFlag(args[1:])
+		if err != nil {
+			return newError(UsageError, err)
+		}
+		if pluginDir != "" {
+			if err := validatePluginDir(pluginDir); err != nil {
+				return newError(UsageError, err)
+			}
+			context.pluginDir = pluginDir
+		}
+		args = append(args[:1:1], rest...)
+	}
+
+	rest, assumeYes := extractYesFlag(args[1:])
+	args = append(args[:1:1], rest...)
+	component.assumeYes = assumeYes
+
+	rest, configFlag, err := extractConfigFlag(args[1:])
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	args = append(args[:1:1], rest...)
+	config, err := loadConfig(context, configFlag)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	context = context.WithConfig(config)
+
+	// we dispatch on the command, wherever among our flags it is
+	args = append(args[:1:1], hoistCommand(args[1:])...)
 
 	if len(args) == 1 || (len(args) == 2 && (args[1] == "-h" || args[1] == "--help")) {
-		fmt.Printf("Usage: %v\n\n", cyan("kask <command>"))
+		base := path.Base(args[0])
+		fmt.Printf("Usage: %v\n", cyan(invocationName(base) + " <command>"))
+		mode, _ := contextMode(context)
+		if kuiCommandContext, subcommand := inferCommandContext(base, mode); kuiCommandContext != defaultCommandContext {
+			fmt.Printf("Commands run in the Kui command context %v\n", cyan(strings.Join(append([]string{kuiCommandContext}, subcommand...), " ")))
+		}
+		fmt.Println()
 		fmt.Printf("%v\n", yellow("Commands:"))
-		fmt.Printf("%v\t\tList installed plugins\n", blue("list"))
-		fmt.Printf("%v\tShow commands offered by a plugin\n", blue("commands"))
-		fmt.Printf("%v\t\tInstall a plugin\n", blue("install"))
+		fmt.Printf("%v\t\tList installed plugins; --installed-only and --available (those in the catalog not yet installed) filter it, and --json renders it as json\n", blue("list"))
+		fmt.Printf("%v\tShow commands offered by a plugin; with --json, as json\n", blue("commands"))
+		fmt.Printf("%v\t\tSearch for plugins to install\n", blue("search"))
+		fmt.Printf("%v\t\tInstall one or more plugins, by name or from a local file; with --keep-going, continue past failures, and with --force, reinstall those already installed\n", blue("install"))
 		fmt.Printf("%v\tRemove a previously installed plugin\n", blue("uninstall"))
+		fmt.Printf("%v\tInstall the plugins of a bundle, defined by KASK_BUNDLE_<NAME> or the KASK_BUNDLES manifest; --keep-going and --force apply as to install\n", blue("bundle install <name>"))
 
 		fmt.Printf("\n%v\n", yellow("Admin Commands:"))
 		fmt.Printf("%v\t\tUpdate the local UI code\n", blue("refresh"))
-		fmt.Printf("%v\t\tPrint the current version", blue("version"))
-		os.Exit(1)
-		return
+		fmt.Printf("%v\tDownload the UI code, without running anything\n", blue("prefetch"))
+		fmt.Printf("%v\tRemove the oldest cached UI code, until the cache is under the given size, e.g. 2GB\n", blue("cache gc --max-size <size>"))
+		fmt.Printf("%v\tList the cached UI code; with --older-than, only that fetched longer ago than the given age, e.g. 30d\n", blue("cache list"))
+		fmt.Printf("%v\tRemove the cached UI code fetched longer ago than the given age, e.g. 30d, other than that in use\n", blue("cache clean --older-than <age>"))
+		fmt.Printf("%v\t\tShow what was extracted of the UI code, and where its executable should be; with --version, of that version\n", blue("cache tree"))
+		fmt.Printf("%v\t\tCheck the local UI code for missing or modified files\n", blue("verify"))
+		fmt.Printf("%v\t\tRepoint the kubectl plugin link at this kask, e.g. after moving it\n", blue("relink"))
+		fmt.Printf("%v\t\tFix the kubectl plugin link, and a cached UI code that is corrupt, or has lost its executable\n", blue("repair"))
+		fmt.Printf("%v\t\tShow the path of the Kui executable that kask runs\n", blue("which"))
+		fmt.Printf("%v\t\tShow the url from which kask would fetch the UI code\n", blue("url"))
+		fmt.Printf("%v\t\tCheck that the UI code's host is reachable, and how quickly it responds\n", blue("ping"))
+		fmt.Printf("%v\tCollect version, environment, and cache details into a zip, for a bug report\n", blue("bugreport"))
+		fmt.Printf("%v\t\tPrint the current version\n", blue("version"))
+
+		fmt.Printf("\n%v\tRun Kui in this directory (default: the current directory)\n", blue("--workdir <dir>"))
+		fmt.Printf("%v\tDownload the UI code and link the kubectl plugin, then exit without running anything\n", blue("--no-exec"))
+		fmt.Printf("%v\t\tDo not ask before downloading the UI code\n", blue("--yes"))
+		fmt.Printf("%v\tRead settings from this file (default: ~/.kask/config)\n", blue("--config <file>"))
+		fmt.Printf("%v\tKeep the UI code in this directory (default: ~/.kask)\n", blue("--plugin-dir <dir>"))
+		fmt.Printf("%v\tRender the output of list or commands as json, yaml, or table\n", blue("--format <fmt>"))
+		fmt.Printf("%v\t\tDo not report progress, nor offer newer releases of kask\n", blue("--quiet"))
+		fmt.Printf("%v\tLog what kask does, as does DEBUG\n", blue("--verbose"))
+		fmt.Printf("%v\t\tBefore the command, report failures as json, as does KASK_OUTPUT=json\n", blue("--json"))
+		fmt.Printf("%v\tNever download; use only the cached UI code, as does KASK_OFFLINE\n", blue("--offline"))
+		fmt.Printf("\nArguments following %v are passed verbatim to Kui\n", blue("--"))
+
+		if len(args) == 1 {
+			return newErrorf(UsageError, "no command specified")
+		}
+		return nil
+	}
+
+	if args[1] == "--no-exec" {
+		return component.Prepare(context, args[2:])
+	}
+	if args[1] == "search" {
+		return component.Search(context, args[2:])
+	}
+	if args[1] == "prefetch" {
+		return component.Prefetch(context, args[2:])
+	}
+	if args[1] == "cache" {
+		return component.Cache(context, args[2:])
+	}
+	if args[1] == "verify" {
+		return component.Verify(context, args[2:])
+	}
+	if args[1] == "relink" {
+		return component.Relink(context, args[2:])
+	}
+	if args[1] == "repair" {
+		return component.Repair(context, args[2:])
+	}
+	if args[1] == "which" {
+		return component.Which(context, args[2:])
+	}
+	if args[1] == "url" {
+		return component.URL(context, args[2:])
+	}
+	if args[1] == "ping" {
+		return component.Ping(context, args[2:])
+	}
+	if args[1] == "bugreport" {
+		return component.BugReport(context, args[2:])
+	}
+
+	// a bundle installs as its plugins would, named one by one
+	if args[1] == "bundle" {
+		expanded, err := expandBundle(context, args[2:])
+		if err != nil {
+			return err
+		}
+		args = append(args[:1:1], expanded...)
 	}
 
-	refreshRequested := args[1] == "refresh"
+	// everything after "--" goes to Kui verbatim; we interpret only what precedes it
+	kaskArgs, passthrough := splitPassthrough(args[1:])
+
+	kaskArgs, workdirFlag, err := extractWorkdirFlag(kaskArgs)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	workdir, err := resolveWorkdir(context, workdirFlag)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+
+	kaskArgs, format, err := extractFormatFlag(kaskArgs)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+
+	if len(kaskArgs) == 0 {
+		return newErrorf(UsageError, "no command given before --")
+	}
+
+	aliases, err := aliasTable(component.GetMetadata().Commands)
+	if err != nil {
+		return err
+	}
+	if canonical, isAlias := aliases[kaskArgs[0]]; isAlias {
+		context.logger().Debugf("%s is an alias of %s", kaskArgs[0], canonical)
+		kaskArgs[0] = canonical
+	}
+
+	if format != "" {
+		if err := checkFormat(kaskArgs[0], format); err != nil {
+			return newError(UsageError, err)
+		}
+	}
+
+	headless, err := headlessCommands(context)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	guiStyle, err := guiExecStyle(context)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	mode, err := contextMode(context)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	if _, err := distChannel(context); err != nil {
+		return newError(UsageError, err)
+	}
+	ref, err := catalogRef(context)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	if uiRequested(kaskArgs, headless) && !hasDisplay() {
+		return newErrorf(UsageError, "--ui requested but no display available; set DISPLAY, or drop --ui to run %s headless", kaskArgs[0])
+	}
+
+	refreshRequested := kaskArgs[0] == "refresh"
 	context.logger().Debugf("refreshRequested? %v", refreshRequested)
 
+	// with nothing yet cached, a refresh is just an install
+	installRequested := false
+	if refreshRequested {
+		if status, err := component.CacheStatus(context); err == nil && !status.SuccessExists {
+			refreshRequested, installRequested = false, true
+		}
+	}
+
 	cmd, err := component.DownloadDistIfNecessary(context, refreshRequested)
 	if err != nil {
-		os.Exit(1)
-		return
+		return err
+	}
+
+	if installRequested {
+		fmt.Printf("Installed Kui base %s\n", component.DistVersion(context))
+		return nil
 	}
 
-	var kaskArgs []string
 	if refreshRequested {
 		context.logger().Debug("refresh done")
 		kaskArgs = []string{"version"}
-	} else {
-		kaskArgs = args[1:]
+		passthrough = nil
 	}
 
 	base := path.Base(args[0])
-	r := regexp.MustCompile("^kubectl-")
-	var kuiCommandContext string
-	if r.MatchString(base) {
-		kuiCommandContext = r.ReplaceAllString(base, "")
-	} else {
-		kuiCommandContext = defaultCommandContext
-	}
-	if kuiCommandContext == "kask" {
-		kuiCommandContext = defaultCommandContext
-	}
-	context.logger().Debugf("command context: %s", kuiCommandContext)
+	kuiCommandContext, subcommand := inferCommandContext(base, mode)
+	context.logger().Debugf("command context: %s %v", kuiCommandContext, subcommand)
 	cmd.Env = append(cmd.Env, "KUI_COMMAND_CONTEXT=" + kuiCommandContext)
+	cmd.Dir = workdir
 
-	var style ExecStyle = ExecWithStart
+	// only a launch of a window may be detached; anything else we wait
+	// for, so that its exit code is ours
+	style := ExecStyle(ExecWithRun)
 	arg := kaskArgs[0]
-	if arg == "install" || arg == "uninstall" || arg == "list" || arg == "version" || arg == "commands" {
-		if len(kaskArgs) == 1 || kaskArgs[1] != "--ui" {
-			context.logger().Debug("using headless mode")
-			cmd.Env = append(cmd.Env, "KUI_HEADLESS=true")
-			style = ExecWithRun
+	if runsHeadless(kaskArgs, headless) {
+		context.logger().Debug("using headless mode")
+		cmd.Env = append(cmd.Env, "KUI_HEADLESS=true")
+	} else if guiLaunch(kaskArgs) {
+		style = guiStyle
+	}
+
+	// a list filtered to the installed, or the available, plugins we
+	// render ourselves, too
+	if arg == "list" && len(passthrough) == 0 {
+		filter, filtered, err := parseListArgs(kaskArgs[1:])
+		if err != nil {
+			return newError(UsageError, err)
+		}
+		if filtered {
+			cmd.Args = append(cmd.Args, subcommand...)
+			component.result.Command = append(append([]string{}, cmd.Args...), "list")
+			emit(context, RunningEvent{component.result.Command, false})
+			return filteredList(context, os.Stdout, cmd, filter, format)
 		}
 	}
 
-	if arg == "version" {
-		// also report our version
-		fmt.Printf("%v\t%v %v\n%v\t", blue(base), context.version, context.date, blue("kui"))
+	// as are a plugin's commands
+	if arg == "commands" && len(passthrough) == 0 && style == ExecWithRun && runsHeadless(kaskArgs, headless) {
+		if plugin, asJSON, ok := parseCommandsArgs(kaskArgs[1:]); ok {
+			cmd.Args = append(cmd.Args, subcommand...)
+			component.result.Command = append(append([]string{}, cmd.Args...), "commands", plugin)
+			emit(context, RunningEvent{component.result.Command, false})
+			commands, err := listCommands(cmd, plugin)
+			if err != nil {
+				return err
+			}
+			if asJSON && format == "" {
+				format = "json"
+			}
+			return printCommands(os.Stdout, plugin, commands, format)
+		}
+	}
+
+	// a plain list we render ourselves, from what Kui tells us
+	if arg == "list" && len(kaskArgs) == 1 && len(passthrough) == 0 && style == ExecWithRun && runsHeadless(kaskArgs, headless) {
+		cmd.Args = append(cmd.Args, subcommand...)
+		component.result.Command = append(append([]string{}, cmd.Args...), "list")
+		emit(context, RunningEvent{component.result.Command, false})
+		plugins, err := listPlugins(cmd)
+		if err != nil {
+			return err
+		}
+		return printPlugins(os.Stdout, plugins, format)
 	}
 
-	component.invokeRun(context, cmd, kaskArgs, style)
+	// what we do not render ourselves, we cannot render in a format
+	if format != "" {
+		return newErrorf(UsageError, "--format applies only to a headless %s, with no arguments for Kui", arg)
+	}
 
 	if arg == "version" {
-		// missing trailing newline; fixing here for now
-		fmt.Print("\n")
+		err := component.printVersion(context, base, cmd, kaskArgs, os.Stdout)
+		notifyOfUpdate(context)
+		return err
+	}
+
+	if arg == "install" {
+		plugins, flags, keepGoing := parseInstallArgs(kaskArgs[1:])
+		flags, force := extractForceFlag(flags)
+		for idx, plugin := range plugins {
+			if isLocalPlugin(plugin) {
+				if plugins[idx], err = resolveLocalPlugin(plugin); err != nil {
+					return newError(UsageError, err)
+				}
+			}
+		}
+		if !force && len(plugins) > 0 {
+			var installed []string
+			plugins, installed = component.skipInstalled(context, cmd, plugins)
+			for _, plugin := range installed {
+				fmt.Printf("%s is already installed; use --force to reinstall it\n", plugin)
+			}
+			if len(plugins) == 0 {
+				return nil
+			}
+		}
+		if plugins, err = pinToCatalogRef(context, ref, plugins); err != nil {
+			return err
+		}
+		if len(plugins) > 1 || keepGoing {
+			results, err := component.InstallPlugins(context, cmd, plugins, append(flags, passthrough...), style, keepGoing)
+			printInstallSummary(os.Stdout, results)
+			return err
+		}
+		kaskArgs = append(append([]string{arg}, plugins...), flags...)
+	}
+
+	err = component.invokeRun(context, cmd, append(append(subcommand, kaskArgs...), passthrough...), style)
+	if !runsHeadless(kaskArgs, headless) {
+		err = preservingExitCode(err)
+	}
+	return err
+}
+
+// splitPassthrough splits args at the first "--", returning copies of
+// the arguments before and after it
+func splitPassthrough(args []string) ([]string, []string) {
+	for idx, arg := range args {
+		if arg == "--" {
+			return append([]string{}, args[:idx]...), append([]string{}, args[idx+1:]...)
+		}
 	}
+	return append([]string{}, args...), nil
 }
 
-func (component *KuiComponent) invokeRun(context Context, cmd *exec.Cmd, kaskArgs []string, style ExecStyle) {
+func (component *KuiComponent) invokeRun(context Context, cmd *exec.Cmd, kaskArgs []string, style ExecStyle) error {
 	cmd.Args = append(cmd.Args, kaskArgs...)
+	component.resultLock.Lock()
+	component.result.Command = cmd.Args
+	component.resultLock.Unlock()
 	context.logger().Debugf("args %s", cmd.Args)
+	emit(context, RunningEvent{cmd.Args, style == ExecWithStart})
 
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+	// the child's output is ours, unless the caller would collect it
+	var out, errOut io.Writer = os.Stdout, os.Stderr
+	if cmd.Stdout != nil {
+		out = cmd.Stdout
+	}
+	if cmd.Stderr != nil {
+		errOut = cmd.Stderr
+	}
+	cmd.Stdout, cmd.Stderr = out, errOut
 
 	if style == ExecWithStart {
 		if err := cmd.Start(); err != nil {
-			fmt.Println("command failed!")
+			fmt.Fprintln(out, "command failed!")
+			return newError(ChildError, err)
 		}
+		// we will not wait for it
+		cmd.Process.Release()
 	} else {
-		if err := cmd.Run(); err != nil {
-			fmt.Println("command failed!")
+		// a detached child outlives any pipe we could filter it through
+		flush := func() {}
+		if prefix := outputPrefix(context); prefix != "" {
+			stdout, stderr := newPrefixWriter(out, prefix), newPrefixWriter(errOut, prefix)
+			cmd.Stdout, cmd.Stderr = stdout, stderr
+			flush = func() {
+				stdout.Flush()
+				stderr.Flush()
+			}
+		}
+		err := cmd.Run()
+		flush()
+		if err != nil {
+			fmt.Fprintln(out, "command failed!")
+			return newError(ChildError, err)
 		}
-	}		
+	}
+
+	return nil
 }
 
-func GetDistOSSuffix() string {
-	switch runtime.GOOS {
-	case "windows":
-		return "-base-win32-x64.zip"
-	case "darwin":
-		return "-base-darwin-x64.tar.bz2"
-	default:
-		return "-base-linux-x64.zip"
+// printVersion reports our version along with that of Kui. We collect
+// the Kui version before printing anything, so that a failing child
+// does not leave behind a half-printed line; if Kui cannot tell us its
+// version, we say so, and fail, so that health checks notice.
+func (component *KuiComponent) printVersion(context MainContext, base string, cmd *exec.Cmd, kaskArgs []string, out io.Writer) error {
+	var kuiVersion bytes.Buffer
+	cmd.Args = append(cmd.Args, kaskArgs...)
+	component.result.Command = cmd.Args
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = &kuiVersion
+	context.logger().Debugf("args %s", cmd.Args)
+	err := cmd.Run()
+
+	fmt.Fprintf(out, "%v\t%v %v\n", blue(base), context.version, context.date)
+
+	version := strings.TrimSpace(kuiVersion.String())
+	if err == nil && version == "" {
+		err = fmt.Errorf("Kui did not report its version")
 	}
+	if err != nil {
+		context.logger().Debugf("unable to determine the Kui version %v", err)
+		fmt.Fprintf(out, "%v\t%v\n", blue("kui"), "<unavailable>")
+		return newError(ChildError, err)
+	}
+
+	fmt.Fprintf(out, "%v\t%v\n", blue("kui"), version)
+	return nil
 }
 
-func GetRootCommand(extractedDir string) *exec.Cmd {
-	switch runtime.GOOS {
-	case "windows":
-		// TODO verify
-		return exec.Command(filepath.Join(extractedDir, "Kui-base-win32-x64\\Kui.exe"))
-	case "darwin":
-		return exec.Command(filepath.Join(extractedDir, "Kui-base-darwin-x64/Kui.app/Contents/MacOS/Kui"))
-	default:
-		// TODO verify
-		return exec.Command(filepath.Join(extractedDir, "Kui-base-linux-x64/Kui"))
+func GetDistOSSuffix(context Context) string {
+	return distSuffix(context, PlatformKey())
+}
+
+func GetRootCommand(context Context, extractedDir string) *exec.Cmd {
+	return exec.Command(filepath.Join(extractedDir, rootCommandPath(context, PlatformKey())))
+}
+
+// GetDistLocation is distLocation, for callers with no way to handle
+// its error; we warn of that instead, rather than let a malformed
+// KASK_DIST_URL_TEMPLATE or KASK_CHANNEL pass unnoticed
+func GetDistLocation(context Context, version string) string {
+	location, err := distLocation(context, version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", yellow(fmt.Sprintf("Warning: unable to locate Kui base %s: %v", version, err)))
 	}
+	return location
 }
 
-func GetDistLocation(version string) string {
-	host := "https://s3-api.us-geo.objectstorage.softlayer.net/kui-" + version
-	DEV_OVERRIDE_HOST, overrideSet := os.LookupEnv("KUI_DIST")
+// distLocation returns the url of the dist of the given version, on
+// the KASK_CHANNEL channel; it fails only for a malformed
+// KASK_DIST_URL_TEMPLATE, or an unknown channel
+func distLocation(context Context, version string) (string, error) {
+	if template, isSet := lookupEnv(context, "KASK_DIST_URL_TEMPLATE"); isSet {
+		location, err := expandDistTemplate(context, template, version, PlatformKey())
+		if err != nil {
+			return location, err
+		}
+		return normalizeURL(location), nil
+	}
+
+	channel, err := distChannel(context)
+	if err != nil {
+		return "", err
+	}
+	host := "https://s3-api.us-geo.objectstorage.softlayer.net/" + channelPrefix(channel) + version
+	DEV_OVERRIDE_HOST, overrideSet := lookupEnv(context, "KUI_DIST")
 	if overrideSet {
 		host = DEV_OVERRIDE_HOST
 	}
 	if !strings.HasSuffix(host, "/") {
 		host += "/"
 	}
-	return host + "Kui" + GetDistOSSuffix()
+	return normalizeURL(host + "Kui" + GetDistOSSuffix(context)), nil
+}
+
+// distLocations returns the url of the dist of the given version,
+// followed by those of its mirrors, from the comma-separated templates
+// of KASK_DIST_MIRRORS
+func distLocations(context Context, version string) ([]string, error) {
+	location, err := distLocation(context, version)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []string{location}
+	for _, template := range strings.Split(getenv(context, "KASK_DIST_MIRRORS"), ",") {
+		if template = strings.TrimSpace(template); template == "" {
+			continue
+		}
+		mirror, err := expandDistTemplate(context, template, version, PlatformKey())
+		if err != nil {
+			return nil, fmt.Errorf("invalid KASK_DIST_MIRRORS: %v", err)
+		}
+		locations = append(locations, normalizeURL(mirror))
+	}
+	return locations, nil
+}
+
+var duplicateSlashes = regexp.MustCompile("/{2,}")
+
+// normalizeURL collapses duplicate slashes in the path of the given
+// url; some object stores treat "a//b" as a different key than "a/b".
+// The "//" following the scheme is of course preserved.
+func normalizeURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+	u.Path = duplicateSlashes.ReplaceAllString(u.Path, "/")
+	u.RawPath = duplicateSlashes.ReplaceAllString(u.RawPath, "/")
+	return u.String()
 }
 
 // DownloadFile will download a url to a local file. It's efficient because it will
 // write as it downloads and not load the whole file into memory.
-func DownloadFile(filepath string, url string) error {
+func DownloadFile(context Context, filepath string, url string) error {
+	_, _, err := ConditionalGet(context, url, filepath, Validators{})
+	return err
+}
 
-    // Get the data
-    resp, err := http.Get(url)
-    if err != nil {
-        return err
-    }
-    defer resp.Body.Close()
+func (p *KuiComponent) DownloadDistIfNecessary(context Context, force bool) (*exec.Cmd, error) {
+	return p.DownloadVersionIfNecessary(context, p.DistVersion(context), force)
+}
 
-    // Create the file
-    out, err := os.Create(filepath)
-    if err != nil {
-        return err
-    }
-    defer out.Close()
+// DownloadVersionIfNecessary ensures that the given version of the Kui
+// base is cached, returning the command that would run it. A transient
+// failure is retried up to KASK_INSTALL_RETRIES times, and a dist that
+// fails its checksum is fetched instead from the next of any mirrors.
+// If KASK_ALLOW_VERSION_FALLBACK is set, and the dist host does not
+// have this version, the latest earlier version is used instead.
+func (p *KuiComponent) DownloadVersionIfNecessary(context Context, version string, force bool) (*exec.Cmd, error) {
+	cmd, err := p.downloadVersion(context, version, force)
+	if err == nil || !versionFallbackAllowed(context) || !distNotFound(err) {
+		return cmd, err
+	}
 
-    // Write the body to file
-    _, err = io.Copy(out, resp.Body)
-    return err
+	earlier, fallbackErr := earlierDistVersion(context, version)
+	if fallbackErr != nil {
+		context.logger().Debugf("no fallback from Kui base %s: %v", version, fallbackErr)
+		return cmd, err
+	}
+	fmt.Fprintf(os.Stderr, "%v\n", yellow(fmt.Sprintf("Warning: Kui base %s is not available; using %s instead", version, earlier)))
+	return p.downloadVersion(context, earlier, force)
 }
 
-func (p *KuiComponent) DownloadDistIfNecessary(context Context, force bool) (*exec.Cmd, error) {
+// downloadVersion does the work of DownloadVersionIfNecessary, for
+// exactly the given version
+func (p *KuiComponent) downloadVersion(context Context, version string, force bool) (*exec.Cmd, error) {
+	retries, err := installRetries(context)
+	if err != nil {
+		return nil, newError(UsageError, err)
+	}
+
+	locations, err := distLocations(context, version)
+	if err != nil {
+		return nil, newError(UsageError, err)
+	}
+
+	for mirror := 0; ; mirror++ {
+		var cmd *exec.Cmd
+		policy := installRetryPolicy(retries)
+		policy.OnRetry = func(attempt int, err error) error {
+			context.logger().Debugf("retrying install of Kui base %s after %v", version, err)
+			return p.discardPartialInstall(context, version)
+		}
+		err := retry.Do(stdcontext.Background(), policy, func() error {
+			var err error
+			cmd, err = p.downloadVersionOnce(context, version, locations[mirror], force)
+			if err != nil {
+				if pluginDir, err := context.PluginDirectory(); err == nil {
+					forgetReachability(pluginDir)
+				}
+			}
+			return err
+		})
+		if err != nil {
+			p.reportFailure(context, version, locations[mirror], err)
+		}
+
+		// the corruption may be particular to this copy of the dist
+		if errors.Is(err, ErrChecksumMismatch) && mirror+1 < len(locations) {
+			context.logger().Debugf("retrying install of Kui base %s from %s after %v", version, locations[mirror+1], err)
+			if err := p.discardPartialInstall(context, version); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return cmd, err
+	}
+}
+
+func (p *KuiComponent) downloadVersionOnce(context Context, version string, url string, force bool) (_ *exec.Cmd, err error) {
 	Debug := context.logger().Debug
 	Debugf := context.logger().Debugf
+	emit(context, ResolvingEvent{version, url})
 
-	Debugf("force refetch? %v", force)
-
-	metadata := p.GetMetadata()
-	version := metadata.Version.String()
+	// however we fail, leave behind a valid cache or nothing, bar a
+	// partial download to resume
+	defer func() {
+		if err != nil {
+			if cleanupErr := p.discardPartialInstall(context, version); cleanupErr != nil {
+				Debugf("unable to clean up after the failed install %v", cleanupErr)
+			}
+		}
+	}()
 
-	url := GetDistLocation(version)
+	Debugf("force refetch? %v", force)
 
 	pluginDir, err := context.PluginDirectory()
 	if err != nil {
-		handleError(context, err)
-		return nil, err
+		return nil, fmt.Errorf("unable to locate the plugin directory: %w", err)
 	}
 
 	binDir := filepath.Join(pluginDir, "bin")
-	targetDir := filepath.Join(pluginDir, "/cache-"+version)
-	successFile := filepath.Join(targetDir, "success")
-	extractedDir := filepath.Join(targetDir, "extract")
-	Debugf("targetDir %s", targetDir)
+	cache := cacheLayoutOf(context, pluginDir, version)
+	successFile := cache.successFile
+	extractedDir := cache.extractedDir
+	downloadedFile := cache.downloadedFile
+	validatorsFile := cache.validatorsFile
+	manifestFile := cache.manifestFile
+	provenanceFile := cache.provenanceFile
+	Debugf("targetDir %s", cache.dir)
 
 	executable, err := os.Executable()
 	if err != nil {
-		handleError(context, err)
-		return nil, err
+		return nil, fmt.Errorf("unable to locate the kask executable: %w", err)
 	}
 	basenameOfSelf := filepath.Base(executable)
 
-	command := GetRootCommand(extractedDir)
-	command.Env = append(os.Environ(), "KUI_BIN_DIR=" + binDir, "KUI_BIN_PREFIX=kubectl-", "KUI_BIN_PREFIX_FOR_COMMANDS=kubectl", "KUI_BIN=" + executable, "KUI_DEFAULT_PRETTY_TYPE=" + basenameOfSelf)
+	command := GetRootCommand(context, extractedDir)
+	command.Env = append(childEnvironment(context, withConfigEnvironment(context, os.Environ())), "KUI_BIN_DIR=" + binDir, "KUI_BIN_PREFIX=kubectl-", "KUI_BIN_PREFIX_FOR_COMMANDS=kubectl", "KUI_BIN=" + executable, "KUI_DEFAULT_PRETTY_TYPE=" + basenameOfSelf)
+
+	extractOptions, err := GetExtractOptions(context)
+	if err != nil {
+		return nil, newError(UsageError, err)
+	}
+
+	// a system-wide cache, if it has this version, spares us our own
+	if !force {
+		if systemDir, ok := systemExtract(context, version, extractOptions); ok {
+			Debugf("Using the system-wide Kui base %s", systemDir)
+			p.result.CacheUsed = true
+			system := GetRootCommand(context, systemDir)
+			system.Env = command.Env
+			return system, nil
+		}
+	}
+
+	// the download and extraction, together, must finish within KASK_DOWNLOAD_TIMEOUT
+	ctx, cancel, err := downloadContext(context)
+	if err != nil {
+		return nil, newError(UsageError, err)
+	}
+	defer cancel()
+
+	// whether we already have the archive we need to extract
+	fetched := false
+
+	// a cache extracted with a different subset is no good to us, but
+	// the archive it was extracted from is
+	if marker, err := ioutil.ReadFile(successFile); err == nil && string(marker) != extractOptions.Include && !offlineMode(context) {
+		Debugf("cached extract does not match KASK_EXTRACT_INCLUDE; re-extracting")
+		os.Remove(successFile)
+		os.RemoveAll(extractedDir)
+		_, err := os.Stat(downloadedFile)
+		fetched = err == nil
+	}
+
+	// the same version on another channel is another Kui base, so the
+	// cache of that one, archive and all, is no good to us
+	channel, err := distChannel(context)
+	if err != nil {
+		return nil, newError(UsageError, err)
+	}
+	if _, err := os.Stat(successFile); err == nil && !offlineMode(context) {
+		if cached := readProvenance(provenanceFile).channel(); cached != channel {
+			Debugf("cached Kui base %s is from the %s channel, not %s; fetching afresh", version, cached, channel)
+			// not excepting a partial download, which is of that one too
+			os.Remove(successFile)
+			os.RemoveAll(cache.dir)
+			fetched = false
+		}
+	}
+
+	// the checksum the dist should have, once we have looked it up
+	expected := ""
+	haveExpected := false
+
+	// the checksum of the dist, once we have verified it
+	checksum := ""
+	verified := false
+	// whether we re-extract the archive we have, as verified
+	reextracting := false
+
+	// when debugging extraction, extract afresh every time, from the
+	// archive we already have, as verified against the checksum we
+	// recorded of it, rather than against the dist host's
+	if _, err := os.Stat(successFile); err == nil && alwaysExtract(context) && !offlineMode(context) {
+		if _, err := os.Stat(downloadedFile); err == nil {
+			recorded := readProvenance(provenanceFile).SHA256
+			if actual, err := sha256File(downloadedFile); err != nil || recorded == "" || actual != recorded {
+				Debugf("KASK_ALWAYS_EXTRACT is set, but the archive does not match its recorded checksum; keeping the extract")
+			} else {
+				Debugf("KASK_ALWAYS_EXTRACT is set; re-extracting")
+				os.Remove(successFile)
+				os.RemoveAll(extractedDir)
+				fetched = true
+				checksum, verified = actual, true
+				reextracting = true
+			}
+		}
+	}
+
+	// a refresh is impossible offline, so keep whatever we have cached
+	if force && !offlineMode(context) {
+		if _, err := os.Stat(successFile); err == nil {
+			// we have a cached copy; only re-fetch if the dist host says it has changed
+			if err := probeDistHostCached(context, pluginDir, url); err != nil {
+				return nil, newError(DownloadError, err)
+			}
+
+			if expected, err = expectedChecksum(ctx, context, url); err != nil {
+				err = timedOut(ctx, context, err)
+				return nil, newError(ChecksumError, err)
+			}
+			haveExpected = true
+			if expected != "" && expected == readProvenance(provenanceFile).SHA256 {
+				emit(context, UpToDateEvent{version, url})
+				p.result.CacheUsed = true
+				return command, nil
+			}
+
+			updated, actual, err := fetchDist(ctx, context, url, downloadedFile, validatorsFile, expected)
+			if err != nil {
+				err = timedOut(ctx, context, err)
+				return nil, newError(DownloadError, err)
+			}
+			if !updated {
+				Debug("Kui base is unchanged, keeping cached download")
+				emit(context, UpToDateEvent{version, url})
+				p.result.CacheUsed = true
+				return command, nil
+			}
+			p.result.recordDownload(downloadedFile)
+			fetched = true
+			checksum, verified = actual, true
+		}
 
-	if force {
 		err := os.Remove(successFile)
 		if err != nil {
 			Debugf("error removing lock file %v", err)
@@ -294,65 +921,252 @@ func (p *KuiComponent) DownloadDistIfNecessary(context Context, force bool) (*ex
 		}
 	}
 
+	// we may have been interrupted after extracting, but before marking
+	// the extract as complete; adopt it if it checks out, otherwise
+	// start over from a clean slate
+	if _, err := os.Stat(successFile); err != nil && populated(extractedDir) {
+		if err := validateExtract(extractedDir, manifestFile, provenanceFile, command.Path, extractOptions); err != nil {
+			Debugf("discarding unmarked extract %v", err)
+			os.RemoveAll(extractedDir)
+		} else if err := ioutil.WriteFile(successFile, []byte(extractOptions.Include), 0666); err == nil {
+			Debug("adopted unmarked extract")
+		}
+	}
+
 	if _, err := os.Stat(successFile); err != nil {
-		downloadedFile := filepath.Join(targetDir, "downloaded.zip")
-		extractedDir := filepath.Join(targetDir, "extract")
+		if offlineMode(context) {
+			err := newErrorf(OfflineMissError, "Kui base %s is not cached, and offline mode is enabled", version)
+			return nil, err
+		}
 
-		os.MkdirAll(extractedDir, 0700)
+		os.MkdirAll(cache.dir, 0700)
+		os.MkdirAll(cache.stateDir, 0700)
 
-		if err := DownloadFile(downloadedFile, url); err != nil {
-			handleError(context, err)
-			return nil, err
+		// the ETag of the dist, if the server tells us
+		etag := ""
+
+		// a copy on a shared mount spares us the dist host
+		if !fetched {
+			copied, actual, err := copyLocalDist(ctx, context, version, url, downloadedFile)
+			if err != nil {
+				return nil, err
+			}
+			if copied {
+				Debugf("Copied Kui base %s from KASK_DIST_LOCAL", version)
+				os.Remove(validatorsFile)
+				fetched = true
+				checksum, verified = actual, true
+			}
+		}
+
+		if !fetched {
+			if err := probeDistHostCached(context, pluginDir, url); err != nil {
+				return nil, newError(DownloadError, err)
+			}
+			if !haveExpected {
+				if expected, err = expectedChecksum(ctx, context, url); err != nil {
+					err = timedOut(ctx, context, err)
+					return nil, newError(ChecksumError, err)
+				}
+				haveExpected = true
+			}
+		}
+
+		if !fetched && contentAddressed() && expected != "" && blobOf(pluginDir, expected, extractOptions).intact() {
+			// we already have this content, under another version
+			Debugf("Kui base %s is already cached under its checksum %s", version, expected)
+			p.result.CacheUsed = true
+			checksum, verified = expected, true
+		} else if !fetched {
+			object, err := checkDistObject(ctx, context, url, version)
+			if err != nil {
+				err = timedOut(ctx, context, err)
+				return nil, newError(DownloadError, err)
+			}
+			etag = object.etag
+
+			if same := sameContentAs(context, pluginDir, version, url, etag, expected, extractOptions); same != "" {
+				// another version's dist had the same ETag, and so the same bytes
+				Debugf("Kui base %s has the ETag %s of the cached %s", version, etag, same)
+				p.result.CacheUsed = true
+				checksum, verified = same, true
+			} else {
+				if err := p.confirmDownload(context, version, url, object.size); err != nil {
+					return nil, err
+				}
+
+				// the archive we have, if any, may be why we are here, so
+				// fetch afresh rather than conditionally
+				os.Remove(validatorsFile)
+				start := time.Now()
+				progress := func(bytes int64, total int64) {
+					emit(context, DownloadingEvent{Version: version, URL: url, Bytes: bytes, Total: total})
+				}
+				_, actual, err := fetchDist(withDownloadProgress(ctx, progress), context, url, downloadedFile, validatorsFile, expected)
+				if err != nil {
+					err = timedOut(ctx, context, err)
+					return nil, newError(DownloadError, err)
+				}
+				if info, err := os.Stat(downloadedFile); err == nil {
+					emit(context, DownloadingEvent{version, url, info.Size(), info.Size(), true, time.Since(start)})
+				}
+				p.result.recordDownload(downloadedFile)
+				checksum, verified = actual, true
+			}
 		}
 
 		// link ourselves to kubectl-<basename>
-		targetOfSymlink := filepath.Join(binDir, "kubectl-" + basenameOfSelf)
-		os.Remove(targetOfSymlink)
-		os.Symlink(executable, targetOfSymlink)
-		Debugf("Symlinked ourselves to %s", targetOfSymlink)
+		if targetOfSymlink, err := linkSelf(binDir, executable); err != nil {
+			Debugf("error symlinking ourselves %v", err)
+		} else {
+			Debugf("Symlinked ourselves to %s", targetOfSymlink)
+			emit(context, LinkingEvent{targetOfSymlink})
+		}
 
 		Debugf("Downloaded kui-base %s", downloadedFile)
-		Debugf("Extracting kui-base %s", extractedDir)
 
-		if strings.HasSuffix(url, ".tar.bz2") {
-			if err := archiver.DefaultTarBz2.Unarchive(downloadedFile, extractedDir); err != nil {
-				handleError(context, err)
+		// an archive that we did not just download has yet to be verified
+		if !verified {
+			if !haveExpected {
+				if expected, err = expectedChecksum(ctx, context, url); err != nil {
+					err = timedOut(ctx, context, err)
+					return nil, newError(ChecksumError, err)
+				}
+			}
+			if checksum, err = verifyDist(context, url, downloadedFile, expected); err != nil {
 				return nil, err
 			}
+		}
+
+		// these describe a complete extract, which we no longer have;
+		// though the provenance still vouches for an archive we re-extract
+		os.Remove(manifestFile)
+		if !reextracting {
+			os.Remove(provenanceFile)
+		}
+
+		// tell of the extraction as it goes, and when it is over
+		extracted := 0
+		extractOptions.Progress = func(entries int) {
+			extracted = entries
+			emit(context, ExtractingEvent{Version: version, Entries: entries})
+		}
+		extractionOver := func() {
+			emit(context, ExtractingEvent{version, extracted, true})
+		}
+
+		if contentAddressed() {
+			blob := blobOf(pluginDir, checksum, extractOptions)
+			if blob.intact() && !alwaysExtract(context) {
+				Debugf("Reusing extract %s", blob.extractedDir)
+			} else {
+				Debugf("Extracting kui-base %s", blob.extractedDir)
+				extractor, err := extractBlob(ctx, context, url, downloadedFile, blob, extractOptions)
+				extractionOver()
+				if err != nil {
+					return nil, err
+				}
+				Debugf("Extracted kui-base %s using %s", blob.extractedDir, extractor)
+			}
+			if err := linkToBlob(extractedDir, blob); err != nil {
+				return nil, newError(ExtractError, fmt.Errorf("unable to link the Kui base: %w", err))
+			}
 		} else {
-			if err := archiver.DefaultZip.Unarchive(downloadedFile, extractedDir); err != nil {
-				handleError(context, err)
+			Debugf("Extracting kui-base %s", extractedDir)
+			// whatever an earlier, failed, attempt left must not merge into this one
+			if err := os.RemoveAll(extractedDir); err != nil {
+				return nil, newError(ExtractError, fmt.Errorf("unable to clear the extract of a failed attempt: %w", err))
+			}
+			os.MkdirAll(extractedDir, 0700)
+			extractor, err := extractDist(ctx, context, url, downloadedFile, extractedDir, extractOptions)
+			extractionOver()
+			if err != nil {
 				return nil, err
 			}
+			Debugf("Extracted kui-base %s using %s", extractedDir, extractor)
 		}
 
-		Debugf("Extracted kui-base %s", extractedDir)
+		manifest, err := BuildManifest(extractedDir, manifestHashes(context))
+		if err != nil {
+			return nil, newError(ExtractError, err)
+		}
+		if err := writeManifest(manifestFile, manifest); err != nil {
+			return nil, newError(ExtractError, fmt.Errorf("unable to record the manifest of the Kui base: %w", err))
+		}
 
-		if _, err := os.OpenFile(successFile, os.O_RDONLY|os.O_CREATE, 0666); err != nil {
-			handleError(context, err)
+		if validators := readValidators(validatorsFile); validators.ETag != "" {
+			etag = validators.ETag
+		}
+		kuiVersion := ""
+		if verifyKuiVersion(context) {
+			kuiVersion = checkKuiVersion(command, version, os.Stderr)
+		}
+		if err := writeProvenance(provenanceFile, Provenance{version, url, PlatformKey(), checksum, time.Now(), etag, kuiVersion, channel}); err != nil {
+			return nil, newError(ExtractError, fmt.Errorf("unable to record the provenance of the Kui base: %w", err))
+		}
+
+		// an install whose required post-install step failed is no
+		// install, so that the next run tries again
+		if err := runPostInstall(context, version, pluginDir, command); err != nil {
+			os.RemoveAll(extractedDir)
 			return nil, err
 		}
+
+		// the success marker records what subset, if any, we extracted
+		if err := ioutil.WriteFile(successFile, []byte(extractOptions.Include), 0666); err != nil {
+			return nil, newError(ExtractError, fmt.Errorf("unable to mark the Kui base as cached: %w", err))
+		}
 	} else {
 		Debug("Using cached download")
+		p.result.CacheUsed = true
+
+		repaired, err := repairExecutable(command.Path)
+		if err != nil {
+			return nil, newError(ExtractError, fmt.Errorf("the cached Kui executable %s is not executable: %w", command.Path, err))
+		} else if repaired {
+			Debugf("restored the execute bits of %s", command.Path)
+		}
+
+		// caches from before content addressing move over as we come across them
+		if checksum := readProvenance(provenanceFile).SHA256; contentAddressed() && checksum != "" {
+			if err := migrateToBlob(context, pluginDir, cache, checksum, extractOptions); err != nil {
+				Debugf("unable to move the extract to %s %v", checksum, err)
+			}
+		}
 	}
 
 	return command, nil
 }
 
+// offline mode forbids any download; we may only use a cached Kui base
+func offlineMode(context Context) bool {
+	_, offline := lookupEnv(context, "KASK_OFFLINE")
+	return offline
+}
+
+// whether to discard the extract, though not the archive, of a cached
+// Kui base on every run
+func alwaysExtract(context Context) bool {
+	_, always := lookupEnv(context, "KASK_ALWAYS_EXTRACT")
+	return always
+}
+
+// MakeExecutable makes the file at path executable by whoever may
+// read it, leaving the rest of its mode as it is
 func MakeExecutable(path string) error {
-	return filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
-		if err == nil {
-			err = os.Chmod(name, 0700)
-		}
+	info, err := os.Stat(path)
+	if err != nil {
 		return err
-	})
+	}
+	perm := info.Mode().Perm()
+	return os.Chmod(path, perm|(perm&0444)>>2)
 }
 
 type Command struct {
-	Name string
-	Alias string
-	Description string
-	Usage string
+	Name string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+	Description string `json:"description,omitempty"`
+	Usage string `json:"usage,omitempty"`
 }
 type Metadata struct {
 	Name string
@@ -371,16 +1185,35 @@ func (component *KuiComponent) GetMetadata() Metadata {
 				Description: "Kask for Krew",
 				Usage:       "krew kask install ...",
 			},
+			{
+				Name:        "list",
+				Description: "List installed plugins",
+				Usage:       "kask list",
+			},
+			{
+				Name:        "install",
+				Alias:       "i",
+				Description: "Install one or more plugins",
+				Usage:       "kask install <plugin>...",
+			},
+			{
+				Name:        "uninstall",
+				Description: "Remove a previously installed plugin",
+				Usage:       "kask uninstall <plugin>",
+			},
 		},
 	}
 }
 
 func handleError(context Context, err error) {
-	switch err {
-	case nil:
+	switch {
+	case err == nil:
 		return
+	case jsonOutput(context):
+		// Start will report this as JSON; human-readable logs would only get in the way
+		context.logger().Debugf("error %v", err)
 	default:
-		context.logger().Errorw("msg", T("An error has occurred:\n{{.Error}}\n", map[string]interface{}{"Error": err.Error()}))
+		context.logger().Errorw(T("An error has occurred:\n{{.Error}}\n", map[string]interface{}{"Error": err.Error()}), "kind", KindOf(err).String())
 	}
 
 	return
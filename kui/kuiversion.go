@@ -0,0 +1,48 @@
+package kui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// whether to ask a freshly extracted Kui base for its version, to catch
+// a mis-tagged dist; this costs an extra launch of Kui per install
+func verifyKuiVersion(context Context) bool {
+	_, verify := lookupEnv(context, "KASK_VERIFY_VERSION")
+	return verify
+}
+
+// reportedKuiVersion runs the given Kui headlessly, to ask its version
+func reportedKuiVersion(cmd *exec.Cmd) (string, error) {
+	query := cloneCommand(cmd)
+	query.Args = append(query.Args, "version")
+	query.Env = append(query.Env, "KUI_HEADLESS=true")
+	var stdout bytes.Buffer
+	query.Stdout = &stdout
+	if err := query.Run(); err != nil {
+		return "", err
+	}
+
+	version := strings.TrimSpace(stdout.String())
+	if version == "" {
+		return "", fmt.Errorf("Kui did not report its version")
+	}
+	return version, nil
+}
+
+// checkKuiVersion warns, on out, if the given Kui reports other than
+// the expected version; it returns the reported version, if any
+func checkKuiVersion(cmd *exec.Cmd, expected string, out io.Writer) string {
+	actual, err := reportedKuiVersion(cmd)
+	if err != nil {
+		fmt.Fprintf(out, "%v\n", yellow(fmt.Sprintf("Unable to check the version of Kui base %s: %v", expected, err)))
+		return ""
+	}
+	if strings.TrimPrefix(actual, "v") != strings.TrimPrefix(expected, "v") {
+		fmt.Fprintf(out, "%v\n", yellow(fmt.Sprintf("Kui base %s reports that it is version %s", expected, actual)))
+	}
+	return actual
+}
@@ -0,0 +1,37 @@
+package kui
+
+import (
+	"bytes"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestVerifyKuiVersionMismatch() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_VERIFY_VERSION", "true")()
+	defer setenv("FAKE_KUI_VERSION", "9.9.9")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	provenance := readProvenance(filepath.Join(pluginDir, "cache-1.2.3", "provenance.json"))
+	suite.Equal("1.2.3", provenance.Version)
+	suite.Equal("9.9.9", provenance.KuiVersion)
+}
+
+func (suite *KaskTestSuite) TestCheckKuiVersion() {
+	suite.skipUnlessLinux()
+	cmd := suite.fakeKuiCommand()
+
+	var out bytes.Buffer
+	cmd.Env = append(cmd.Env, "FAKE_KUI_VERSION=v1.2.3")
+	suite.Equal("v1.2.3", checkKuiVersion(cmd, "1.2.3", &out))
+	suite.Empty(out.String())
+
+	cmd.Env = append(cmd.Env, "FAKE_KUI_VERSION=9.9.9")
+	suite.Equal("9.9.9", checkKuiVersion(cmd, "1.2.3", &out))
+	suite.Contains(out.String(), "reports that it is version 9.9.9")
+}
@@ -0,0 +1,153 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// how long we trust a previous reading of the latest pointer
+const distLatestTTL = time.Hour
+
+// how long we wait for the latest pointer, before settling for the
+// version we were built with
+const distLatestTimeout = 10 * time.Second
+
+// the pointer is a version string; anything bigger than this is not
+const maxDistLatestSize = 1024
+
+var distVersionPattern = regexp.MustCompile("^[0-9A-Za-z][0-9A-Za-z.+_-]*$")
+
+// the on-disk memory of the last reading of the latest pointer
+type distLatestCache struct {
+	Location  string    `json:"location"`
+	Version   string    `json:"version"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// DistVersion returns the version of the Kui base to run: by default,
+// the version kask was built with; but, if KASK_DIST_LATEST names a
+// "latest" pointer, the version it points to; {channel} in the pointer's
+// url is replaced by the KASK_CHANNEL. The pointer is read at most once
+// per distLatestTTL, and failing to read it means the built-in version.
+func (component *KuiComponent) DistVersion(context Context) string {
+	builtin := component.GetMetadata().Version.String()
+	location, isSet := lookupEnv(context, "KASK_DIST_LATEST")
+	if !isSet || location == "" {
+		return builtin
+	}
+
+	channel, err := distChannel(context)
+	if err != nil {
+		context.logger().Debugf("using Kui base %s %v", builtin, err)
+		return builtin
+	}
+	location = strings.Replace(location, "{channel}", channel, -1)
+
+	version, err := latestDistVersion(context, location)
+	if err != nil {
+		context.logger().Debugf("using Kui base %s, as the latest pointer is unavailable %v", builtin, err)
+		return builtin
+	}
+	return version
+}
+
+func latestDistVersion(context Context, location string) (string, error) {
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return "", err
+	}
+	cacheFile := filepath.Join(pluginDir, "dist-latest.json")
+
+	var cache distLatestCache
+	if bytes, err := ioutil.ReadFile(cacheFile); err == nil {
+		json.Unmarshal(bytes, &cache)
+	}
+	if cache.Location == location && cache.Version != "" && (offlineMode(context) || time.Since(cache.CheckedAt) < distLatestTTL) {
+		return cache.Version, nil
+	}
+	if offlineMode(context) {
+		return "", fmt.Errorf("the latest pointer has not been read, and offline mode is enabled")
+	}
+
+	version, err := fetchDistLatest(context, location)
+	if err != nil {
+		return "", err
+	}
+
+	cache = distLatestCache{location, version, time.Now()}
+	if bytes, err := json.Marshal(cache); err == nil {
+		if err := os.MkdirAll(pluginDir, 0700); err == nil {
+			ioutil.WriteFile(cacheFile, bytes, 0644)
+		}
+	}
+	return version, nil
+}
+
+// fetchDistLatest reads the version from the latest pointer at the
+// given url; either the bare version, e.g. "1.2.3", or a json object
+// such as {"version": "1.2.3"}
+func fetchDistLatest(context Context, url string) (string, error) {
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), distLatestTimeout)
+	defer cancel()
+
+	var body []byte
+	err := withRetries(ctx, context, func() error {
+		var err error
+		body, err = readDistLatest(ctx, context, url)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(body) > maxDistLatestSize {
+		return "", fmt.Errorf("latest pointer %s is too large", url)
+	}
+	return parseDistLatest(body)
+}
+
+// readDistLatest reads the latest pointer at the given url, and a byte
+// more than it may hold, so that the caller can tell if it is too large
+func readDistLatest(ctx stdcontext.Context, context Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doRequest(context, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPStatusError{url, resp.StatusCode, resp.Status}
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxDistLatestSize+1))
+}
+
+func parseDistLatest(body []byte) (string, error) {
+	version := strings.TrimSpace(string(body))
+
+	var pointer struct {
+		Version string `json:"version"`
+	}
+	if strings.HasPrefix(version, "{") {
+		if err := json.Unmarshal(body, &pointer); err != nil {
+			return "", fmt.Errorf("unable to parse the latest pointer: %v", err)
+		}
+		version = strings.TrimSpace(pointer.Version)
+	}
+
+	version = strings.TrimPrefix(version, "v")
+	if !distVersionPattern.MatchString(version) {
+		return "", fmt.Errorf("invalid version %q in the latest pointer", version)
+	}
+	return version, nil
+}
@@ -0,0 +1,78 @@
+package kui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+)
+
+// a dist host that also publishes a latest pointer, counting requests for it
+func serveDistWithLatest(dist []byte, latest *string, latestRequests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/latest.txt") {
+			*latestRequests++
+			w.Write([]byte(*latest))
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(dist)
+	}))
+}
+
+func (suite *KaskTestSuite) TestDistVersionFromLatestPointer() {
+	latest := "1.2.3\n"
+	latestRequests := 0
+	server := serveDistWithLatest(makeFakeDist(), &latest, &latestRequests)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_LATEST", server.URL+"/latest.txt")()
+
+	suite.Equal("1.2.3", suite.cmd.DistVersion(suite.pluginContext))
+
+	latest = "2.0.0"
+	suite.Equal("1.2.3", suite.cmd.DistVersion(suite.pluginContext), "the pointer should be cached")
+	suite.Equal(1, latestRequests)
+}
+
+func (suite *KaskTestSuite) TestDistVersionFallsBack() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_LATEST", server.URL+"/latest.txt")()
+
+	suite.Equal(suite.version, suite.cmd.DistVersion(suite.pluginContext))
+}
+
+func (suite *KaskTestSuite) TestDistVersionWithoutPointer() {
+	suite.Equal(suite.version, suite.cmd.DistVersion(suite.pluginContext))
+}
+
+func (suite *KaskTestSuite) TestDownloadLatestPointer() {
+	suite.skipUnlessLinux()
+	latest := `{"version": "v1.2.3"}`
+	latestRequests := 0
+	server := serveDistWithLatest(makeFakeDist(), &latest, &latestRequests)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_DIST_LATEST", server.URL+"/latest.txt")()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	suite.FileExists(filepath.Join(pluginDir, "cache-1.2.3", "success"))
+}
+
+func (suite *KaskTestSuite) TestParseDistLatest() {
+	version, err := parseDistLatest([]byte("v1.2.3\n"))
+	suite.Nil(err)
+	suite.Equal("1.2.3", version)
+
+	_, err = parseDistLatest([]byte("../../etc"))
+	suite.NotNil(err)
+	_, err = parseDistLatest([]byte(`{"name": "x"}`))
+	suite.NotNil(err)
+}
@@ -0,0 +1,117 @@
+package kui
+
+import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// localDist returns the archive of the given version in KASK_DIST_LOCAL,
+// a directory, e.g. on a network mount shared by a team, laid out as
+// <dir>/<version>/<name of the dist>; "" if unset, or if it does not
+// have this version
+func localDist(context Context, version string, location string) string {
+	dir := getenv(context, "KASK_DIST_LOCAL")
+	if dir == "" {
+		return ""
+	}
+	name := path.Base(location)
+	if u, err := url.Parse(location); err == nil {
+		name = path.Base(u.Path)
+	}
+
+	local := filepath.Join(dir, version, name)
+	if info, err := os.Stat(local); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	return local
+}
+
+// localChecksum returns the digest that the given local dist must
+// have: that of KASK_DIST_SHA256, else that published alongside it,
+// in <dist>.sha256; "" if neither is there
+func localChecksum(context Context, local string) (string, error) {
+	if pinned, isPinned := lookupEnv(context, "KASK_DIST_SHA256"); isPinned {
+		digest, err := parseChecksum(pinned)
+		if err != nil {
+			return "", fmt.Errorf("invalid KASK_DIST_SHA256: %v", err)
+		}
+		return digest, nil
+	}
+
+	published, err := ioutil.ReadFile(local + ".sha256")
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if len(published) > maxChecksumSize {
+		return "", fmt.Errorf("checksum for %s is too large to be one", local)
+	}
+	return parseChecksum(string(published))
+}
+
+// copyLocalDist copies the given version's dist from KASK_DIST_LOCAL,
+// if it is there, into file, verifying its checksum; returning whether
+// it did, and the checksum. Lacking a checksum beside the local dist,
+// we use the one the dist host publishes; lacking that too, we do not
+// trust the local dist at all, and leave the dist host to supply it.
+func copyLocalDist(ctx stdcontext.Context, context Context, version string, location string, file string) (bool, string, error) {
+	local := localDist(context, version, location)
+	if local == "" {
+		return false, "", nil
+	}
+
+	expected, err := localChecksum(context, local)
+	if err != nil {
+		return false, "", newError(ChecksumError, err)
+	}
+	if expected == "" {
+		if expected, err = expectedChecksum(ctx, context, location); err != nil {
+			context.logger().Debugf("not using %s, as its checksum is unavailable: %v", local, err)
+			return false, "", nil
+		} else if expected == "" {
+			context.logger().Debugf("not using %s, having no checksum to verify it against", local)
+			return false, "", nil
+		}
+	}
+	if err := copyFile(local, file); err != nil {
+		return false, "", newError(DownloadError, fmt.Errorf("unable to copy the Kui base from %s: %w", local, err))
+	}
+	checksum, err := verifyDist(context, local, file, expected)
+	if err != nil {
+		return false, "", err
+	}
+	return true, checksum, nil
+}
+
+// copyFile copies src to dst, by way of a temporary file beside dst,
+// so that an interrupted copy does not pass for a complete one
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	part := dst + ".copy"
+	out, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(part)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(part)
+		return err
+	}
+	return os.Rename(part, dst)
+}
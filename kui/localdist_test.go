@@ -0,0 +1,84 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// a KASK_DIST_LOCAL directory holding the given version of the dist,
+// along with its checksum
+func (suite *KaskTestSuite) makeLocalDist(version string, dist []byte, checksum string) string {
+	dir, err := ioutil.TempDir(suite.SaveDir, "local")
+	suite.Require().Nil(err)
+	archive := filepath.Join(dir, version, path.Base(GetDistLocation(suite.pluginContext, version)))
+	suite.Require().Nil(os.MkdirAll(filepath.Dir(archive), 0755))
+	suite.Require().Nil(ioutil.WriteFile(archive, dist, 0644))
+	suite.Require().Nil(ioutil.WriteFile(archive+".sha256", []byte(checksum+"  "+filepath.Base(archive)+"\n"), 0644))
+	return dir
+}
+
+func (suite *KaskTestSuite) TestLocalDist() {
+	suite.skipUnlessLinux()
+	// the dist host is out of reach, but the shared mount is not
+	defer suite.isolate(unreachableURL())()
+	dist := makeFakeDist()
+	defer setenv("KASK_DIST_LOCAL", suite.makeLocalDist("1.2.3", dist, sha256Hex(dist)))()
+
+	cmd, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.FileExists(cmd.Path)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	suite.Equal(sha256Hex(dist), readProvenance(filepath.Join(pluginDir, "cache-1.2.3", "provenance.json")).SHA256)
+}
+
+func (suite *KaskTestSuite) TestLocalDistChecksumMismatch() {
+	defer suite.isolate(unreachableURL())()
+	dist := makeFakeDist()
+	defer setenv("KASK_DIST_LOCAL", suite.makeLocalDist("1.2.3", dist, sha256Hex([]byte("something else"))))()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Equal(ChecksumError, KindOf(err))
+}
+
+func (suite *KaskTestSuite) TestLocalDistAbsent() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	dist := makeFakeDist()
+	defer setenv("KASK_DIST_LOCAL", suite.makeLocalDist("4.5.6", dist, sha256Hex(dist)))()
+
+	// the local mirror lacks this version, so we fetch it as usual
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Nil(err)
+}
+
+func (suite *KaskTestSuite) TestLocalDistWithoutChecksum() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	local := suite.makeLocalDist("1.2.3", dist, sha256Hex(dist))
+	suite.Require().Nil(os.Remove(filepath.Join(local, "1.2.3", path.Base(GetDistLocation(suite.pluginContext, "1.2.3"))+".sha256")))
+	defer setenv("KASK_DIST_LOCAL", local)()
+
+	// with no checksum anywhere, the local dist is not to be trusted
+	gets, notModified := 0, 0
+	server := serveWithETag(dist, `"v1"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.Equal(1, gets, "the dist should have come from the dist host")
+
+	// but the dist host's checksum vouches for it
+	checksum := sha256Hex(dist)
+	vouching := serveDistCountingGets(&dist, &checksum, &gets)
+	defer vouching.Close()
+	defer suite.isolate(vouching.URL)()
+	gets = 0
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.Equal(0, gets, "the verified local dist should have been used")
+}
@@ -0,0 +1,31 @@
+package kui
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	log "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func (suite *KaskTestSuite) TestInjectedLogger() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	context := NewMainContext("dev", "", "unknown", log.New(core).Sugar())
+
+	err := suite.cmd.Run(context, []string{"kask", "list"})
+	suite.Require().NotNil(err)
+	report(context, err, ioutil.Discard)
+	suite.NotZero(logs.Len(), "entries should go to the injected logger")
+	suite.Equal(1, logs.FilterLevelExact(zapcore.ErrorLevel).Len())
+}
+
+func (suite *KaskTestSuite) TestDefaultLogger() {
+	context := NewMainContext("dev", "", "unknown", nil)
+	suite.NotNil(context.logger())
+}
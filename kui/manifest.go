@@ -0,0 +1,228 @@
+package kui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestEntry records a file (or symlink) that we extracted
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Link   string `json:"link,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Manifest records the contents of an extracted Kui base, so that we
+// can later detect tampering or partial deletion
+type Manifest struct {
+	Entries []ManifestEntry `json:"entries"`
+
+	// whether the entries record content hashes
+	WithHashes bool `json:"withHashes,omitempty"`
+}
+
+// ManifestDiff lists the paths that differ between two manifests
+type ManifestDiff struct {
+	Missing []string `json:"missing"`
+	Extra   []string `json:"extra"`
+	Changed []string `json:"changed"`
+}
+
+func (diff ManifestDiff) Empty() bool {
+	return len(diff.Missing) == 0 && len(diff.Extra) == 0 && len(diff.Changed) == 0
+}
+
+// whether to record content hashes in the manifest, in addition to sizes
+func manifestHashes(context Context) bool {
+	_, hashes := lookupEnv(context, "KASK_MANIFEST_HASHES")
+	return hashes
+}
+
+// BuildManifest describes the files and symlinks beneath dir
+func BuildManifest(dir string, withHashes bool) (Manifest, error) {
+	manifest := Manifest{Entries: []ManifestEntry{}, WithHashes: withHashes}
+
+	// dir itself may be a symlink, e.g. to a shared blob
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		entry := ManifestEntry{Path: filepath.ToSlash(rel)}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if entry.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		} else {
+			entry.Size = info.Size()
+			if withHashes {
+				if entry.SHA256, err = sha256File(path); err != nil {
+					return err
+				}
+			}
+		}
+
+		manifest.Entries = append(manifest.Entries, entry)
+		return nil
+	})
+
+	// in order of path, rather than of the walk, so that identical
+	// trees have byte-identical manifests
+	sort.Slice(manifest.Entries, func(i, j int) bool { return manifest.Entries[i].Path < manifest.Entries[j].Path })
+	return manifest, err
+}
+
+// hashed returns whether m records content hashes; a manifest that
+// predates WithHashes does if any of its entries has one
+func (m Manifest) hashed() bool {
+	if m.WithHashes {
+		return true
+	}
+	for _, entry := range m.Entries {
+		if entry.SHA256 != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// buildLike describes dir as m was built, with hashes if m has them,
+// for a Diff against m
+func (m Manifest) buildLike(dir string) (Manifest, error) {
+	return BuildManifest(dir, m.hashed())
+}
+
+// Diff compares actual against the expected manifest m. Hashes are
+// compared only if recorded in both.
+func (m Manifest) Diff(actual Manifest) ManifestDiff {
+	diff := ManifestDiff{Missing: []string{}, Extra: []string{}, Changed: []string{}}
+
+	found := map[string]ManifestEntry{}
+	for _, entry := range actual.Entries {
+		found[entry.Path] = entry
+	}
+
+	for _, expected := range m.Entries {
+		entry, ok := found[expected.Path]
+		if !ok {
+			diff.Missing = append(diff.Missing, expected.Path)
+			continue
+		}
+		delete(found, expected.Path)
+
+		if entry.Size != expected.Size || entry.Link != expected.Link ||
+			(entry.SHA256 != "" && expected.SHA256 != "" && entry.SHA256 != expected.SHA256) {
+			diff.Changed = append(diff.Changed, expected.Path)
+		}
+	}
+
+	for path := range found {
+		diff.Extra = append(diff.Extra, path)
+	}
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Extra)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
+
+func writeManifest(file string, manifest Manifest) error {
+	bytes, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, bytes, 0644)
+}
+
+func readManifest(file string) (Manifest, error) {
+	var manifest Manifest
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(bytes, &manifest)
+	return manifest, err
+}
+
+// VerifyCache compares the extracted Kui base of the given version
+// against the manifest recorded when it was extracted
+func (component *KuiComponent) VerifyCache(context Context, version string) (ManifestDiff, error) {
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return ManifestDiff{}, err
+	}
+	cache := cacheLayoutOf(context, pluginDir, version)
+
+	expected, err := readManifest(cache.manifestFile)
+	if err != nil {
+		return ManifestDiff{}, fmt.Errorf("no manifest for Kui base %s; try `kask refresh`: %v", version, err)
+	}
+
+	actual, err := expected.buildLike(cache.extractedDir)
+	if err != nil && !os.IsNotExist(err) {
+		return ManifestDiff{}, err
+	}
+
+	return expected.Diff(actual), nil
+}
+
+func printManifestDiff(out io.Writer, diff ManifestDiff) {
+	for _, path := range diff.Missing {
+		fmt.Fprintf(out, "%v\t%s\n", yellow("missing"), path)
+	}
+	for _, path := range diff.Extra {
+		fmt.Fprintf(out, "%v\t%s\n", yellow("extra"), path)
+	}
+	for _, path := range diff.Changed {
+		fmt.Fprintf(out, "%v\t%s\n", yellow("changed"), path)
+	}
+}
+
+// Verify implements `kask verify [version]`
+func (component *KuiComponent) Verify(context Context, args []string) error {
+	version := component.DistVersion(context)
+	switch len(args) {
+	case 0:
+	case 1:
+		version = args[0]
+	default:
+		return newErrorf(UsageError, "usage: kask verify [version]")
+	}
+
+	diff, err := component.VerifyCache(context, version)
+	if err != nil {
+		return newError(ExtractError, err)
+	}
+
+	if !diff.Empty() {
+		printManifestDiff(os.Stdout, diff)
+		return newErrorf(ExtractError, "the cached Kui base %s does not match its manifest: %s",
+			version, strings.Join([]string{
+				fmt.Sprintf("%d missing", len(diff.Missing)),
+				fmt.Sprintf("%d extra", len(diff.Extra)),
+				fmt.Sprintf("%d changed", len(diff.Changed)),
+			}, ", "))
+	}
+
+	fmt.Printf("Kui base %s verified\n", version)
+	return nil
+}
@@ -0,0 +1,121 @@
+package kui
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mholt/archiver"
+)
+
+func (suite *KaskTestSuite) TestVerifyDetectsTampering() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeZip(
+		fakeEntry{filepath.ToSlash(rootCommandPath(suite.pluginContext, PlatformKey())), fakeKuiScript, 0755},
+		fakeEntry{"Kui-base-linux-x64/resources/app.asar", "app", 0644},
+		fakeEntry{"Kui-base-linux-x64/resources/icon.png", "icon", 0644},
+	))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+
+	diff, err := suite.cmd.VerifyCache(suite.pluginContext, suite.version)
+	suite.Nil(err)
+	suite.True(diff.Empty())
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	resources := filepath.Join(pluginDir, "cache-"+suite.version, "extract", "Kui-base-linux-x64", "resources")
+	suite.Require().Nil(os.Remove(filepath.Join(resources, "app.asar")))
+	suite.Require().Nil(ioutil.WriteFile(filepath.Join(resources, "icon.png"), []byte("tampered"), 0644))
+	suite.Require().Nil(ioutil.WriteFile(filepath.Join(resources, "extra"), []byte{}, 0644))
+
+	diff, err = suite.cmd.VerifyCache(suite.pluginContext, suite.version)
+	suite.Nil(err)
+	suite.Equal([]string{"Kui-base-linux-x64/resources/app.asar"}, diff.Missing)
+	suite.Equal([]string{"Kui-base-linux-x64/resources/icon.png"}, diff.Changed)
+	suite.Equal([]string{"Kui-base-linux-x64/resources/extra"}, diff.Extra)
+
+	err = suite.cmd.Run(*suite.pluginContext, []string{"kask", "verify"})
+	suite.Equal(ExitExtract, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestManifestHashes() {
+	dir, _ := ioutil.TempDir(suite.SaveDir, "manifest")
+	file := filepath.Join(dir, "f")
+	suite.Require().Nil(ioutil.WriteFile(file, []byte("abc"), 0644))
+
+	expected, err := BuildManifest(dir, true)
+	suite.Nil(err)
+	suite.NotEmpty(expected.Entries[0].SHA256)
+
+	// same size, different content
+	suite.Require().Nil(ioutil.WriteFile(file, []byte("xyz"), 0644))
+	actual, err := BuildManifest(dir, true)
+	suite.Nil(err)
+	suite.Equal([]string{"f"}, expected.Diff(actual).Changed)
+}
+
+func (suite *KaskTestSuite) TestManifestRecordsWithHashes() {
+	suite.skipUnlessLinux()
+	dir, _ := ioutil.TempDir(suite.SaveDir, "manifest")
+	file := filepath.Join(dir, "f")
+	suite.Require().Nil(ioutil.WriteFile(file, []byte("abc"), 0644))
+	// sorted first, and with no hash of its own
+	suite.Require().Nil(os.Symlink("f", filepath.Join(dir, "a-link")))
+
+	built, err := BuildManifest(dir, true)
+	suite.Require().Nil(err)
+	manifestFile := filepath.Join(suite.SaveDir, "manifest.json")
+	suite.Require().Nil(writeManifest(manifestFile, built))
+	expected, err := readManifest(manifestFile)
+	suite.Require().Nil(err)
+	suite.True(expected.WithHashes)
+
+	suite.Require().Nil(ioutil.WriteFile(file, []byte("xyz"), 0644))
+	actual, err := expected.buildLike(dir)
+	suite.Nil(err)
+	suite.Equal([]string{"f"}, expected.Diff(actual).Changed, "a symlink first should not hide that there are hashes")
+}
+
+func (suite *KaskTestSuite) TestVerifyWithoutManifest() {
+	defer suite.isolate("")()
+	_, err := suite.cmd.VerifyCache(suite.pluginContext, "0.0.0")
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestIdenticalArchivesHaveIdenticalManifests() {
+	suite.skipUnlessLinux()
+	archive := suite.writeZip(
+		fakeEntry{"base/z", "z", 0644},
+		fakeEntry{"base/a-b", "dash", 0644},
+		fakeEntry{"base/a/b", "slash", 0755},
+		fakeEntry{"base/m/link", "../z", os.ModeSymlink | 0777},
+		fakeEntry{"base/z", "replaced", 0644},
+	)
+
+	// twice in parallel, and then twice one by one
+	var manifests [][]byte
+	for attempt, workers := range []int{4, 4, 1, 1} {
+		destination, _ := ioutil.TempDir(suite.SaveDir, "extract")
+		_, err := unarchive(context.Background(), archiver.DefaultZip, archive, destination, ExtractOptions{Workers: workers})
+		suite.Require().Nil(err)
+
+		manifest, err := BuildManifest(destination, true)
+		suite.Require().Nil(err)
+		file := filepath.Join(suite.SaveDir, fmt.Sprintf("manifest-%d.json", attempt))
+		suite.Require().Nil(writeManifest(file, manifest))
+		bytes, _ := ioutil.ReadFile(file)
+		manifests = append(manifests, bytes)
+	}
+
+	for _, manifest := range manifests[1:] {
+		suite.Equal(string(manifests[0]), string(manifest))
+	}
+	suite.Contains(string(manifests[0]), `"path":"base/a-b"`)
+	suite.True(strings.Index(string(manifests[0]), `"base/a-b"`) < strings.Index(string(manifests[0]), `"base/a/b"`))
+}
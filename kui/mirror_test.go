@@ -0,0 +1,62 @@
+package kui
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// a dist host serving the given archive, but publishing the checksum
+// of the valid dist, counting fetches of the archive
+func serveMirror(archive []byte, valid []byte, gets *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			w.Write([]byte(sha256Hex(valid)))
+			return
+		}
+		if r.Method == "HEAD" {
+			return
+		}
+		*gets++
+		w.Write(archive)
+	}))
+}
+
+func (suite *KaskTestSuite) TestChecksumMismatchTriesNextMirror() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	corruptGets, validGets := 0, 0
+	corrupt := serveMirror(append(dist[:len(dist):len(dist)], "corrupt"...), dist, &corruptGets)
+	defer corrupt.Close()
+	valid := serveMirror(dist, dist, &validGets)
+	defer valid.Close()
+	defer suite.isolate(corrupt.URL)()
+	defer setenv("KASK_DIST_MIRRORS", valid.URL+"/kui-{version}/Kui{suffix}")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Nil(err)
+	suite.Equal(1, corruptGets)
+	suite.Equal(1, validGets)
+
+	status, _ := suite.cmd.CacheStatusOf(suite.pluginContext, "1.2.3")
+	suite.Require().NotNil(status.Provenance)
+	suite.True(strings.HasPrefix(status.Provenance.URL, valid.URL), "the provenance should name the mirror")
+}
+
+func (suite *KaskTestSuite) TestChecksumMismatchOnEveryMirror() {
+	dist := makeFakeDist()
+	corrupted := append(dist[:len(dist):len(dist)], "corrupt"...)
+	gets := 0
+	first := serveMirror(corrupted, dist, &gets)
+	defer first.Close()
+	second := serveMirror(corrupted, dist, &gets)
+	defer second.Close()
+	defer suite.isolate(first.URL)()
+	defer setenv("KASK_DIST_MIRRORS", second.URL+"/Kui{suffix}")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.True(errors.Is(err, ErrChecksumMismatch))
+	suite.Equal(ChecksumError, KindOf(err))
+	suite.Equal(2, gets)
+}
@@ -0,0 +1,105 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// how long we wait for the object store to answer a HEAD
+const headTimeout = 10 * time.Second
+
+// the most of an error body we read, looking for its code
+const maxErrorBodySize = 16 * 1024
+
+// the error document of an S3-compatible object store
+type objectStoreError struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// distObject is what a HEAD tells us of the dist
+type distObject struct {
+	// its ETag, if it has one
+	etag string
+
+	// its size, or -1 if unknown
+	size int64
+}
+
+// checkDistObject asks the object store, with a HEAD, whether it can
+// serve the dist of the given version at url, so that a missing or
+// forbidden dist, or a degraded store, fails fast and says why. A HEAD
+// says only how it failed, so we GET the error document to learn why.
+// If the dist is there, we return what the HEAD told us of it.
+func checkDistObject(ctx stdcontext.Context, context Context, url string, version string) (distObject, error) {
+	ctx, cancel := stdcontext.WithTimeout(ctx, headTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return distObject{size: -1}, err
+	}
+	resp, err := doDistRequest(context, req)
+	if err != nil {
+		return distObject{size: -1}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 300 {
+		size := resp.ContentLength
+		if contentEncoding(resp) != "" {
+			// that of the encoded bytes, not of the dist
+			size = -1
+		}
+		return distObject{resp.Header.Get("ETag"), size}, nil
+	}
+	if resp.StatusCode < 400 || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		// the server cannot tell us this way
+		return distObject{size: -1}, nil
+	}
+	statusErr := &HTTPStatusError{url, resp.StatusCode, resp.Status}
+
+	code := ""
+	if req, err := http.NewRequestWithContext(ctx, "GET", url, nil); err == nil {
+		if resp, err := doDistRequest(context, req); err == nil {
+			code = objectStoreErrorCode(resp.Body)
+			resp.Body.Close()
+		}
+	}
+	return distObject{size: -1}, classifyObjectStoreError(version, code, statusErr)
+}
+
+// objectStoreErrorCode returns the code of the object store error
+// document in body, if there is one
+func objectStoreErrorCode(body io.Reader) string {
+	bytes, err := ioutil.ReadAll(io.LimitReader(body, maxErrorBodySize))
+	if err != nil {
+		return ""
+	}
+	var doc objectStoreError
+	if err := xml.Unmarshal(bytes, &doc); err != nil {
+		return ""
+	}
+	return doc.Code
+}
+
+// classifyObjectStoreError turns a failed request for the dist into
+// guidance. The status error stays wrapped, so that whether we retry
+// still depends on the status.
+func classifyObjectStoreError(version string, code string, statusErr *HTTPStatusError) error {
+	switch {
+	case code == "NoSuchKey" || code == "NoSuchBucket" || (code == "" && statusErr.StatusCode == http.StatusNotFound):
+		return fmt.Errorf("Kui base %s not found on server; check the version, and KUI_DIST or KASK_DIST_URL_TEMPLATE: %w", version, statusErr)
+	case code == "AccessDenied" || (code == "" && statusErr.StatusCode == http.StatusForbidden):
+		return fmt.Errorf("access to Kui base %s denied by server; check that the dist is public, or that your mirror grants access: %w", version, statusErr)
+	case code == "SlowDown" || code == "ServiceUnavailable" || code == "InternalError" || statusErr.StatusCode >= 500:
+		return fmt.Errorf("the server hosting Kui base %s is degraded; try again later: %w", version, statusErr)
+	case code != "":
+		return fmt.Errorf("the server refused Kui base %s (%s): %w", version, code, statusErr)
+	}
+	return statusErr
+}
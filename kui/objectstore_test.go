@@ -0,0 +1,60 @@
+package kui
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// an S3-like store that answers every request for the dist with the
+// given error; as S3 does, a HEAD gets the status, but no body
+func serveObjectStoreError(status int, code string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(status)
+		if r.Method != "HEAD" {
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>` + code + `</Code><Message>oops</Message></Error>`))
+		}
+	}))
+}
+
+func (suite *KaskTestSuite) TestDistNotFoundOnObjectStore() {
+	server := serveObjectStoreError(http.StatusNotFound, "NoSuchKey")
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "9.9.9", false)
+	suite.Require().NotNil(err)
+	suite.Contains(err.Error(), "Kui base 9.9.9 not found on server")
+	suite.Equal(ExitDownload, ExitCode(err))
+	suite.False(retryable(err))
+}
+
+func (suite *KaskTestSuite) TestDistAccessDeniedOnObjectStore() {
+	server := serveObjectStoreError(http.StatusForbidden, "AccessDenied")
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "9.9.9", false)
+	suite.Require().NotNil(err)
+	suite.Contains(err.Error(), "access to Kui base 9.9.9 denied")
+}
+
+func (suite *KaskTestSuite) TestDegradedObjectStoreIsRetryable() {
+	err := classifyObjectStoreError("1.0.0", "SlowDown", &HTTPStatusError{"u", http.StatusServiceUnavailable, "503"})
+	suite.Contains(err.Error(), "degraded")
+	suite.True(retryable(err))
+
+	var statusErr *HTTPStatusError
+	suite.True(errors.As(err, &statusErr))
+}
+
+func (suite *KaskTestSuite) TestObjectStoreErrorCode() {
+	suite.Equal("NoSuchKey", objectStoreErrorCode(strings.NewReader(`<Error><Code>NoSuchKey</Code></Error>`)))
+	suite.Equal("", objectStoreErrorCode(strings.NewReader("not xml")))
+}
@@ -0,0 +1,86 @@
+package kui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the formats in which we can render the output of each command; we
+// render it ourselves, from what Kui tells us, rather than leave it to
+// Kui, which has no contract with us for how to ask it for a format
+var outputFormats = map[string][]string{
+	"list":     {"json", "yaml", "table"},
+	"commands": {"json", "yaml", "table"},
+}
+
+// extractFormatFlag removes any --format <format> or --format=<format>
+// from args, returning the remaining args and the requested format
+func extractFormatFlag(args []string) ([]string, string, error) {
+	var rest []string
+	format := ""
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		switch {
+		case arg == "--format":
+			if idx+1 >= len(args) {
+				return nil, "", fmt.Errorf("--format requires one of json, yaml, or table")
+			}
+			format = args[idx+1]
+			idx++
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, strings.ToLower(format), nil
+}
+
+// checkFormat returns an error unless we can render the output of the
+// given command in the given format
+func checkFormat(command string, format string) error {
+	supported, ok := outputFormats[command]
+	if !ok {
+		commands := make([]string, 0, len(outputFormats))
+		for command := range outputFormats {
+			commands = append(commands, command)
+		}
+		sort.Strings(commands)
+		return fmt.Errorf("--format is not supported by %s; it is supported by %s", command, strings.Join(commands, ", "))
+	}
+
+	for _, candidate := range supported {
+		if candidate == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s does not support --format=%s; use one of %s", command, format, strings.Join(supported, ", "))
+}
+
+// printFormatted renders value as json or yaml, with the field names
+// of its json, or else by the given table
+func printFormatted(out io.Writer, format string, value interface{}, table func() error) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case "yaml":
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		var fields interface{}
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			return err
+		}
+		return yaml.NewEncoder(out).Encode(fields)
+	default:
+		return table()
+	}
+}
@@ -0,0 +1,63 @@
+package kui
+
+import (
+	"io/ioutil"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestCheckFormat() {
+	suite.Nil(checkFormat("list", "json"))
+	suite.Nil(checkFormat("commands", "yaml"))
+	suite.Nil(checkFormat("list", "table"))
+}
+
+func (suite *KaskTestSuite) TestUnsupportedFormat() {
+	err := checkFormat("commands", "xml")
+	suite.Require().NotNil(err)
+	suite.Contains(err.Error(), "use one of json, yaml, table")
+
+	err = checkFormat("version", "json")
+	suite.Require().NotNil(err)
+	suite.Contains(err.Error(), "not supported by version")
+
+	err = suite.cmd.Run(*suite.pluginContext, []string{"kask", "version", "--format=json"})
+	suite.Equal(UsageError, KindOf(err))
+}
+
+func (suite *KaskTestSuite) TestExtractFormatFlag() {
+	rest, format, err := extractFormatFlag([]string{"list", "--format", "JSON", "-a"})
+	suite.Nil(err)
+	suite.Equal([]string{"list", "-a"}, rest)
+	suite.Equal("json", format)
+
+	rest, format, err = extractFormatFlag([]string{"--format=yaml", "commands"})
+	suite.Nil(err)
+	suite.Equal([]string{"commands"}, rest)
+	suite.Equal("yaml", format)
+
+	_, _, err = extractFormatFlag([]string{"list", "--format"})
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestFormatRenderedByKask() {
+	suite.skipUnlessLinux()
+	record := filepath.Join(suite.SaveDir, "fake-kui-env")
+	defer setenv("FAKE_KUI_ENV", record)()
+	defer setenv("FAKE_KUI_LIST", `[{"name": "foo", "version": "1.0.0"}]`)()
+
+	var forwarded []string
+	var err error
+	output := suite.captureStdout(func() {
+		forwarded, err = suite.runFakeKui("list", "--format", "yaml")
+	})
+	suite.Nil(err)
+	suite.Equal([]string{"list"}, forwarded)
+	suite.Contains(output, "name: foo")
+	suite.Contains(output, "version: 1.0.0")
+
+	env, _ := ioutil.ReadFile(record)
+	suite.NotContains(string(env), "KUI_OUTPUT_FORMAT=yaml", "the format is ours to render, not Kui's")
+
+	_, err = suite.runFakeKui("list", "--format", "json", "--", "-a")
+	suite.Equal(UsageError, KindOf(err), "what Kui renders, we cannot format")
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package kui
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByUs returns whether the file was created by our user
+func ownedByUs(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
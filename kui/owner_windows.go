@@ -0,0 +1,9 @@
+package kui
+
+import "os"
+
+// ownedByUs returns whether the file was created by our user; Windows
+// keeps temporary directories per user, so we do not ask
+func ownedByUs(info os.FileInfo) bool {
+	return true
+}
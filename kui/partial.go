@@ -0,0 +1,225 @@
+package kui
+
+import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fetchDist downloads the dist at url to file. The download proceeds
+// through the following states:
+//
+//	fetch     GET into file.part; if an earlier attempt left a .part
+//	          behind, resume it with a Range request, provided the
+//	          server still has the same content (If-Range)
+//	verify    check the complete .part against the expected checksum,
+//	          if there is one; a .part that fails is removed, since
+//	          resuming it could only reproduce the same bad bytes
+//	finalize  rename the .part over file, and record its validators
+//	          in validatorsFile
+//
+// If file exists and the server reports that it is unchanged, per the
+// validators in validatorsFile, nothing is written and updated is
+// false. On any other failure the .part is kept, to be resumed.
+// KASK_DOWNLOADER may hand the fetch to an external tool, in which
+// case the download is always complete, and is still verified.
+func fetchDist(ctx stdcontext.Context, context Context, url string, file string, validatorsFile string, expected string) (updated bool, checksum string, err error) {
+	part := file + ".part"
+
+	tool, argsOf, err := externalDownloader(context)
+	if err != nil {
+		return false, "", err
+	}
+
+	var validators Validators
+	if tool != "" {
+		// an external tool neither resumes nor revalidates for us
+		updated, err = true, fetchExternal(ctx, tool, argsOf(url, part), url, part)
+	} else {
+		updated, validators, err = fetchPart(ctx, context, url, part, file, validatorsFile)
+	}
+	if err != nil || !updated {
+		return updated, "", err
+	}
+
+	checksum, err = sha256File(part)
+	if err != nil {
+		return false, "", newError(ChecksumError, err)
+	}
+	if expected != "" && checksum != expected {
+		removePart(part)
+		return false, "", newError(ChecksumError, fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, url, expected, checksum))
+	}
+
+	if err := os.Rename(part, file); err != nil {
+		return false, "", err
+	}
+	os.Remove(partValidatorsFile(part))
+	return true, checksum, writeValidators(validatorsFile, validators)
+}
+
+// the validators of the content that a .part is a prefix of
+func partValidatorsFile(part string) string {
+	return part + ".json"
+}
+
+func removePart(part string) {
+	os.Remove(part)
+	os.Remove(partValidatorsFile(part))
+}
+
+// ifRange returns the If-Range header that resumes a download of the
+// content described by validators, if there is one; only a strong
+// ETag or a Last-Modified date qualify
+func ifRange(validators Validators) string {
+	if validators.ETag != "" && !strings.HasPrefix(validators.ETag, "W/") {
+		return validators.ETag
+	}
+	return validators.LastModified
+}
+
+// fetchPart is the fetch state of fetchDist; it returns whether part
+// now holds the complete content, and the validators of that content
+func fetchPart(ctx stdcontext.Context, context Context, url string, part string, file string, validatorsFile string) (bool, Validators, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, Validators{}, err
+	}
+
+	var offset int64
+	partValidators := readValidators(partValidatorsFile(part))
+	previous := Validators{}
+	if info, err := os.Stat(part); err == nil && info.Size() > 0 && ifRange(partValidators) != "" {
+		offset = info.Size()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", ifRange(partValidators))
+	} else {
+		removePart(part)
+		if _, err := os.Stat(file); err == nil {
+			previous = readValidators(validatorsFile)
+		}
+		if previous.ETag != "" {
+			req.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			req.Header.Set("If-Modified-Since", previous.LastModified)
+		}
+	}
+
+	resp, err := doDistRequest(context, req)
+	if err != nil {
+		return false, Validators{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && !previous.empty():
+		return false, previous, nil
+
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// perhaps we were interrupted after receiving the last byte
+		if total, ok := rangeTotal(resp.Header.Get("Content-Range")); ok && total == offset {
+			return true, partValidators, nil
+		}
+		removePart(part)
+		return false, Validators{}, &HTTPStatusError{url, resp.StatusCode, resp.Status}
+
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		// a range of encoded bytes does not continue the decoded ones we have
+		if contentEncoding(resp) != "" {
+			removePart(part)
+			return false, Validators{}, fmt.Errorf("unable to resume %s: the server encoded the rest as %s", url, contentEncoding(resp))
+		}
+		if start, ok := rangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			removePart(part)
+			return false, Validators{}, fmt.Errorf("unexpected range %q resuming %s", resp.Header.Get("Content-Range"), url)
+		}
+
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		// a full response, whether or not we asked to resume
+		offset = 0
+
+	default:
+		return false, Validators{}, &HTTPStatusError{url, resp.StatusCode, resp.Status}
+	}
+
+	if err := checkDiskSpace(context, filepath.Dir(part), resp.ContentLength, offset); err != nil {
+		return false, Validators{}, err
+	}
+
+	validators := Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if offset > 0 {
+		validators = partValidators
+	} else if err := writeValidators(partValidatorsFile(part), validators); err != nil {
+		return false, Validators{}, err
+	}
+
+	if err := appendBody(part, offset, resp); err != nil {
+		return false, Validators{}, err
+	}
+	return true, validators, nil
+}
+
+// appendBody writes the body of resp, decoded, to part, starting at offset
+func appendBody(part string, offset int64, resp *http.Response) error {
+	body, encoded, err := decodedBody(resp)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	length := resp.ContentLength
+	if encoded {
+		length = -1
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	out, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(out, readerWithProgress(resp.Request.Context(), body, offset, length))
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if length >= 0 && written != length {
+		return fmt.Errorf("short download of %s: expected %d bytes, got %d", resp.Request.URL, resp.ContentLength, written)
+	}
+	return nil
+}
+
+// rangeStart parses the first byte position of "bytes <start>-<end>/<total>"
+func rangeStart(contentRange string) (int64, bool) {
+	spec := strings.TrimPrefix(contentRange, "bytes ")
+	idx := strings.Index(spec, "-")
+	if spec == contentRange || idx < 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(spec[:idx], 10, 64)
+	return start, err == nil
+}
+
+// rangeTotal parses the total length of "bytes <range>/<total>"
+func rangeTotal(contentRange string) (int64, bool) {
+	idx := strings.LastIndex(contentRange, "/")
+	if !strings.HasPrefix(contentRange, "bytes ") || idx < 0 {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(contentRange[idx+1:], 10, 64)
+	return total, err == nil
+}
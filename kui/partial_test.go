@@ -0,0 +1,132 @@
+package kui
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// a dist host that supports Range and If-Range requests, and counts
+// the bytes of content it sends
+func serveResumable(body *[]byte, etag *string, sent *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", *etag)
+		counter := &countingWriter{ResponseWriter: w, sent: sent}
+		http.ServeContent(counter, r, "Kui.zip", time.Time{}, bytes.NewReader(*body))
+	}))
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	sent *int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	*w.sent += len(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (suite *KaskTestSuite) partialDir() (string, string, string) {
+	dir, err := ioutil.TempDir(suite.SaveDir, "partial")
+	suite.Require().Nil(err)
+	return filepath.Join(dir, "downloaded.zip"), filepath.Join(dir, "validators.json"), filepath.Join(dir, "downloaded.zip.part")
+}
+
+func (suite *KaskTestSuite) TestFetchDistFresh() {
+	body := []byte("the whole of the Kui base")
+	etag, sent := `"v1"`, 0
+	server := serveResumable(&body, &etag, &sent)
+	defer server.Close()
+	file, validatorsFile, part := suite.partialDir()
+
+	updated, checksum, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, validatorsFile, sha256Hex(body))
+	suite.Require().Nil(err)
+	suite.True(updated)
+	suite.Equal(sha256Hex(body), checksum)
+
+	content, _ := ioutil.ReadFile(file)
+	suite.Equal(body, content)
+	suite.Equal(`"v1"`, readValidators(validatorsFile).ETag)
+	_, err = os.Stat(part)
+	suite.True(os.IsNotExist(err), "the .part should have been renamed into place")
+}
+
+func (suite *KaskTestSuite) TestFetchDistResumes() {
+	body := []byte("the whole of the Kui base")
+	etag, sent := `"v1"`, 0
+	server := serveResumable(&body, &etag, &sent)
+	defer server.Close()
+	file, validatorsFile, part := suite.partialDir()
+
+	// an earlier attempt got the first ten bytes
+	suite.Require().Nil(ioutil.WriteFile(part, body[:10], 0644))
+	suite.Require().Nil(writeValidators(partValidatorsFile(part), Validators{ETag: `"v1"`}))
+
+	updated, checksum, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, validatorsFile, sha256Hex(body))
+	suite.Require().Nil(err)
+	suite.True(updated)
+	suite.Equal(sha256Hex(body), checksum)
+	suite.Equal(len(body)-10, sent, "only the remainder should have been fetched")
+
+	content, _ := ioutil.ReadFile(file)
+	suite.Equal(body, content)
+}
+
+func (suite *KaskTestSuite) TestFetchDistRestartsWhenContentChanged() {
+	body := []byte("a newer Kui base, altogether")
+	etag, sent := `"v2"`, 0
+	server := serveResumable(&body, &etag, &sent)
+	defer server.Close()
+	file, validatorsFile, part := suite.partialDir()
+
+	suite.Require().Nil(ioutil.WriteFile(part, []byte("the whole"), 0644))
+	suite.Require().Nil(writeValidators(partValidatorsFile(part), Validators{ETag: `"v1"`}))
+
+	_, _, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, validatorsFile, "")
+	suite.Require().Nil(err)
+	suite.Equal(len(body), sent)
+	content, _ := ioutil.ReadFile(file)
+	suite.Equal(body, content)
+}
+
+func (suite *KaskTestSuite) TestFetchDistChecksumMismatch() {
+	body := []byte("the whole of the Kui base")
+	etag, sent := `"v1"`, 0
+	server := serveResumable(&body, &etag, &sent)
+	defer server.Close()
+	file, validatorsFile, part := suite.partialDir()
+
+	_, _, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, validatorsFile, sha256Hex([]byte("something else")))
+	suite.Equal(ExitDownload, ExitCode(err))
+	suite.Equal(ChecksumError, KindOf(err))
+
+	for _, leftover := range []string{file, part, partValidatorsFile(part)} {
+		_, err = os.Stat(leftover)
+		suite.True(os.IsNotExist(err), "%s should not survive a checksum mismatch", leftover)
+	}
+}
+
+func (suite *KaskTestSuite) TestFetchDistKeepsPartOnInterruption() {
+	// promise more than we send, then hang up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("only ten.."))
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+	file, validatorsFile, part := suite.partialDir()
+
+	_, _, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, validatorsFile, "")
+	suite.NotNil(err)
+	content, _ := ioutil.ReadFile(part)
+	suite.Equal("only ten..", string(content))
+	suite.Equal(`"v1"`, readValidators(partValidatorsFile(part)).ETag)
+}
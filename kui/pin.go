@@ -0,0 +1,100 @@
+package kui
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrDistPinMismatch is returned when the dist host presents a
+// certificate whose public key does not match KASK_DIST_PIN
+var ErrDistPinMismatch = errors.New("the dist host's public key does not match KASK_DIST_PIN")
+
+// the roots that verify the dist host's certificate; nil means the
+// system's. A variable, so that tests may trust their own servers.
+var distRootCAs *x509.CertPool
+
+// clients for the dist host, by pin, so that they may reuse connections
+var pinnedClients = struct {
+	sync.Mutex
+	byPin map[string]*http.Client
+}{byPin: map[string]*http.Client{}}
+
+// distPin returns the SHA-256 digest of the public key (SPKI) that the
+// dist host must present, from the base64 KASK_DIST_PIN; nil if unset
+func distPin(context Context) ([]byte, error) {
+	pin := getenv(context, "KASK_DIST_PIN")
+	if pin == "" {
+		return nil, nil
+	}
+	digest, err := base64.StdEncoding.DecodeString(pin)
+	if err != nil || len(digest) != sha256.Size {
+		return nil, newErrorf(UsageError, "invalid KASK_DIST_PIN %q: expected the base64 SHA-256 digest of a public key", pin)
+	}
+	return digest, nil
+}
+
+// pinnedClient returns a client that, beyond the usual checks of the
+// certificate chain, insists that the leaf certificate carries the
+// pinned public key; this defends against a rogue CA
+func pinnedClient(pin []byte) *http.Client {
+	pinnedClients.Lock()
+	defer pinnedClients.Unlock()
+	if client, ok := pinnedClients.byPin[string(pin)]; ok {
+		return client
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs: distRootCAs,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return ErrDistPinMismatch
+			}
+			digest := sha256.Sum256(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+			if !bytes.Equal(digest[:], pin) {
+				return ErrDistPinMismatch
+			}
+			return nil
+		},
+	}
+	// the pin means nothing for a hop that is not over TLS
+	checkRedirect := func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return ErrDistPinMismatch
+		}
+		return httpClient.CheckRedirect(req, via)
+	}
+	client := &http.Client{Transport: newSocksTransport(transport), CheckRedirect: checkRedirect}
+	pinnedClients.byPin[string(pin)] = client
+	return client
+}
+
+// doDistRequest is doRequest, for a request to the dist host, which is
+// held to KASK_DIST_PIN if that is set, and asked not to encode its
+// response
+func doDistRequest(context Context, req *http.Request) (*http.Response, error) {
+	askForIdentity(req)
+	pin, err := distPin(context)
+	if err != nil {
+		return nil, err
+	}
+	if pin == nil {
+		return doRequest(context, req)
+	}
+	if req.URL.Scheme != "https" {
+		return nil, newErrorf(PolicyError, "KASK_DIST_PIN is set, so refusing to fetch %s other than by https", req.URL)
+	}
+
+	resp, err := doRequestWith(context, pinnedClient(pin), req)
+	if errors.Is(err, ErrDistPinMismatch) {
+		return nil, newError(PolicyError, fmt.Errorf("%w: %s", ErrDistPinMismatch, req.URL.Host))
+	}
+	return resp, err
+}
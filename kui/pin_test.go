@@ -0,0 +1,90 @@
+package kui
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+)
+
+// a dist host over TLS, whose certificate we trust, returning the pin
+// of its public key
+func (suite *KaskTestSuite) serveDistTLS(body []byte) (*httptest.Server, string, func()) {
+	return suite.serveTLS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+}
+
+// serveDistTLS, with the given handler
+func (suite *KaskTestSuite) serveTLS(handler http.Handler) (*httptest.Server, string, func()) {
+	server := httptest.NewTLSServer(handler)
+
+	previous := distRootCAs
+	distRootCAs = x509.NewCertPool()
+	distRootCAs.AddCert(server.Certificate())
+	pinnedClients.byPin = map[string]*http.Client{}
+
+	digest := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	return server, base64.StdEncoding.EncodeToString(digest[:]), func() {
+		server.Close()
+		distRootCAs = previous
+	}
+}
+
+func (suite *KaskTestSuite) TestDistPinMatches() {
+	server, pin, cleanup := suite.serveDistTLS([]byte("content"))
+	defer cleanup()
+	defer setenv("KASK_DIST_PIN", pin)()
+
+	file := filepath.Join(suite.SaveDir, "pinned")
+	updated, _, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, file+".validators", "")
+	suite.Nil(err)
+	suite.True(updated)
+}
+
+func (suite *KaskTestSuite) TestDistPinMismatch() {
+	server, _, cleanup := suite.serveDistTLS([]byte("content"))
+	defer cleanup()
+	other := sha256.Sum256([]byte("some other key"))
+	defer setenv("KASK_DIST_PIN", base64.StdEncoding.EncodeToString(other[:]))()
+
+	file := filepath.Join(suite.SaveDir, "pinned")
+	_, _, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, file+".validators", "")
+	suite.True(errors.Is(err, ErrDistPinMismatch))
+	suite.Equal(ExitPolicy, ExitCode(err))
+	suite.False(retryable(err))
+}
+
+func (suite *KaskTestSuite) TestDistPinRefusesRedirectToHTTP() {
+	plain := serveDist([]byte("content"))
+	defer plain.Close()
+	server, pin, cleanup := suite.serveTLS(http.RedirectHandler(plain.URL, http.StatusFound))
+	defer cleanup()
+	defer setenv("KASK_DIST_PIN", pin)()
+
+	file := filepath.Join(suite.SaveDir, "pinned")
+	_, _, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, file+".validators", "")
+	suite.True(errors.Is(err, ErrDistPinMismatch))
+	suite.Equal(ExitPolicy, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestDistPinRequiresHTTPS() {
+	server := serveDist([]byte("content"))
+	defer server.Close()
+	digest := sha256.Sum256([]byte("key"))
+	defer setenv("KASK_DIST_PIN", base64.StdEncoding.EncodeToString(digest[:]))()
+
+	file := filepath.Join(suite.SaveDir, "pinned")
+	_, _, err := fetchDist(context.Background(), suite.pluginContext, server.URL, file, file+".validators", "")
+	suite.Equal(ExitPolicy, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestInvalidDistPin() {
+	defer setenv("KASK_DIST_PIN", "not a pin")()
+	_, err := distPin(suite.pluginContext)
+	suite.Equal(ExitUsage, ExitCode(err))
+}
@@ -0,0 +1,128 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"time"
+
+	"github.com/kui-shell/kask/i18n"
+)
+
+// how much of the dist we fetch to gauge the dist host's throughput
+const pingBytes = 64 * 1024
+
+// how long we allow a ping
+const pingTimeout = 30 * time.Second
+
+// PingResult describes how quickly the dist host served the start of
+// the dist
+type PingResult struct {
+	URL string `json:"url"`
+
+	// from the start of the request until we had a connection,
+	// including any DNS lookup and TLS handshake
+	Connect time.Duration `json:"connectNanos"`
+
+	// from the start of the request until the first byte of the response
+	FirstByte time.Duration `json:"firstByteNanos"`
+
+	// how much of the dist we fetched, and how long that took
+	Bytes   int64         `json:"bytes"`
+	Elapsed time.Duration `json:"elapsedNanos"`
+
+	// bytes per second, over the whole request
+	Throughput float64 `json:"throughput"`
+}
+
+// PingDist fetches the first few KB of this platform's dist, as a ping
+// of the dist host. It goes as a download would, so that proxies, and
+// KASK_DIST_PIN and KASK_ALLOWED_HOSTS, apply.
+func (component *KuiComponent) PingDist(context Context) (PingResult, error) {
+	location, err := distLocation(context, component.DistVersion(context))
+	if err != nil {
+		return PingResult{}, newError(UsageError, err)
+	}
+	return pingDist(context, location)
+}
+
+func pingDist(context Context, location string) (PingResult, error) {
+	result := PingResult{URL: location}
+
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), pingTimeout)
+	defer cancel()
+
+	var start time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			result.Connect = time.Since(start)
+		},
+		GotFirstResponseByte: func() {
+			result.FirstByte = time.Since(start)
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), "GET", location, nil)
+	if err != nil {
+		return result, newError(UsageError, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", pingBytes-1))
+
+	start = time.Now()
+	resp, err := doDistRequest(context, req)
+	if err != nil {
+		return result, newError(DownloadError, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, newError(DownloadError, &HTTPStatusError{location, resp.StatusCode, resp.Status})
+	}
+
+	// a host that ignores the Range sends it all; we need only the start
+	if result.Bytes, err = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, pingBytes)); err != nil {
+		return result, newError(DownloadError, err)
+	}
+	result.Elapsed = time.Since(start)
+	if result.Elapsed > 0 {
+		result.Throughput = float64(result.Bytes) / result.Elapsed.Seconds()
+	}
+	return result, nil
+}
+
+func printPing(out io.Writer, result PingResult, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	locale := i18n.CurrentLocale()
+	fmt.Fprintf(out, "%s\n", result.URL)
+	fmt.Fprintf(out, "%v\t%v\n", gray("connect"), result.Connect.Round(time.Millisecond))
+	fmt.Fprintf(out, "%v\t%v\n", gray("first byte"), result.FirstByte.Round(time.Millisecond))
+	fmt.Fprintf(out, "%v\t%s (%s in %v)\n", gray("throughput"), FormatRate(locale, result.Bytes, result.Elapsed),
+		FormatBytes(locale, result.Bytes), result.Elapsed.Round(time.Millisecond))
+	return nil
+}
+
+// Ping implements `kask ping [--json]`
+func (component *KuiComponent) Ping(context Context, args []string) error {
+	asJSON := false
+	for _, arg := range args {
+		if arg == "--json" {
+			asJSON = true
+		} else {
+			return newErrorf(UsageError, "usage: kask ping [--json]")
+		}
+	}
+
+	result, err := component.PingDist(context)
+	if err != nil {
+		return err
+	}
+	return printPing(os.Stdout, result, asJSON)
+}
@@ -0,0 +1,44 @@
+package kui
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+func (suite *KaskTestSuite) TestPing() {
+	ranges := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "dist.zip", time.Time{}, bytes.NewReader(make([]byte, 1024*1024)))
+	}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	result, err := suite.cmd.PingDist(suite.pluginContext)
+	suite.Require().Nil(err)
+	suite.Equal(GetDistLocation(suite.pluginContext, suite.version), result.URL)
+	suite.Equal([]string{"bytes=0-65535"}, ranges)
+	suite.Equal(int64(pingBytes), result.Bytes)
+	suite.Greater(int64(result.Connect), int64(0))
+	suite.GreaterOrEqual(int64(result.FirstByte), int64(result.Connect))
+	suite.GreaterOrEqual(int64(result.Elapsed), int64(result.FirstByte))
+	suite.Greater(result.Throughput, 0.0)
+
+	var out bytes.Buffer
+	suite.Nil(printPing(&out, result, true))
+	var decoded PingResult
+	suite.Nil(json.Unmarshal(out.Bytes(), &decoded))
+	suite.Equal(result, decoded)
+}
+
+func (suite *KaskTestSuite) TestPingMissingDist() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.PingDist(suite.pluginContext)
+	suite.Equal(ExitDownload, ExitCode(err))
+}
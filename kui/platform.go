@@ -0,0 +1,113 @@
+package kui
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// computed once; the platform does not change while we run
+var platformKey = makePlatformKey(runtime.GOOS, runtime.GOARCH)
+
+// PlatformKey returns the normalized "os-arch" name of the platform we
+// are running on, e.g. "darwin-arm64". This is the one source of truth
+// for platform naming; the dist suffix and the location of the root
+// command within the dist are both derived from it.
+func PlatformKey() string {
+	return platformKey
+}
+
+func makePlatformKey(goos string, goarch string) string {
+	return strings.ToLower(goos) + "-" + strings.ToLower(goarch)
+}
+
+func splitPlatformKey(key string) (string, string) {
+	idx := strings.Index(key, "-")
+	if idx < 0 {
+		return key, ""
+	}
+	return key[:idx], key[idx+1:]
+}
+
+// nativeArch returns whether to fetch the dist built for our own
+// architecture, if KASK_NATIVE_ARCH is set. Otherwise, we fetch the x64
+// dist, as kask always has: that is the one every release publishes,
+// and e.g. macOS runs it on arm64 just the same.
+func nativeArch(context Context) bool {
+	_, native := lookupEnv(context, "KASK_NATIVE_ARCH")
+	return native
+}
+
+// kuiPlatform maps a platform key to the electron-style platform name
+// used by Kui builds, e.g. "linux-amd64" -> "linux-x64"; the arch is
+// x64 regardless, unless nativeArch
+func kuiPlatform(context Context, key string) string {
+	goos, goarch := splitPlatformKey(key)
+
+	switch goos {
+	case "windows":
+		goos = "win32"
+	case "darwin":
+	default:
+		goos = "linux"
+	}
+
+	if !nativeArch(context) {
+		return goos + "-x64"
+	}
+
+	switch goarch {
+	case "amd64":
+		goarch = "x64"
+	case "386":
+		goarch = "ia32"
+	case "arm":
+		goarch = "armv7l"
+	}
+
+	return goos + "-" + goarch
+}
+
+// distOSSuffix is the suffix of the name of the dist for the given platform
+func distOSSuffix(context Context, key string) string {
+	goos, _ := splitPlatformKey(key)
+	if goos == "darwin" {
+		return "-base-" + kuiPlatform(context, key) + ".tar.bz2"
+	}
+	return "-base-" + kuiPlatform(context, key) + ".zip"
+}
+
+// distSuffix is distOSSuffix, unless KASK_DIST_SUFFIX_OVERRIDE says
+// otherwise; e.g. should the published dists be renamed before kask
+// catches up
+func distSuffix(context Context, key string) string {
+	if suffix := getenv(context, "KASK_DIST_SUFFIX_OVERRIDE"); suffix != "" {
+		return suffix
+	}
+	return distOSSuffix(context, key)
+}
+
+// rootDir is the top-level directory of the extracted dist for the
+// given platform, unless KASK_DIST_ROOT_OVERRIDE says otherwise
+func rootDir(context Context, key string) string {
+	if dir := getenv(context, "KASK_DIST_ROOT_OVERRIDE"); dir != "" {
+		return filepath.FromSlash(dir)
+	}
+	return "Kui-base-" + kuiPlatform(context, key)
+}
+
+// rootCommandPath is the path, relative to the extracted dist, of the
+// Kui executable for the given platform
+func rootCommandPath(context Context, key string) string {
+	dir := rootDir(context, key)
+
+	goos, _ := splitPlatformKey(key)
+	switch goos {
+	case "windows":
+		return filepath.Join(dir, "Kui.exe")
+	case "darwin":
+		return filepath.Join(dir, "Kui.app", "Contents", "MacOS", "Kui")
+	default:
+		return filepath.Join(dir, "Kui")
+	}
+}
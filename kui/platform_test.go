@@ -0,0 +1,78 @@
+package kui
+
+import (
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestPlatformNaming() {
+	cases := []struct {
+		goos, goarch, key, suffix, root string
+	}{
+		{"linux", "amd64", "linux-amd64", "-base-linux-x64.zip", "Kui-base-linux-x64/Kui"},
+		{"linux", "arm64", "linux-arm64", "-base-linux-x64.zip", "Kui-base-linux-x64/Kui"},
+		{"darwin", "amd64", "darwin-amd64", "-base-darwin-x64.tar.bz2", "Kui-base-darwin-x64/Kui.app/Contents/MacOS/Kui"},
+		{"darwin", "arm64", "darwin-arm64", "-base-darwin-x64.tar.bz2", "Kui-base-darwin-x64/Kui.app/Contents/MacOS/Kui"},
+		{"windows", "amd64", "windows-amd64", "-base-win32-x64.zip", "Kui-base-win32-x64/Kui.exe"},
+		{"windows", "386", "windows-386", "-base-win32-x64.zip", "Kui-base-win32-x64/Kui.exe"},
+		{"freebsd", "amd64", "freebsd-amd64", "-base-linux-x64.zip", "Kui-base-linux-x64/Kui"},
+	}
+
+	for _, c := range cases {
+		key := makePlatformKey(c.goos, c.goarch)
+		suite.Equal(c.key, key)
+		suite.Equal(c.suffix, distOSSuffix(suite.pluginContext, key), key)
+		suite.Equal(filepath.FromSlash(c.root), rootCommandPath(suite.pluginContext, key), key)
+	}
+}
+
+func (suite *KaskTestSuite) TestNativeArchPlatformNaming() {
+	defer setenv("KASK_NATIVE_ARCH", "true")()
+	cases := map[string]string{
+		"linux-amd64":   "-base-linux-x64.zip",
+		"linux-arm64":   "-base-linux-arm64.zip",
+		"linux-arm":     "-base-linux-armv7l.zip",
+		"darwin-arm64":  "-base-darwin-arm64.tar.bz2",
+		"windows-386":   "-base-win32-ia32.zip",
+		"windows-amd64": "-base-win32-x64.zip",
+	}
+	for key, suffix := range cases {
+		suite.Equal(suffix, distOSSuffix(suite.pluginContext, key), key)
+	}
+}
+
+func (suite *KaskTestSuite) TestDistSuffixFollowsPlatformKey() {
+	suite.Equal(distOSSuffix(suite.pluginContext, PlatformKey()), GetDistOSSuffix(suite.pluginContext))
+	suite.Equal(filepath.Join("x", rootCommandPath(suite.pluginContext, PlatformKey())), GetRootCommand(suite.pluginContext, "x").Path)
+}
+
+func (suite *KaskTestSuite) TestDistLocationNormalized() {
+	dist := "Kui" + GetDistOSSuffix(suite.pluginContext)
+	cases := map[string]string{
+		"https://example.com/kui-1.0.0":     "https://example.com/kui-1.0.0/" + dist,
+		"https://example.com/kui-1.0.0/":    "https://example.com/kui-1.0.0/" + dist,
+		"https://example.com/kui-1.0.0//":   "https://example.com/kui-1.0.0/" + dist,
+		"https://example.com//a//kui-1.0.0": "https://example.com/a/kui-1.0.0/" + dist,
+		"http://localhost:8080":             "http://localhost:8080/" + dist,
+		"http://localhost:8080/":            "http://localhost:8080/" + dist,
+		"file:///tmp//dist/":                "file:///tmp/dist/" + dist,
+	}
+
+	for host, expected := range cases {
+		restore := setenv("KUI_DIST", host)
+		suite.Equal(expected, GetDistLocation(suite.pluginContext, "1.0.0"), host)
+		restore()
+	}
+}
+
+func (suite *KaskTestSuite) TestDistNamingOverrides() {
+	defer setenv("KASK_DIST_SUFFIX_OVERRIDE", "-renamed-linux.zip")()
+	defer setenv("KASK_DIST_ROOT_OVERRIDE", "Kui-renamed")()
+	defer setenv("KUI_DIST", "https://example.com/kui-1.0.0")()
+
+	suite.Equal("https://example.com/kui-1.0.0/Kui-renamed-linux.zip", GetDistLocation(suite.pluginContext, "1.0.0"))
+	suite.Equal(filepath.Join("x", "Kui-renamed", filepath.Base(rootCommandPath(suite.pluginContext, PlatformKey()))), GetRootCommand(suite.pluginContext, "x").Path)
+
+	location, err := expandDistTemplate(suite.pluginContext, "https://mirror/{version}/Kui{suffix}", "1.0.0", PlatformKey())
+	suite.Nil(err)
+	suite.Equal("https://mirror/1.0.0/Kui-renamed-linux.zip", location)
+}
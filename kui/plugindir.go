@@ -0,0 +1,138 @@
+package kui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// extractPluginDirFlag removes any --plugin-dir <dir> or
+// --plugin-dir=<dir> preceding the first "--" in args, returning the
+// remaining args and the requested directory
+func extractPluginDirFlag(args []string) ([]string, string, error) {
+	rest := []string{}
+	pluginDir := ""
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		switch {
+		case arg == "--":
+			return append(rest, args[idx:]...), pluginDir, nil
+		case arg == "--plugin-dir":
+			if idx+1 >= len(args) {
+				return nil, "", fmt.Errorf("--plugin-dir requires a directory")
+			}
+			pluginDir = args[idx+1]
+			idx++
+		case strings.HasPrefix(arg, "--plugin-dir="):
+			pluginDir = strings.TrimPrefix(arg, "--plugin-dir=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, pluginDir, nil
+}
+
+// validatePluginDir checks that we can keep our cache in the given
+// directory, creating it if need be. It must be absolute, as Kui runs
+// elsewhere than we do.
+func validatePluginDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("invalid plugin directory %s: not an absolute path", dir)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("invalid plugin directory: %v", err)
+	}
+	probe, err := ioutil.TempFile(dir, ".writable-")
+	if err != nil {
+		return fmt.Errorf("invalid plugin directory %s: not writable", dir)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// the unusable home directories that we have warned about
+var warnedOfHome sync.Map
+
+// usableHome returns whether we can keep our cache beneath the given
+// home directory: it must exist, and we must be able to write to it,
+// unless our cache is already there. A misconfigured $HOME otherwise
+// fails confusingly, much later, when we first write to the cache.
+func usableHome(home string) bool {
+	if info, err := os.Stat(filepath.Join(home, ".kask")); err == nil && info.IsDir() {
+		return true
+	}
+	info, err := os.Stat(home)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	probe, err := ioutil.TempFile(home, ".kask-writable-")
+	if err != nil {
+		return false
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return true
+}
+
+// privateDir creates dir, for our use alone, or checks that what is
+// already there is ours alone: a directory, not a symlink, owned by us,
+// and closed to everyone else. A shared path, e.g. beneath /tmp, could
+// otherwise be created first by another user, who could then plant a
+// "Kui" for us to run.
+func privateDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() || !ownedByUs(info) {
+		return fmt.Errorf("%s is not a directory of our own", dir)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0700 {
+		return fmt.Errorf("%s may be accessed by other users (mode %04o)", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// fallbackPluginDirs lists, best first, where we might keep our cache
+// in place of the unusable home directory: the per-user runtime and
+// cache directories, if they lie elsewhere than home, and else one of
+// our own beneath the system's temporary directory
+func fallbackPluginDirs(home string) []string {
+	var candidates []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(runtimeDir) {
+		candidates = append(candidates, filepath.Join(runtimeDir, "kask"))
+	}
+	if cacheDir, err := os.UserCacheDir(); err == nil && filepath.IsAbs(cacheDir) && !within(home, cacheDir) {
+		candidates = append(candidates, filepath.Join(cacheDir, "kask"))
+	}
+
+	name := "kask"
+	if uid := os.Getuid(); uid >= 0 {
+		name = fmt.Sprintf("kask-%d", uid)
+	}
+	return append(candidates, filepath.Join(os.TempDir(), name))
+}
+
+// fallbackPluginDir returns the first of the fallbackPluginDirs that is
+// ours alone, warning (once per home) that we are using it
+func fallbackPluginDir(home string) (string, error) {
+	var refused []string
+	for _, dir := range fallbackPluginDirs(home) {
+		if err := privateDir(dir); err != nil {
+			refused = append(refused, err.Error())
+			continue
+		}
+		if _, warned := warnedOfHome.LoadOrStore(home, true); !warned {
+			fmt.Fprintf(os.Stderr, "%v\n", yellow(fmt.Sprintf("Warning: the home directory %s does not exist or is not writable; keeping the UI code in %s instead. Use --plugin-dir to choose elsewhere.", home, dir)))
+		}
+		return dir, nil
+	}
+	return "", fmt.Errorf("the home directory %s does not exist or is not writable, and there is nowhere safe to keep the UI code instead (%s); use --plugin-dir", home, strings.Join(refused, "; "))
+}
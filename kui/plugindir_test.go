@@ -0,0 +1,106 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestPluginDirFlag() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	pluginDir := filepath.Join(suite.SaveDir, "elsewhere")
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "--plugin-dir", pluginDir, "list"})
+	suite.Require().Nil(err)
+	suite.FileExists(filepath.Join(pluginDir, "cache-"+suite.version, "success"))
+
+	home, _ := suite.pluginContext.PluginDirectory()
+	_, err = os.Stat(filepath.Join(home, "cache-"+suite.version))
+	suite.True(os.IsNotExist(err), "the default plugin directory should be untouched")
+}
+
+func (suite *KaskTestSuite) TestExtractPluginDirFlag() {
+	rest, pluginDir, err := extractPluginDirFlag([]string{"--plugin-dir=/tmp/a", "list", "--", "--plugin-dir", "kui's"})
+	suite.Nil(err)
+	suite.Equal("/tmp/a", pluginDir)
+	suite.Equal([]string{"list", "--", "--plugin-dir", "kui's"}, rest)
+
+	_, _, err = extractPluginDirFlag([]string{"list", "--plugin-dir"})
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestInvalidPluginDir() {
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "--plugin-dir", "relative", "list"})
+	suite.Equal(UsageError, KindOf(err))
+
+	file, _ := ioutil.TempFile(suite.SaveDir, "not-a-dir")
+	file.Close()
+	suite.NotNil(validatePluginDir(file.Name()))
+	suite.Nil(validatePluginDir(filepath.Join(suite.SaveDir, "fresh")))
+}
+
+func (suite *KaskTestSuite) TestNonexistentHome() {
+	tmp, err := ioutil.TempDir(suite.SaveDir, "tmp")
+	suite.Require().Nil(err)
+	defer setenv("TMPDIR", tmp)()
+	defer setenv("XDG_RUNTIME_DIR", "")()
+	defer setenv("XDG_CACHE_HOME", "")()
+	home := filepath.Join(suite.SaveDir, "no-such-home")
+	defer setenv("HOME", home)()
+
+	pluginDir, err := suite.pluginContext.PluginDirectory()
+	suite.Require().Nil(err)
+	suite.True(strings.HasPrefix(pluginDir, tmp+string(filepath.Separator)), pluginDir)
+	suite.Nil(validatePluginDir(pluginDir))
+	_, err = os.Stat(home)
+	suite.True(os.IsNotExist(err), "the missing home should not be created")
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(pluginDir)
+		suite.Require().Nil(err)
+		suite.Equal(os.FileMode(0700), info.Mode().Perm(), "the fallback should be ours alone")
+	}
+}
+
+func (suite *KaskTestSuite) TestNonexistentHomePrefersRuntimeDir() {
+	runtimeDir, err := ioutil.TempDir(suite.SaveDir, "run")
+	suite.Require().Nil(err)
+	defer setenv("XDG_RUNTIME_DIR", runtimeDir)()
+	defer setenv("HOME", filepath.Join(suite.SaveDir, "no-such-home"))()
+
+	pluginDir, err := suite.pluginContext.PluginDirectory()
+	suite.Require().Nil(err)
+	suite.Equal(filepath.Join(runtimeDir, "kask"), pluginDir)
+}
+
+func (suite *KaskTestSuite) TestNonexistentHomeRefusesASharedFallback() {
+	suite.skipUnlessLinux()
+	tmp, err := ioutil.TempDir(suite.SaveDir, "tmp")
+	suite.Require().Nil(err)
+	defer setenv("TMPDIR", tmp)()
+	defer setenv("XDG_RUNTIME_DIR", "")()
+	defer setenv("XDG_CACHE_HOME", "")()
+	defer setenv("HOME", filepath.Join(suite.SaveDir, "no-such-home"))()
+
+	// as if planted by another user, for all to write to
+	planted := fallbackPluginDirs(os.Getenv("HOME"))[0]
+	suite.Require().Nil(os.Mkdir(planted, 0777))
+	suite.Require().Nil(os.Chmod(planted, 0777))
+
+	_, err = suite.pluginContext.PluginDirectory()
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "may be accessed by other users")
+
+	// nor a symlink to a directory that is otherwise ours
+	suite.Require().Nil(os.Remove(planted))
+	ours := filepath.Join(tmp, "ours")
+	suite.Require().Nil(os.Mkdir(ours, 0700))
+	suite.Require().Nil(os.Symlink(ours, planted))
+	_, err = suite.pluginContext.PluginDirectory()
+	suite.NotNil(err)
+}
@@ -0,0 +1,204 @@
+package kui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// Plugin describes a Kui plugin
+type Plugin struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+	Installed   bool   `json:"installed"`
+}
+
+// the name of a plugin, as npm would have it, e.g. @kui-shell/plugin-s3
+var pluginNamePattern = regexp.MustCompile(`^(@[a-z0-9][a-z0-9._~-]*/)?[a-z0-9][a-z0-9._~-]*$`)
+
+// the escape sequences with which a headless Kui may color its output
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// parsePlugins parses the output of a headless Kui list: a table of
+// name, version, and description, perhaps beneath a header and perhaps
+// colored, or else a json array of plugins. Kui lists installed plugins,
+// so they are Installed unless it says otherwise. Output that is
+// neither, e.g. an error, is an error, rather than a list of plugins.
+func parsePlugins(output []byte) ([]Plugin, error) {
+	var listed []struct {
+		Name        string `json:"name"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+		Installed   *bool  `json:"installed"`
+	}
+	if err := json.Unmarshal(output, &listed); err == nil {
+		plugins := []Plugin{}
+		for _, plugin := range listed {
+			installed := plugin.Installed == nil || *plugin.Installed
+			plugins = append(plugins, Plugin{plugin.Name, plugin.Version, plugin.Description, installed})
+		}
+		return plugins, nil
+	}
+
+	plugins := []Plugin{}
+	header := true
+	for _, line := range strings.Split(ansiEscape.ReplaceAllString(string(output), ""), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if header {
+			header = false
+			if strings.EqualFold(fields[0], "name") {
+				continue
+			}
+		}
+		if !pluginNamePattern.MatchString(fields[0]) {
+			return nil, fmt.Errorf("unexpected line in the list of plugins: %s", strings.TrimSpace(line))
+		}
+		plugin := Plugin{Name: fields[0], Installed: true}
+		if len(fields) > 1 {
+			plugin.Version = fields[1]
+		}
+		if len(fields) > 2 {
+			plugin.Description = strings.Join(fields[2:], " ")
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
+
+// listPlugins asks the given Kui, headlessly, which plugins are installed
+func listPlugins(cmd *exec.Cmd) ([]Plugin, error) {
+	list := cloneCommand(cmd)
+	list.Args = append(list.Args, "list")
+	list.Env = append(list.Env, "KUI_HEADLESS=true")
+	var stdout bytes.Buffer
+	list.Stdout = &stdout
+	if err := list.Run(); err != nil {
+		return nil, newError(ChildError, fmt.Errorf("unable to list plugins: %w", err))
+	}
+	plugins, err := parsePlugins(stdout.Bytes())
+	if err != nil {
+		return nil, newError(ChildError, err)
+	}
+	return plugins, nil
+}
+
+// ListPlugins returns the installed plugins, first fetching the Kui
+// base if need be
+func (component *KuiComponent) ListPlugins(context Context) ([]Plugin, error) {
+	cmd, err := component.DownloadDistIfNecessary(context, false)
+	if err != nil {
+		return nil, err
+	}
+	return listPlugins(cmd)
+}
+
+// listFilter is what `kask list --installed-only|--available [--json]`
+// asks for
+type listFilter struct {
+	installedOnly bool
+	available     bool
+	asJSON        bool
+}
+
+// parseListArgs parses the arguments of `kask list`, returning whether
+// they ask for a filtered list; if not, the list is Kui's own. A
+// filtered list takes no other arguments, wherever they appear.
+func parseListArgs(args []string) (listFilter, bool, error) {
+	var filter listFilter
+	var unexpected []string
+	for _, arg := range args {
+		switch arg {
+		case "--installed-only":
+			filter.installedOnly = true
+		case "--available":
+			filter.available = true
+		case "--json":
+			filter.asJSON = true
+		default:
+			unexpected = append(unexpected, arg)
+		}
+	}
+	filtered := filter.installedOnly || filter.available
+	if filtered && len(unexpected) > 0 {
+		return filter, true, fmt.Errorf("unexpected argument to list: %s", unexpected[0])
+	}
+	if filter.installedOnly && filter.available {
+		return filter, true, fmt.Errorf("--installed-only and --available are mutually exclusive")
+	}
+	return filter, filtered, nil
+}
+
+// onlyInstalled asks the given Kui which plugins are installed, and
+// keeps only those it says are
+func onlyInstalled(context Context, cmd *exec.Cmd) ([]Plugin, error) {
+	plugins, err := listPlugins(cmd)
+	if err != nil {
+		return nil, err
+	}
+	installed := []Plugin{}
+	for _, plugin := range plugins {
+		if plugin.Installed {
+			installed = append(installed, plugin)
+		}
+	}
+	return installed, nil
+}
+
+// availablePlugins returns the plugins in the catalog, all of them,
+// that the given Kui does not already have installed
+func availablePlugins(context Context, cmd *exec.Cmd) ([]Plugin, error) {
+	entries, err := allCatalogEntries(context)
+	if err != nil {
+		return nil, newError(DownloadError, err)
+	}
+	installed, err := installedPlugins(cmd)
+	if err != nil {
+		return nil, err
+	}
+	available := []Plugin{}
+	for _, entry := range entries {
+		if !installed[entry.Name] {
+			available = append(available, Plugin{entry.Name, entry.Version, entry.Description, false})
+		}
+	}
+	return available, nil
+}
+
+// filteredList implements `kask list --installed-only|--available`,
+// rendering the list in the given format
+func filteredList(context Context, out io.Writer, cmd *exec.Cmd, filter listFilter, format string) error {
+	list := onlyInstalled
+	if filter.available {
+		list = availablePlugins
+	}
+	plugins, err := list(context, cmd)
+	if err != nil {
+		return err
+	}
+	if filter.asJSON && format == "" {
+		format = "json"
+	}
+	return printPlugins(out, plugins, format)
+}
+
+// printPlugins renders the given plugins in the given format, by
+// default as a table
+func printPlugins(out io.Writer, plugins []Plugin, format string) error {
+	return printFormatted(out, format, plugins, func() error {
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "%v\t%v\t%v\n", blue("NAME"), blue("VERSION"), blue("DESCRIPTION"))
+		for _, plugin := range plugins {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", plugin.Name, plugin.Version, plugin.Description)
+		}
+		return w.Flush()
+	})
+}
@@ -0,0 +1,160 @@
+package kui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestParsePlugins() {
+	// as from a Kui that lists its plugins as json
+	plugins, err := parsePlugins([]byte(`[
+  {"name": "@kui-shell/plugin-s3", "version": "9.1.0", "description": "Browse S3 buckets"},
+  {"name": "plugin-foo", "version": "0.1.0", "installed": false}
+]`))
+	suite.Nil(err)
+	suite.Equal([]Plugin{
+		{"@kui-shell/plugin-s3", "9.1.0", "Browse S3 buckets", true},
+		{"plugin-foo", "0.1.0", "", false},
+	}, plugins)
+
+	// as from a Kui that renders only tables
+	plugins, err = parsePlugins([]byte("NAME        VERSION  DESCRIPTION\nplugin-bar  2.0.0  Bars, in the terminal\nplugin-baz\n\n"))
+	suite.Nil(err)
+	suite.Equal([]Plugin{
+		{"plugin-bar", "2.0.0", "Bars, in the terminal", true},
+		{Name: "plugin-baz", Installed: true},
+	}, plugins)
+
+	// as from a headless Kui, which colors its table
+	plugins, err = parsePlugins([]byte("\x1b[0;34mNAME\x1b[0m  \x1b[0;34mVERSION\x1b[0m\n\x1b[1mplugin-bar\x1b[0m  2.0.0\n"))
+	suite.Nil(err)
+	suite.Equal([]Plugin{{Name: "plugin-bar", Version: "2.0.0", Installed: true}}, plugins)
+
+	plugins, err = parsePlugins([]byte("[]"))
+	suite.Nil(err)
+	suite.Empty(plugins)
+
+	_, err = parsePlugins([]byte("Error: Kui could not start\n    at main.js:1\n"))
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestListPlugins() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("FAKE_KUI_LIST", `[{"name": "foo", "version": "1.0.0", "description": "Foo things"}]`)()
+
+	plugins, err := suite.cmd.ListPlugins(suite.pluginContext)
+	suite.Nil(err)
+	suite.Equal([]Plugin{{"foo", "1.0.0", "Foo things", true}}, plugins)
+
+	output := suite.captureStdout(func() {
+		suite.Nil(suite.cmd.Run(*suite.pluginContext, []string{"kask", "list"}))
+	})
+	suite.Contains(output, "foo  ")
+	suite.Contains(output, "1.0.0")
+	suite.Contains(output, "Foo things")
+}
+
+func (suite *KaskTestSuite) TestListInstalledOnly() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_LIST", `[{"name": "foo", "version": "1.0.0"}, {"name": "bar", "installed": false}]`)()
+
+	var forwarded []string
+	var err error
+	output := suite.captureStdout(func() {
+		forwarded, err = suite.runFakeKui("list", "--installed-only", "--json")
+	})
+	suite.Require().Nil(err)
+	suite.Equal([]string{"list"}, forwarded)
+
+	var plugins []Plugin
+	suite.Require().Nil(json.Unmarshal([]byte(output), &plugins))
+	suite.Equal([]Plugin{{Name: "foo", Version: "1.0.0", Installed: true}}, plugins)
+}
+
+func (suite *KaskTestSuite) TestListAvailable() {
+	suite.skipUnlessLinux()
+	catalog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fakeCatalogResponse))
+	}))
+	defer catalog.Close()
+	defer setenv("KASK_CATALOG", catalog.URL)()
+	defer setenv("FAKE_KUI_LIST", `[{"name": "@kui-shell/plugin-s3", "version": "2.1.0"}]`)()
+
+	var forwarded []string
+	var err error
+	output := suite.captureStdout(func() {
+		forwarded, err = suite.runFakeKui("list", "--available", "--json")
+	})
+	suite.Require().Nil(err)
+	suite.Equal([]string{"list"}, forwarded, "the installed plugins come from a headless list")
+
+	var plugins []Plugin
+	suite.Require().Nil(json.Unmarshal([]byte(output), &plugins))
+	suite.Equal([]Plugin{{"@kui-shell/plugin-kubeui", "1.0.0", "Kubernetes UI", false}}, plugins)
+
+	// and as a table
+	output = suite.captureStdout(func() {
+		_, err = suite.runFakeKui("list", "--available")
+	})
+	suite.Nil(err)
+	suite.Contains(output, "@kui-shell/plugin-kubeui")
+	suite.NotContains(output, "@kui-shell/plugin-s3")
+}
+
+func (suite *KaskTestSuite) TestListAvailablePages() {
+	suite.skipUnlessLinux()
+	names := []string{"plugin-a", "plugin-b", "plugin-c"}
+	var sizes []string
+	catalog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a catalog that returns fewer than asked for, one at a time
+		sizes = append(sizes, r.URL.Query().Get("size"))
+		from, _ := strconv.Atoi(r.URL.Query().Get("from"))
+		objects := []string{}
+		if from < len(names) {
+			objects = append(objects, `{"package": {"name": "`+names[from]+`"}}`)
+		}
+		fmt.Fprintf(w, `{"objects": [%s], "total": %d}`, strings.Join(objects, ","), len(names))
+	}))
+	defer catalog.Close()
+	defer setenv("KASK_CATALOG", catalog.URL)()
+	defer setenv("FAKE_KUI_LIST", `[{"name": "plugin-b"}]`)()
+
+	var err error
+	output := suite.captureStdout(func() {
+		_, err = suite.runFakeKui("list", "--available", "--json")
+	})
+	suite.Require().Nil(err)
+	var plugins []Plugin
+	suite.Require().Nil(json.Unmarshal([]byte(output), &plugins))
+	suite.Equal([]Plugin{{Name: "plugin-a"}, {Name: "plugin-c"}}, plugins)
+	suite.Equal([]string{"250", "250", "250"}, sizes)
+}
+
+func (suite *KaskTestSuite) TestParseListArgs() {
+	_, filtered, err := parseListArgs(nil)
+	suite.Nil(err)
+	suite.False(filtered)
+
+	_, filtered, err = parseListArgs([]string{"--ui"})
+	suite.Nil(err)
+	suite.False(filtered, "anything else is Kui's to interpret")
+
+	filter, filtered, err := parseListArgs([]string{"--json", "--available"})
+	suite.Nil(err)
+	suite.True(filtered)
+	suite.Equal(listFilter{available: true, asJSON: true}, filter)
+
+	_, _, err = parseListArgs([]string{"--installed-only", "--available"})
+	suite.NotNil(err)
+	_, _, err = parseListArgs([]string{"--installed-only", "foo"})
+	suite.NotNil(err)
+	_, _, err = parseListArgs([]string{"foo", "--installed-only"})
+	suite.NotNil(err, "an unexpected argument should be rejected wherever it appears")
+}
@@ -0,0 +1,54 @@
+package kui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// postInstallCommand returns the shell command, from KASK_POST_INSTALL,
+// to run after each fresh install of a Kui base; "" if there is none
+func postInstallCommand(context Context) string {
+	return strings.TrimSpace(getenv(context, "KASK_POST_INSTALL"))
+}
+
+// whether a failure of the post-install command fails the install
+func postInstallRequired(context Context) bool {
+	_, required := lookupEnv(context, "KASK_POST_INSTALL_REQUIRED")
+	return required
+}
+
+// runPostInstall runs the KASK_POST_INSTALL command, if any, after the
+// given version, run by command, has been freshly installed in
+// pluginDir. Its output is logged. A failure is only a warning, unless
+// KASK_POST_INSTALL_REQUIRED is set.
+func runPostInstall(context Context, version string, pluginDir string, command *exec.Cmd) error {
+	hook := postInstallCommand(context)
+	if hook == "" {
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", hook)
+	} else {
+		cmd = exec.Command("sh", "-c", hook)
+	}
+	cmd.Env = append(withConfigEnvironment(context, os.Environ()), "KASK_KUI_VERSION="+version, "KASK_KUI_BIN="+command.Path, "KASK_PLUGIN_DIR="+pluginDir)
+
+	context.logger().Debugf("running post-install command %s", hook)
+	output, err := cmd.CombinedOutput()
+	context.logger().Debugf("post-install command output: %s", output)
+	if err == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("the post-install command %q failed: %v %s", hook, err, strings.TrimSpace(string(output)))
+	if postInstallRequired(context) {
+		return newError(ChildError, err)
+	}
+	fmt.Fprintf(os.Stderr, "%v\n", yellow(fmt.Sprintf("Warning: %v", err)))
+	return nil
+}
@@ -0,0 +1,46 @@
+package kui
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestPostInstallRunsOnlyOnFreshInstall() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	record := filepath.Join(suite.SaveDir, "post-install")
+	defer setenv("KASK_POST_INSTALL", `echo "$KASK_KUI_VERSION" >> `+record)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.True(suite.cmd.result.CacheUsed)
+
+	runs, err := ioutil.ReadFile(record)
+	suite.Require().Nil(err)
+	suite.Equal([]string{"1.2.3"}, strings.Fields(string(runs)), "the hook should run once, for the fresh install")
+}
+
+func (suite *KaskTestSuite) TestPostInstallFailure() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_POST_INSTALL", "exit 3")()
+
+	// by default, only a warning
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Nil(err)
+
+	defer setenv("KASK_POST_INSTALL_REQUIRED", "true")()
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "4.5.6", false)
+	suite.Equal(ExitChild, ExitCode(err))
+	status, err := suite.cmd.CacheStatusOf(suite.pluginContext, "4.5.6")
+	suite.Require().Nil(err)
+	suite.False(status.SuccessExists, "a failed required hook should leave nothing installed")
+}
@@ -0,0 +1,26 @@
+package kui
+
+import (
+	"fmt"
+)
+
+// Prefetch implements `kask prefetch [version]`, which populates the
+// cache without running any Kui command; e.g. to warm the cache of a
+// CI image at build time
+func (component *KuiComponent) Prefetch(context Context, args []string) error {
+	version := component.DistVersion(context)
+	switch len(args) {
+	case 0:
+	case 1:
+		version = args[0]
+	default:
+		return newErrorf(UsageError, "usage: kask prefetch [version]")
+	}
+
+	if _, err := component.DownloadVersionIfNecessary(context, version, false); err != nil {
+		return err
+	}
+
+	fmt.Printf("Kui base %s is cached\n", version)
+	return nil
+}
@@ -0,0 +1,32 @@
+package kui
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestPrefetch() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	record := filepath.Join(suite.SaveDir, "prefetch-args")
+	defer setenv("FAKE_KUI_ARGS", record)()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "prefetch", "1.2.3"})
+	suite.Nil(err)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	cacheDir := filepath.Join(pluginDir, "cache-1.2.3")
+	suite.FileExists(filepath.Join(cacheDir, "success"))
+	suite.FileExists(filepath.Join(cacheDir, "extract", rootCommandPath(suite.pluginContext, PlatformKey())))
+
+	_, err = os.Stat(record)
+	suite.True(os.IsNotExist(err), "prefetch should not spawn Kui")
+}
+
+func (suite *KaskTestSuite) TestPrefetchUsage() {
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "prefetch", "1", "2"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
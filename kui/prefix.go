@@ -0,0 +1,63 @@
+package kui
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// outputPrefix returns what to prefix each line of Kui's output with,
+// from KASK_OUTPUT_PREFIX, e.g. "[kui] "; by default, nothing, so that
+// Kui's output passes through untouched
+func outputPrefix(context Context) string {
+	return getenv(context, "KASK_OUTPUT_PREFIX")
+}
+
+// prefixWriter writes each line written to it to out, preceded by
+// prefix. A line may arrive across several writes; it is held until
+// its end arrives, or until Flush.
+type prefixWriter struct {
+	out     io.Writer
+	prefix  []byte
+	mutex   sync.Mutex
+	pending []byte
+}
+
+func newPrefixWriter(out io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{out: out, prefix: []byte(prefix)}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.pending[:end+1]); err != nil {
+			return len(p), err
+		}
+		w.pending = w.pending[end+1:]
+	}
+}
+
+// Flush writes any final, unterminated line
+func (w *prefixWriter) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+	err := w.writeLine(append(w.pending, '\n'))
+	w.pending = nil
+	return err
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	_, err := w.out.Write(append(append([]byte{}, w.prefix...), line...))
+	return err
+}
@@ -0,0 +1,38 @@
+package kui
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+)
+
+func (suite *KaskTestSuite) TestPrefixWriter() {
+	var out bytes.Buffer
+	w := newPrefixWriter(&out, "[kui] ")
+
+	w.Write([]byte("one\ntw"))
+	suite.Equal("[kui] one\n", out.String(), "a partial line should be held back")
+	w.Write([]byte("o\nthree\n\nfo"))
+	w.Write([]byte("ur"))
+	suite.Nil(w.Flush())
+
+	suite.Equal("[kui] one\n[kui] two\n[kui] three\n[kui] \n[kui] four\n", out.String())
+}
+
+func (suite *KaskTestSuite) TestChildOutputIsPrefixed() {
+	suite.skipUnlessLinux()
+	defer setenv("KASK_OUTPUT_PREFIX", "[kui] ")()
+
+	// capture our stdout, which the fake Kui echoes its arguments to
+	stdout := os.Stdout
+	capture, err := ioutil.TempFile(suite.SaveDir, "stdout")
+	suite.Require().Nil(err)
+	os.Stdout = capture
+	_, err = suite.runFakeKui("list", "--", "a", "b")
+	os.Stdout = stdout
+	capture.Close()
+	suite.Require().Nil(err)
+
+	output, _ := ioutil.ReadFile(capture.Name())
+	suite.Equal("[kui] list a b\n", string(output))
+}
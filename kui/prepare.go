@@ -0,0 +1,41 @@
+package kui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Prepare implements `kask --no-exec`, which ensures that the Kui base
+// is cached and that our kubectl plugin link is in place, without
+// running any Kui command; e.g. in an init container
+func (component *KuiComponent) Prepare(context Context, args []string) error {
+	if len(args) != 0 {
+		return newErrorf(UsageError, "usage: kask --no-exec")
+	}
+
+	cmd, err := component.DownloadDistIfNecessary(context, false)
+	if err != nil {
+		return err
+	}
+
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return fmt.Errorf("unable to locate the plugin directory: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to locate the kask executable: %w", err)
+	}
+
+	// a warm cache skips the link made on extraction, so make it here
+	link, err := linkSelf(filepath.Join(pluginDir, "bin"), executable)
+	if err != nil {
+		return fmt.Errorf("unable to link %s: %w", executable, err)
+	}
+
+	fmt.Printf("Kui base %s is ready: %s\n", component.DistVersion(context), cmd.Path)
+	fmt.Printf("Linked %s to %s\n", link, executable)
+	return nil
+}
@@ -0,0 +1,39 @@
+package kui
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestNoExec() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	record := filepath.Join(suite.SaveDir, "no-exec-args")
+	defer setenv("FAKE_KUI_ARGS", record)()
+
+	// twice, so that the warm cache too gets its link
+	for i := 0; i < 2; i++ {
+		pluginDir, _ := suite.pluginContext.PluginDirectory()
+		os.RemoveAll(filepath.Join(pluginDir, "bin"))
+
+		err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "--no-exec"})
+		suite.Require().Nil(err)
+
+		suite.FileExists(filepath.Join(pluginDir, "cache-"+suite.version, "success"))
+		executable, _ := os.Executable()
+		target, err := os.Readlink(filepath.Join(pluginDir, "bin", "kubectl-"+filepath.Base(executable)))
+		suite.Nil(err)
+		suite.Equal(executable, target)
+	}
+
+	_, err := os.Stat(record)
+	suite.True(os.IsNotExist(err), "--no-exec should not spawn Kui")
+}
+
+func (suite *KaskTestSuite) TestNoExecUsage() {
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "--no-exec", "list"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
@@ -0,0 +1,141 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// how long we wait for the dist host to accept a connection, before
+// concluding that it is unreachable
+const probeTimeout = 5 * time.Second
+
+// how long a successful probe vouches for a dist host, so that
+// back-to-back commands need not each probe it
+const reachableTTL = time.Minute
+
+// hosts may be stubbed by tests
+var probeHost = probeDistHost
+
+// hostPort returns the host:port that a request to the given url
+// would connect to
+func hostPort(rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	if u.Scheme == "http" {
+		return net.JoinHostPort(u.Hostname(), "80"), nil
+	}
+	return net.JoinHostPort(u.Hostname(), "443"), nil
+}
+
+// probeDistHost checks that we can connect to the host serving the
+// given url, the way that our requests would: through the SOCKS5 proxy,
+// if there is one, or else to the HTTP(S) proxy for that url, if there
+// is one. A multi-hundred-megabyte download from a host we cannot reach
+// would otherwise only fail after a long timeout.
+func probeDistHost(context Context, rawurl string) error {
+	if err := checkEgress(context, rawurl); err != nil {
+		return err
+	}
+	address, err := hostPort(rawurl)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("HEAD", rawurl, nil)
+	if err != nil {
+		return err
+	}
+	transports := httpClient.Transport.(*socksTransport)
+	socks, err := socksProxy(context)
+	if err != nil {
+		return err
+	}
+
+	dial := (&net.Dialer{}).DialContext
+	unreachable := "dist host unreachable: " + address
+	if socks != nil {
+		transport, err := transports.through(socks)
+		if err != nil {
+			return err
+		}
+		dial = transport.DialContext
+		unreachable = fmt.Sprintf("dist host unreachable through the proxy %s: %s", socks.Host, address)
+	} else if httpProxy := transports.httpProxy(req); httpProxy != nil {
+		// the proxy is what we would connect to
+		if address, err = hostPort(httpProxy.String()); err != nil {
+			return err
+		}
+		unreachable = "proxy for the dist host unreachable: " + address
+	}
+
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), probeTimeout)
+	defer cancel()
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return errors.New(unreachable)
+	}
+	conn.Close()
+	return nil
+}
+
+// the on-disk memory of the dist hosts we recently found reachable,
+// keyed by host:port
+type reachability map[string]time.Time
+
+func reachabilityFile(pluginDir string) string {
+	return filepath.Join(pluginDir, "reachable.json")
+}
+
+func readReachability(pluginDir string) reachability {
+	seen := reachability{}
+	if bytes, err := ioutil.ReadFile(reachabilityFile(pluginDir)); err == nil {
+		json.Unmarshal(bytes, &seen)
+	}
+	return seen
+}
+
+// probeDistHostCached is probeDistHost, but trusts a successful probe
+// of the same host from the last reachableTTL
+func probeDistHostCached(context Context, pluginDir string, rawurl string) error {
+	address, err := hostPort(rawurl)
+	if err != nil {
+		return err
+	}
+
+	seen := readReachability(pluginDir)
+	if at, ok := seen[address]; ok && time.Since(at) < reachableTTL {
+		// the egress policy may have changed since
+		return checkEgress(context, rawurl)
+	}
+
+	if err := probeHost(context, rawurl); err != nil {
+		return err
+	}
+
+	seen[address] = time.Now()
+	if bytes, err := json.Marshal(seen); err == nil {
+		ioutil.WriteFile(reachabilityFile(pluginDir), bytes, 0644)
+	}
+	return nil
+}
+
+// forgetReachability discards what we remember of reachable hosts;
+// e.g. after a failed download, which the next attempt ought to
+// diagnose afresh
+func forgetReachability(pluginDir string) {
+	os.Remove(reachabilityFile(pluginDir))
+}
@@ -0,0 +1,87 @@
+package kui
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// returns the url of a local port that nothing is listening on
+func unreachableURL() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return "http://" + address
+}
+
+func (suite *KaskTestSuite) TestProbeUnreachableHost() {
+	url := unreachableURL()
+
+	start := time.Now()
+	err := probeDistHost(suite.pluginContext, url)
+	suite.NotNil(err)
+	suite.Equal("dist host unreachable: "+strings.TrimPrefix(url, "http://"), err.Error())
+	suite.True(time.Since(start) < probeTimeout)
+}
+
+func (suite *KaskTestSuite) TestDownloadFromUnreachableHost() {
+	defer suite.isolate(unreachableURL())()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(ExitDownload, ExitCode(err))
+	suite.Contains(err.Error(), "dist host unreachable")
+}
+
+func (suite *KaskTestSuite) TestHostPort() {
+	address, _ := hostPort("https://example.com/kui")
+	suite.Equal("example.com:443", address)
+	address, _ = hostPort("http://example.com/kui")
+	suite.Equal("example.com:80", address)
+	address, _ = hostPort("http://example.com:8080/kui")
+	suite.Equal("example.com:8080", address)
+}
+
+// counts the probes of dist hosts, which all succeed
+func (suite *KaskTestSuite) countProbes(probes *int) func() {
+	previous := probeHost
+	probeHost = func(context Context, rawurl string) error {
+		*probes++
+		return nil
+	}
+	return func() { probeHost = previous }
+}
+
+func (suite *KaskTestSuite) TestReachabilityIsCached() {
+	suite.skipUnlessLinux()
+	probes := 0
+	defer suite.countProbes(&probes)()
+	gets, notModified := 0, 0
+	server := serveWithETag(makeFakeDist(), `"v1"`, &gets, &notModified)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	_, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext, true)
+	suite.Require().Nil(err)
+	suite.Equal(1, notModified, "the refresh should have asked the dist host")
+	suite.Equal(1, probes)
+}
+
+func (suite *KaskTestSuite) TestReachabilityForgottenOnFailure() {
+	probes := 0
+	defer suite.countProbes(&probes)()
+	gets := 0
+	server := serveDistAfterFailures(1, http.StatusNotFound, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	_, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.NotNil(err)
+	_, err = suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Equal(2, probes)
+}
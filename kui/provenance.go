@@ -0,0 +1,51 @@
+package kui
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+)
+
+// Provenance records where a cached Kui base came from
+type Provenance struct {
+	Version   string    `json:"version"`
+	URL       string    `json:"url"`
+	Platform  string    `json:"platform"`
+	SHA256    string    `json:"sha256"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	ETag      string    `json:"etag,omitempty"`
+
+	// the version the extracted Kui reports, if KASK_VERIFY_VERSION
+	// had us ask it
+	KuiVersion string `json:"kuiVersion,omitempty"`
+
+	// the KASK_CHANNEL channel it was fetched from; a cache that
+	// predates channels is of the stable one
+	Channel string `json:"channel,omitempty"`
+}
+
+// channel returns the channel of the cached Kui base
+func (provenance Provenance) channel() string {
+	if provenance.Channel == "" {
+		return ChannelStable
+	}
+	return provenance.Channel
+}
+
+func writeProvenance(file string, provenance Provenance) error {
+	bytes, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, bytes, 0644)
+}
+
+// readProvenance returns the provenance recorded in the given file; a
+// cache that predates provenance yields the zero value
+func readProvenance(file string) Provenance {
+	var provenance Provenance
+	if bytes, err := ioutil.ReadFile(file); err == nil {
+		json.Unmarshal(bytes, &provenance)
+	}
+	return provenance
+}
@@ -0,0 +1,70 @@
+package kui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// linkSelf points bin/kubectl-<basename> at the given kask executable,
+// returning the path of the link. Windows may refuse to create
+// symlinks without elevated privileges, so there we fall back to a copy.
+func linkSelf(binDir string, executable string) (string, error) {
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return "", err
+	}
+
+	link := filepath.Join(binDir, "kubectl-"+filepath.Base(executable))
+	os.Remove(link)
+
+	err := os.Symlink(executable, link)
+	if err != nil && runtime.GOOS == "windows" {
+		err = copyExecutable(executable, link)
+	}
+	return link, err
+}
+
+func copyExecutable(source string, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// Relink implements `kask relink`, which repoints the kubectl-<name>
+// link at the current kask executable; e.g. after kask has been moved
+func (component *KuiComponent) Relink(context Context, args []string) error {
+	if len(args) != 0 {
+		return newErrorf(UsageError, "usage: kask relink")
+	}
+
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return fmt.Errorf("unable to locate the plugin directory: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to locate the kask executable: %w", err)
+	}
+
+	link, err := linkSelf(filepath.Join(pluginDir, "bin"), executable)
+	if err != nil {
+		return fmt.Errorf("unable to link %s: %w", executable, err)
+	}
+
+	fmt.Printf("Linked %s to %s\n", link, executable)
+	return nil
+}
@@ -0,0 +1,47 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestLinkSelfFollowsAMovedBinary() {
+	dir, err := ioutil.TempDir(suite.SaveDir, "relink")
+	suite.Require().Nil(err)
+	binDir := filepath.Join(dir, "bin")
+
+	before := filepath.Join(dir, "old", "kask")
+	suite.Require().Nil(os.MkdirAll(filepath.Dir(before), 0755))
+	suite.Require().Nil(ioutil.WriteFile(before, []byte("#!/bin/sh\n"), 0755))
+
+	link, err := linkSelf(binDir, before)
+	suite.Require().Nil(err)
+	suite.Equal(filepath.Join(binDir, "kubectl-kask"), link)
+
+	// move the binary; the link is now stale until we relink
+	after := filepath.Join(dir, "new", "kask")
+	suite.Require().Nil(os.MkdirAll(filepath.Dir(after), 0755))
+	suite.Require().Nil(os.Rename(before, after))
+	_, err = os.Stat(link)
+	suite.True(os.IsNotExist(err))
+
+	_, err = linkSelf(binDir, after)
+	suite.Require().Nil(err)
+	target, err := os.Readlink(link)
+	suite.Nil(err)
+	suite.Equal(after, target)
+}
+
+func (suite *KaskTestSuite) TestRelink() {
+	defer suite.isolate("http://127.0.0.1:1/")()
+
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "relink"})
+	suite.Require().Nil(err)
+
+	executable, _ := os.Executable()
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	target, err := os.Readlink(filepath.Join(pluginDir, "bin", "kubectl-"+filepath.Base(executable)))
+	suite.Nil(err)
+	suite.Equal(executable, target)
+}
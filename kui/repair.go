@@ -0,0 +1,140 @@
+package kui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// linkedTo returns whether link runs executable; on Windows, where the
+// link may be a copy, that it exists is the best we can tell
+func linkedTo(link string, executable string) bool {
+	resolved, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	self, err := filepath.EvalSymlinks(executable)
+	return err == nil && resolved == self
+}
+
+// executableFile returns whether path is a file we may execute
+func executableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && (runtime.GOOS == "windows" || info.Mode()&0111 != 0)
+}
+
+// Repair implements `kask repair [version]`, which fixes what commonly
+// breaks once the Kui base is installed: the kubectl plugin link, a
+// cached archive that no longer matches its checksum, and an extract
+// missing its executable, or whose executable is no longer executable.
+// Each fix is reported as it is made; only a fix that fails is an error.
+func (component *KuiComponent) Repair(context Context, args []string) error {
+	version := component.DistVersion(context)
+	switch len(args) {
+	case 0:
+	case 1:
+		version = args[0]
+	default:
+		return newErrorf(UsageError, "usage: kask repair [version]")
+	}
+
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return fmt.Errorf("unable to locate the plugin directory: %w", err)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to locate the kask executable: %w", err)
+	}
+
+	repaired := 0
+	binDir := filepath.Join(pluginDir, "bin")
+	if !linkedTo(filepath.Join(binDir, "kubectl-"+filepath.Base(executable)), executable) {
+		link, err := linkSelf(binDir, executable)
+		if err != nil {
+			return fmt.Errorf("unable to link %s: %w", executable, err)
+		}
+		fmt.Printf("Linked %s to %s\n", link, executable)
+		repaired++
+	}
+
+	// we run the system-wide cache's, if it has this version, and that
+	// is not ours to repair
+	options, err := GetExtractOptions(context)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	if systemDir, ok := systemExtract(context, version, options); ok {
+		fmt.Printf("Kui base %s is that of the system-wide cache, in %s, which kask does not repair\n", version, systemDir)
+		return nil
+	}
+
+	cache := cacheLayoutOf(context, pluginDir, version)
+	if _, err := os.Stat(cache.successFile); err != nil {
+		fmt.Printf("Kui base %s is not installed; it will be, when next needed\n", version)
+		return nil
+	}
+
+	// an archive that fails its checksum would fail any re-extraction
+	archiveIntact := true
+	if expected := readProvenance(cache.provenanceFile).SHA256; expected != "" {
+		if actual, err := sha256File(cache.downloadedFile); err == nil && actual != expected {
+			os.Remove(cache.downloadedFile)
+			os.Remove(cache.validatorsFile)
+			fmt.Printf("Removed the archive of Kui base %s, which no longer matches its checksum\n", version)
+			repaired++
+			archiveIntact = false
+		}
+	}
+	if _, err := os.Stat(cache.downloadedFile); err != nil {
+		archiveIntact = false
+	}
+
+	binary := GetRootCommand(context, cache.extractedDir).Path
+	if _, err := os.Stat(binary); err != nil {
+		if archiveIntact {
+			// extract afresh from the archive we have, as KASK_ALWAYS_EXTRACT does
+			_, err = component.DownloadVersionIfNecessary(withSetting(context, "KASK_ALWAYS_EXTRACT", "true"), version, false)
+			if err != nil {
+				return err
+			}
+			// offline, or with an archive that fails its checksum, the
+			// extract is kept as it is, still missing its executable
+			if _, err := os.Stat(binary); err != nil {
+				if offlineMode(context) {
+					return newErrorf(OfflineMissError, "Kui base %s is missing %s, which offline mode forbids us to re-extract", version, binary)
+				}
+				return newErrorf(ExtractError, "unable to re-extract %s of Kui base %s", binary, version)
+			}
+			fmt.Printf("Re-extracted Kui base %s\n", version)
+		} else {
+			// with nothing to extract from, start over
+			os.Remove(cache.successFile)
+			if err := component.discardPartialInstall(context, version); err != nil {
+				return err
+			}
+			if _, err := component.DownloadVersionIfNecessary(context, version, false); err != nil {
+				return err
+			}
+			fmt.Printf("Reinstalled Kui base %s\n", version)
+		}
+		repaired++
+	}
+
+	if !executableFile(binary) {
+		if err := MakeExecutable(binary); err != nil || !executableFile(binary) {
+			return newErrorf(ExtractError, "unable to make %s executable: %v", binary, err)
+		}
+		fmt.Printf("Made %s executable\n", binary)
+		repaired++
+	}
+
+	if repaired == 0 {
+		fmt.Printf("Kui base %s needs no repair\n", version)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// installs the fake Kui base from a dist host that counts its fetches,
+// returning the cache and the path of the Kui executable
+func (suite *KaskTestSuite) installForRepair(gets *int) (cacheLayout, string, func()) {
+	notModified := 0
+	server := serveWithETag(makeFakeDist(), `"v1"`, gets, &notModified)
+	restore := suite.isolate(server.URL)
+	cmd, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	return cacheLayoutOf(suite.pluginContext, pluginDir, suite.version), cmd.Path, func() {
+		restore()
+		server.Close()
+	}
+}
+
+func (suite *KaskTestSuite) repair() (string, error) {
+	var err error
+	output := suite.captureStdout(func() {
+		err = suite.cmd.Run(*suite.pluginContext, []string{"kask", "repair"})
+	})
+	return output, err
+}
+
+func (suite *KaskTestSuite) TestRepairOfHealthyInstall() {
+	suite.skipUnlessLinux()
+	gets := 0
+	_, _, cleanup := suite.installForRepair(&gets)
+	defer cleanup()
+
+	output, err := suite.repair()
+	suite.Nil(err)
+	suite.Contains(output, "needs no repair")
+}
+
+func (suite *KaskTestSuite) TestRepairRelinks() {
+	suite.skipUnlessLinux()
+	gets := 0
+	_, _, cleanup := suite.installForRepair(&gets)
+	defer cleanup()
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	binDir := filepath.Join(pluginDir, "bin")
+	suite.Require().Nil(os.RemoveAll(binDir))
+
+	output, err := suite.repair()
+	suite.Nil(err)
+	suite.Contains(output, "Linked")
+	executable, _ := os.Executable()
+	suite.True(linkedTo(filepath.Join(binDir, "kubectl-"+filepath.Base(executable)), executable))
+}
+
+func (suite *KaskTestSuite) TestRepairReExtractsAMissingBinary() {
+	suite.skipUnlessLinux()
+	gets := 0
+	_, binary, cleanup := suite.installForRepair(&gets)
+	defer cleanup()
+	suite.Require().Nil(os.Remove(binary))
+
+	output, err := suite.repair()
+	suite.Nil(err)
+	suite.Contains(output, "Re-extracted Kui base "+suite.version)
+	suite.True(executableFile(binary))
+	suite.Equal(1, gets, "the archive we have should be reused")
+}
+
+func (suite *KaskTestSuite) TestRepairMakesTheBinaryExecutable() {
+	suite.skipUnlessLinux()
+	gets := 0
+	_, binary, cleanup := suite.installForRepair(&gets)
+	defer cleanup()
+	suite.Require().Nil(os.Chmod(binary, 0644))
+
+	output, err := suite.repair()
+	suite.Nil(err)
+	suite.Contains(output, "Made "+binary+" executable")
+	suite.True(executableFile(binary))
+}
+
+func (suite *KaskTestSuite) TestRepairReplacesACorruptArchive() {
+	suite.skipUnlessLinux()
+	gets := 0
+	cache, binary, cleanup := suite.installForRepair(&gets)
+	defer cleanup()
+	suite.Require().Nil(ioutil.WriteFile(cache.downloadedFile, []byte("corrupt"), 0644))
+	suite.Require().Nil(os.Remove(binary))
+
+	output, err := suite.repair()
+	suite.Nil(err)
+	suite.Contains(output, "no longer matches its checksum")
+	suite.Contains(output, "Reinstalled Kui base "+suite.version)
+	suite.True(executableFile(binary))
+	suite.Equal(2, gets, "with nothing to extract from, the archive should be fetched again")
+}
+
+func (suite *KaskTestSuite) TestRepairThatCannotFix() {
+	suite.skipUnlessLinux()
+	gets := 0
+	cache, binary, cleanup := suite.installForRepair(&gets)
+	defer cleanup()
+	suite.Require().Nil(os.Remove(cache.downloadedFile))
+	suite.Require().Nil(os.Remove(binary))
+
+	defer setenv("KASK_OFFLINE", "true")()
+	_, err := suite.repair()
+	suite.Equal(ExitOfflineMiss, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestRepairOfflineCannotReExtract() {
+	suite.skipUnlessLinux()
+	gets := 0
+	_, binary, cleanup := suite.installForRepair(&gets)
+	defer cleanup()
+	suite.Require().Nil(os.Remove(binary))
+
+	defer setenv("KASK_OFFLINE", "true")()
+	output, err := suite.repair()
+	suite.Equal(ExitOfflineMiss, ExitCode(err))
+	suite.NotContains(output, "Re-extracted")
+}
@@ -0,0 +1,40 @@
+package kui
+
+import "os"
+
+// Result describes what an Execute did
+type Result struct {
+	// the status that kask, as a command, would exit with
+	ExitCode int `json:"exitCode"`
+
+	// the Kui command line we ran, if we got that far
+	Command []string `json:"command,omitempty"`
+
+	// whether we found the Kui base already cached
+	CacheUsed bool `json:"cacheUsed"`
+
+	// how many bytes of the Kui base we downloaded
+	BytesDownloaded int64 `json:"bytesDownloaded"`
+
+	// whether --json asked that failures be reported as json; it is in
+	// effect only for the run itself, and reporting comes after
+	reportJSON bool
+}
+
+func (result *Result) recordDownload(file string) {
+	if info, err := os.Stat(file); err == nil {
+		result.BytesDownloaded += info.Size()
+	}
+}
+
+// Execute runs kask with the given command line, as its main would,
+// e.g. []string{"kask", "list"}; but, rather than exiting, it returns
+// what happened. Failures are returned, not logged; see report.
+func (context MainContext) Execute(args []string) (Result, error) {
+	component := KuiComponent{}
+	err := component.Run(context, args)
+	result := component.result
+	result.ExitCode = ExitCode(err)
+	result.reportJSON = component.globals.json
+	return result, err
+}
@@ -0,0 +1,42 @@
+package kui
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+func (suite *KaskTestSuite) TestExecute() {
+	suite.skipUnlessLinux()
+	dist := makeFakeDist()
+	server := serveDist(dist)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	result, err := suite.pluginContext.Execute([]string{"kask", "list", "--", "-a"})
+	suite.Require().Nil(err)
+	suite.Equal(ExitOK, result.ExitCode)
+	suite.False(result.CacheUsed)
+	suite.Equal(int64(len(dist)), result.BytesDownloaded)
+	suite.Require().NotEmpty(result.Command)
+	suite.Equal([]string{"list", "-a"}, result.Command[1:])
+
+	result, err = suite.pluginContext.Execute([]string{"kask", "list"})
+	suite.Require().Nil(err)
+	suite.True(result.CacheUsed)
+	suite.Zero(result.BytesDownloaded)
+}
+
+func (suite *KaskTestSuite) TestExecuteFailure() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	result, err := suite.pluginContext.Execute([]string{"kask", "list"})
+	suite.NotNil(err)
+	suite.Equal(ExitDownload, result.ExitCode)
+	suite.Empty(result.Command)
+
+	result, err = suite.pluginContext.Execute([]string{"kask"})
+	suite.Equal(UsageError, KindOf(err))
+	suite.Equal(ExitUsage, result.ExitCode)
+}
@@ -0,0 +1,122 @@
+package kui
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/kui-shell/kask/retry"
+)
+
+// the pause before the first retry of an install; each subsequent
+// retry waits twice as long as the last
+var installRetryDelay = time.Second
+
+// installRetries returns how many times we retry a failed install of
+// the Kui base, from KASK_INSTALL_RETRIES; by default, we do not
+func installRetries(context Context) (int, error) {
+	value, isSet := lookupEnv(context, "KASK_INSTALL_RETRIES")
+	if !isSet {
+		return 0, nil
+	}
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		return 0, fmt.Errorf("invalid KASK_INSTALL_RETRIES %q", value)
+	}
+	return retries, nil
+}
+
+// installRetryPolicy is how we retry what needs the network: the
+// install of the Kui base, and reads of the latest pointer and of the
+// releases of kask
+func installRetryPolicy(retries int) retry.Policy {
+	return retry.Policy{
+		Attempts:  retries + 1,
+		Delay:     installRetryDelay,
+		MaxDelay:  30 * time.Second,
+		Jitter:    0.2,
+		Retryable: retryable,
+	}
+}
+
+// withRetries calls fn, retrying it as KASK_INSTALL_RETRIES allows
+func withRetries(ctx stdcontext.Context, context Context, fn func() error) error {
+	retries, err := installRetries(context)
+	if err != nil {
+		return err
+	}
+	return retry.Do(ctx, installRetryPolicy(retries), fn)
+}
+
+// retryable returns whether an install that failed with err might
+// succeed if tried again. Misconfiguration, policy, and integrity
+// failures will not go away by themselves; network trouble and
+// truncated or half-written files may.
+func retryable(err error) bool {
+	switch KindOf(err) {
+	case UsageError, PolicyError, OfflineMissError, ChecksumError:
+		return false
+	}
+	if errors.Is(err, ErrUnsafeArchivePath) || errors.Is(err, ErrInsufficientDiskSpace) || errors.Is(err, stdcontext.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// discardPartialInstall removes what a failed install of the given
+// version left behind, so that the next attempt starts afresh; except
+// for a partial download, which the next attempt resumes, and, with
+// KASK_ALWAYS_EXTRACT, for the archive that failed to re-extract, if it
+// is still the one we verified. A complete cache, i.e. one that a
+// failed refresh did not get as far as touching, is left alone.
+func (p *KuiComponent) discardPartialInstall(context Context, version string) error {
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return fmt.Errorf("unable to locate the plugin directory: %w", err)
+	}
+	cache := cacheLayoutOf(context, pluginDir, version)
+	if _, err := os.Stat(cache.successFile); err == nil {
+		return nil
+	}
+
+	part := cache.downloadedFile + ".part"
+	kept := map[string]bool{part: true, partValidatorsFile(part): true}
+	if alwaysExtract(context) {
+		recorded := readProvenance(cache.provenanceFile).SHA256
+		if actual, err := sha256File(cache.downloadedFile); err == nil && recorded != "" && actual == recorded {
+			kept[cache.downloadedFile] = true
+			kept[cache.validatorsFile] = true
+			kept[cache.provenanceFile] = true
+		}
+	}
+
+	// the state of an install that failed describes nothing
+	if cache.stateDir != cache.dir && !kept[cache.provenanceFile] {
+		os.Remove(cache.provenanceFile)
+	}
+
+	entries, err := ioutil.ReadDir(cache.dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		path := filepath.Join(cache.dir, entry.Name())
+		if !kept[path] {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
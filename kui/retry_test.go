@@ -0,0 +1,197 @@
+package kui
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// a dist host that answers the first few fetches of the dist with
+// the given status, and thereafter serves it
+func serveDistAfterFailures(failures int, status int, gets *int) *httptest.Server {
+	dist := makeFakeDist()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "HEAD" {
+			// the store looks fine; only fetches are counted
+			return
+		}
+		*gets++
+		if *gets <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(dist)
+	}))
+}
+
+func (suite *KaskTestSuite) withoutRetryDelay() func() {
+	previous := installRetryDelay
+	installRetryDelay = 0
+	return func() { installRetryDelay = previous }
+}
+
+func (suite *KaskTestSuite) TestInstallRetriesTransientFailure() {
+	suite.skipUnlessLinux()
+	defer suite.withoutRetryDelay()()
+	gets := 0
+	server := serveDistAfterFailures(1, http.StatusServiceUnavailable, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_INSTALL_RETRIES", "2")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Nil(err)
+	suite.Equal(2, gets)
+}
+
+func (suite *KaskTestSuite) TestInstallDoesNotRetryPermanentFailure() {
+	defer suite.withoutRetryDelay()()
+	gets := 0
+	server := serveDistAfterFailures(1, http.StatusNotFound, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_INSTALL_RETRIES", "2")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Equal(ExitDownload, ExitCode(err))
+	suite.Equal(1, gets)
+}
+
+func (suite *KaskTestSuite) TestInstallRetriesAreBounded() {
+	defer suite.withoutRetryDelay()()
+	gets := 0
+	server := serveDistAfterFailures(10, http.StatusBadGateway, &gets)
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_INSTALL_RETRIES", "2")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.NotNil(err)
+	suite.Equal(3, gets)
+}
+
+func (suite *KaskTestSuite) TestRetryable() {
+	suite.True(retryable(errors.New("connection reset by peer")))
+	suite.True(retryable(newError(DownloadError, &HTTPStatusError{"u", 503, "503 Service Unavailable"})))
+	suite.False(retryable(newError(DownloadError, &HTTPStatusError{"u", 404, "404 Not Found"})))
+	suite.False(retryable(newErrorf(ChecksumError, "checksum mismatch")))
+	suite.False(retryable(newError(ExtractError, ErrUnsafeArchivePath)))
+}
+
+// a dist whose first entry extracts, but whose second is corrupt, so
+// that its extraction fails halfway
+func makeHalfCorruptDist() []byte {
+	dist := makeZip(
+		fakeEntry{"Kui-base-linux-x64/stale", "from the failed attempt", 0644},
+		fakeEntry{filepath.ToSlash(rootCommandPath(nil, PlatformKey())), fakeKuiScript, 0755},
+	)
+	r, err := zip.NewReader(bytes.NewReader(dist), int64(len(dist)))
+	if err != nil {
+		panic(err)
+	}
+	offset, err := r.File[1].DataOffset()
+	if err != nil {
+		panic(err)
+	}
+	dist[offset+2] ^= 0xff
+	return dist
+}
+
+func (suite *KaskTestSuite) TestRetriedExtractionStartsClean() {
+	suite.skipUnlessLinux()
+	defer suite.withoutRetryDelay()()
+	gets := 0
+	corrupt, good := makeHalfCorruptDist(), makeFakeDist()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "HEAD" {
+			return
+		}
+		gets++
+		if gets == 1 {
+			w.Write(corrupt)
+			return
+		}
+		w.Write(good)
+	}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_INSTALL_RETRIES", "1")()
+
+	cmd, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.Equal(2, gets, "the first extraction should have failed")
+	suite.FileExists(cmd.Path)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	extractedDir := filepath.Join(pluginDir, "cache-1.2.3", "extract")
+	_, err = os.Stat(filepath.Join(extractedDir, "Kui-base-linux-x64", "stale"))
+	suite.True(os.IsNotExist(err), "the retry should not inherit the failed attempt's files")
+
+	// nor should the failed attempt's blob linger
+	blobs, err := ioutil.ReadDir(filepath.Join(pluginDir, "cache", "blobs"))
+	suite.Require().Nil(err)
+	suite.Len(blobs, 1)
+}
+
+func (suite *KaskTestSuite) TestRetriedExtractionStartsCleanWithoutBlobs() {
+	suite.skipUnlessLinux()
+	defer suite.withoutRetryDelay()()
+	previous := contentAddressed
+	contentAddressed = func() bool { return false }
+	defer func() { contentAddressed = previous }()
+
+	gets := 0
+	corrupt, good := makeHalfCorruptDist(), makeFakeDist()
+	var extractedDir string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "HEAD" {
+			return
+		}
+		gets++
+		if gets == 1 {
+			w.Write(corrupt)
+			return
+		}
+		// what a failed attempt might leave, had the retry not swept it
+		// up, in the very extract that the retry extracts into
+		os.MkdirAll(filepath.Join(extractedDir, "Kui-base-linux-x64"), 0700)
+		ioutil.WriteFile(filepath.Join(extractedDir, "Kui-base-linux-x64", "stale"), []byte("stale"), 0644)
+		w.Write(good)
+	}))
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	defer setenv("KASK_INSTALL_RETRIES", "1")()
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	extractedDir = filepath.Join(pluginDir, "cache-1.2.3", "extract")
+
+	cmd, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.Equal(2, gets, "the first extraction should have failed")
+	suite.FileExists(cmd.Path)
+
+	info, err := os.Lstat(extractedDir)
+	suite.Require().Nil(err)
+	suite.True(info.IsDir(), "without blobs, the extract should be a directory of its own")
+	_, err = os.Stat(filepath.Join(extractedDir, "Kui-base-linux-x64", "stale"))
+	suite.True(os.IsNotExist(err), "the retry should not inherit the failed attempt's files")
+	_, err = os.Stat(filepath.Join(pluginDir, "cache", "blobs"))
+	suite.True(os.IsNotExist(err), "without blobs, there should be none")
+}
@@ -0,0 +1,57 @@
+package kui
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestPassthroughArgs() {
+	suite.skipUnlessLinux()
+	forwarded, err := suite.runFakeKui("install", "foo", "--", "--ui", "--theme=dark", "--")
+	suite.Nil(err)
+	suite.Equal([]string{"install", "foo", "--ui", "--theme=dark", "--"}, forwarded)
+}
+
+func (suite *KaskTestSuite) TestNoCommandBeforePassthrough() {
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "--", "list"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestSplitPassthrough() {
+	args := []string{"list", "--", "a", "b"}
+	own, passthrough := splitPassthrough(args)
+	suite.Equal([]string{"list"}, own)
+	suite.Equal([]string{"a", "b"}, passthrough)
+
+	// appending to our half must not clobber the caller's args
+	_ = append(own, "x")
+	suite.Equal("--", args[1])
+
+	own, passthrough = splitPassthrough([]string{"list"})
+	suite.Equal([]string{"list"}, own)
+	suite.Nil(passthrough)
+}
+
+func (suite *KaskTestSuite) TestRefreshOfColdCacheInstalls() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	record := filepath.Join(suite.SaveDir, "fake-kui-args")
+	os.Remove(record)
+	defer setenv("FAKE_KUI_ARGS", record)()
+
+	var err error
+	output := suite.captureStdout(func() {
+		err = suite.cmd.Run(*suite.pluginContext, []string{"kask", "refresh"})
+	})
+	suite.Require().Nil(err)
+	suite.Equal("Installed Kui base "+suite.version+"\n", output)
+
+	_, err = os.Stat(record)
+	suite.True(os.IsNotExist(err), "Kui should not be run to report on a fresh install")
+
+	status, err := suite.cmd.CacheStatus(suite.pluginContext)
+	suite.Nil(err)
+	suite.True(status.SuccessExists)
+}
@@ -0,0 +1,153 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Kui plugins are published to npm, tagged with this keyword
+const pluginKeyword = "kui-plugin"
+
+const defaultCatalog = "https://registry.npmjs.org/-/v1/search"
+
+// CatalogEntry describes a plugin available for install
+type CatalogEntry struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+// the subset of the npm search response that we care about
+type catalogResponse struct {
+	Objects []struct {
+		Package CatalogEntry `json:"package"`
+	} `json:"objects"`
+	Total int `json:"total"`
+}
+
+// the most results npm returns in one page of a search
+const catalogPageSize = 250
+
+func GetCatalogLocation(context Context) string {
+	if catalog, overrideSet := lookupEnv(context, "KASK_CATALOG"); overrideSet {
+		return catalog
+	}
+	return defaultCatalog
+}
+
+// SearchCatalog returns the plugins in the catalog that match the given
+// query; as many as the catalog returns by default, i.e. the best
+// matches
+func SearchCatalog(context Context, query string) ([]CatalogEntry, error) {
+	response, err := searchCatalog(context, query, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	return response.entries(), nil
+}
+
+// allCatalogEntries returns every plugin in the catalog, a page at a
+// time
+func allCatalogEntries(context Context) ([]CatalogEntry, error) {
+	entries := []CatalogEntry{}
+	for {
+		page := url.Values{}
+		page.Set("size", strconv.Itoa(catalogPageSize))
+		page.Set("from", strconv.Itoa(len(entries)))
+		response, err := searchCatalog(context, "", page)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, response.entries()...)
+		if len(response.Objects) == 0 || len(entries) >= response.Total {
+			return entries, nil
+		}
+	}
+}
+
+// searchCatalog fetches one page of the results of the given query
+func searchCatalog(context Context, query string, params url.Values) (catalogResponse, error) {
+	text := "keywords:" + pluginKeyword
+	if query != "" {
+		text += " " + query
+	}
+	params.Set("text", text)
+
+	location := GetCatalogLocation(context) + "?" + params.Encode()
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), distLatestTimeout)
+	defer cancel()
+
+	var response catalogResponse
+	err := withRetries(ctx, context, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := doRequest(context, req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return &HTTPStatusError{location, resp.StatusCode, resp.Status}
+		}
+		response = catalogResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return fmt.Errorf("unable to parse the plugin catalog response: %v", err)
+		}
+		return nil
+	})
+	return response, err
+}
+
+func (response catalogResponse) entries() []CatalogEntry {
+	entries := []CatalogEntry{}
+	for _, object := range response.Objects {
+		entries = append(entries, object.Package)
+	}
+	return entries
+}
+
+func printCatalogEntries(out io.Writer, entries []CatalogEntry, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No matching plugins")
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(out, "%v\t%v\n", blue(entry.Name), entry.Description)
+	}
+	return nil
+}
+
+// Search implements `kask search [--json] [query...]`
+func (component *KuiComponent) Search(context Context, args []string) error {
+	asJSON := false
+	var terms []string
+	for _, arg := range args {
+		if arg == "--json" {
+			asJSON = true
+		} else {
+			terms = append(terms, arg)
+		}
+	}
+
+	entries, err := SearchCatalog(context, strings.Join(terms, " "))
+	if err != nil {
+		return newError(DownloadError, err)
+	}
+
+	return printCatalogEntries(os.Stdout, entries, asJSON)
+}
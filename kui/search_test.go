@@ -0,0 +1,76 @@
+package kui
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+const fakeCatalogResponse = `{
+  "objects": [
+    { "package": { "name": "@kui-shell/plugin-kubeui", "version": "1.0.0", "description": "Kubernetes UI" } },
+    { "package": { "name": "@kui-shell/plugin-s3", "version": "2.1.0", "description": "S3 browser" } }
+  ],
+  "total": 2
+}`
+
+func (suite *KaskTestSuite) TestSearchCatalog() {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("text")
+		w.Write([]byte(fakeCatalogResponse))
+	}))
+	defer server.Close()
+	defer setenv("KASK_CATALOG", server.URL)()
+
+	entries, err := SearchCatalog(suite.pluginContext, "kube")
+	suite.Nil(err)
+	suite.Equal("keywords:kui-plugin kube", query)
+	suite.Equal([]CatalogEntry{
+		{Name: "@kui-shell/plugin-kubeui", Version: "1.0.0", Description: "Kubernetes UI"},
+		{Name: "@kui-shell/plugin-s3", Version: "2.1.0", Description: "S3 browser"},
+	}, entries)
+
+	var out bytes.Buffer
+	suite.Nil(printCatalogEntries(&out, entries, true))
+	var printed []CatalogEntry
+	suite.Nil(json.Unmarshal(out.Bytes(), &printed))
+	suite.Equal(entries, printed)
+
+	out.Reset()
+	suite.Nil(printCatalogEntries(&out, entries, false))
+	suite.Contains(out.String(), "S3 browser")
+}
+
+func (suite *KaskTestSuite) TestSearchCatalogRetries() {
+	defer suite.withoutRetryDelay()()
+	defer setenv("KASK_INSTALL_RETRIES", "1")()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(fakeCatalogResponse))
+	}))
+	defer server.Close()
+	defer setenv("KASK_CATALOG", server.URL)()
+
+	entries, err := SearchCatalog(suite.pluginContext, "")
+	suite.Nil(err)
+	suite.Len(entries, 2)
+	suite.Equal(2, requests)
+}
+
+func (suite *KaskTestSuite) TestSearchCatalogFailure() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+	defer setenv("KASK_CATALOG", server.URL)()
+
+	_, err := SearchCatalog(suite.pluginContext, "")
+	suite.NotNil(err)
+}
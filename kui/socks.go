@@ -0,0 +1,110 @@
+package kui
+
+import (
+	stdcontext "context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"golang.org/x/net/proxy"
+)
+
+// socksProxy returns the SOCKS5 proxy through which we should make our
+// requests: KASK_SOCKS_PROXY, else ALL_PROXY if that names a SOCKS5
+// proxy; nil if neither does. Go's transport honors HTTP_PROXY and
+// HTTPS_PROXY by itself, but not a SOCKS5 ALL_PROXY.
+func socksProxy(context Context) (*url.URL, error) {
+	if value := getenv(context, "KASK_SOCKS_PROXY"); value != "" {
+		u, err := url.Parse(value)
+		if err != nil || !isSocks5(u) || u.Hostname() == "" {
+			return nil, newErrorf(UsageError, "invalid KASK_SOCKS_PROXY %q: expected e.g. socks5://proxy.example.com:1080", value)
+		}
+		return u, nil
+	}
+
+	for _, name := range []string{"ALL_PROXY", "all_proxy"} {
+		if value := os.Getenv(name); value != "" {
+			// another kind of proxy is not ours to handle
+			if u, err := url.Parse(value); err == nil && isSocks5(u) && u.Hostname() != "" {
+				return u, nil
+			}
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+func isSocks5(u *url.URL) bool {
+	return u.Scheme == "socks5" || u.Scheme == "socks5h"
+}
+
+// socksTransport sends each request by its base transport, or, if a
+// SOCKS5 proxy is configured, by a copy of it that dials through that
+type socksTransport struct {
+	base *http.Transport
+
+	sync.Mutex
+	byProxy map[string]*http.Transport
+}
+
+func newSocksTransport(base *http.Transport) *socksTransport {
+	return &socksTransport{base: base, byProxy: map[string]*http.Transport{}}
+}
+
+func (t *socksTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, err := socksProxy(contextOf(req.Context()))
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	transport, err := t.through(u)
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+// httpProxy returns the HTTP(S) proxy, if any, through which the base
+// transport would send req
+func (t *socksTransport) httpProxy(req *http.Request) *url.URL {
+	if t.base.Proxy == nil {
+		return nil
+	}
+	u, err := t.base.Proxy(req)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// through returns the transport that dials through the given proxy
+func (t *socksTransport) through(u *url.URL) (*http.Transport, error) {
+	t.Lock()
+	defer t.Unlock()
+	if transport, ok := t.byProxy[u.String()]; ok {
+		return transport, nil
+	}
+
+	dialer, err := proxy.FromURL(u, proxy.Direct)
+	if err != nil {
+		return nil, newError(UsageError, err)
+	}
+
+	transport := t.base.Clone()
+	// the SOCKS5 proxy takes the place of any HTTP proxy
+	transport.Proxy = nil
+	if contextDialer, ok := dialer.(proxy.ContextDialer); ok {
+		transport.DialContext = contextDialer.DialContext
+	} else {
+		transport.DialContext = func(ctx stdcontext.Context, network string, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		}
+	}
+	t.byProxy[u.String()] = transport
+	return transport, nil
+}
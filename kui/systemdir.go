@@ -0,0 +1,30 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+)
+
+// systemExtract returns the extract of the given version in the
+// read-only, system-wide cache named by KASK_SYSTEM_DIR, if that has
+// a complete one made with the same options. The system-wide cache is
+// laid out as a plugin directory is, and is never written to.
+func systemExtract(context Context, version string, options ExtractOptions) (string, bool) {
+	systemDir := getenv(context, "KASK_SYSTEM_DIR")
+	if systemDir == "" {
+		return "", false
+	}
+	cache := cacheLayoutIn(systemDir, systemDir, version)
+
+	marker, err := ioutil.ReadFile(cache.successFile)
+	if err != nil || string(marker) != options.Include {
+		return "", false
+	}
+	// we may not repair it, so it must be runnable as is
+	info, err := os.Stat(GetRootCommand(context, cache.extractedDir).Path)
+	if err != nil || (runtime.GOOS != "windows" && info.Mode()&0111 == 0) {
+		return "", false
+	}
+	return cache.extractedDir, true
+}
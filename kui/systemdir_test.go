@@ -0,0 +1,74 @@
+package kui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestSystemDir() {
+	suite.skipUnlessLinux()
+
+	// populate what will be the system-wide cache
+	server := serveDist(makeFakeDist())
+	restore := suite.isolate(server.URL)
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	systemDir, _ := suite.pluginContext.PluginDirectory()
+	restore()
+	server.Close()
+
+	// and a user whose own cache is empty, and who cannot download
+	defer suite.isolate(unreachableURL())()
+	defer setenv("KASK_SYSTEM_DIR", systemDir)()
+
+	cmd, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Require().Nil(err)
+	suite.True(strings.HasPrefix(cmd.Path, systemDir), cmd.Path)
+
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	_, err = os.Stat(filepath.Join(pluginDir, "cache-1.2.3"))
+	suite.True(os.IsNotExist(err), "the user's cache should be untouched")
+
+	// a version the system-wide cache lacks is for the user's cache
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "4.5.6", false)
+	suite.Equal(ExitDownload, ExitCode(err))
+}
+
+// a system-wide cache holding the given version
+func (suite *KaskTestSuite) makeSystemDir(version string) string {
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, version, false)
+	suite.Require().Nil(err)
+	systemDir, _ := suite.pluginContext.PluginDirectory()
+	return systemDir
+}
+
+func (suite *KaskTestSuite) TestWhichOfSystemDir() {
+	suite.skipUnlessLinux()
+	systemDir := suite.makeSystemDir(suite.version)
+	defer suite.isolate(unreachableURL())()
+	defer setenv("KASK_SYSTEM_DIR", systemDir)()
+
+	binary, err := suite.cmd.ResolveKuiBinary(suite.pluginContext, suite.version)
+	suite.Require().Nil(err)
+	suite.True(strings.HasPrefix(binary.Path, systemDir), binary.Path)
+	suite.True(binary.Exists)
+	suite.True(binary.System)
+}
+
+func (suite *KaskTestSuite) TestRepairLeavesSystemDir() {
+	suite.skipUnlessLinux()
+	systemDir := suite.makeSystemDir(suite.version)
+	defer suite.isolate(unreachableURL())()
+	defer setenv("KASK_SYSTEM_DIR", systemDir)()
+
+	output, err := suite.repair()
+	suite.Nil(err)
+	suite.Contains(output, "system-wide cache")
+	pluginDir, _ := suite.pluginContext.PluginDirectory()
+	_, err = os.Stat(filepath.Join(pluginDir, "cache-"+suite.version))
+	suite.True(os.IsNotExist(err), "the user's cache should be untouched")
+}
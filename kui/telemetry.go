@@ -0,0 +1,137 @@
+package kui
+
+import (
+	"bytes"
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how long a telemetry report may take, and how long we wait, on the
+// way out, for those still in flight
+var telemetryTimeout = 2 * time.Second
+
+// telemetryReports are those of one Run still in flight; each Run has
+// its own, so that a flush that gave up on one Run's reports never
+// waits on, or races with, the next Run's
+type telemetryReports struct {
+	inFlight sync.WaitGroup
+}
+
+// TelemetryEvent is what we report of a failure to fetch the Kui base,
+// if KASK_TELEMETRY is on: nothing of the user, their machine beyond
+// its platform, or their paths, just enough to spot a failing mirror
+type TelemetryEvent struct {
+	Event    string `json:"event"`
+	Kind     string `json:"kind"`
+	Status   int    `json:"status,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Platform string `json:"platform"`
+	Version  string `json:"version"`
+	Kask     string `json:"kask"`
+}
+
+// telemetryEndpoint returns where to report failures, if KASK_TELEMETRY
+// is on and KASK_TELEMETRY_URL names an endpoint; telemetry is off by
+// default
+func telemetryEndpoint(context Context) (string, bool) {
+	if !strings.EqualFold(getenv(context, "KASK_TELEMETRY"), "on") {
+		return "", false
+	}
+	endpoint := getenv(context, "KASK_TELEMETRY_URL")
+	return endpoint, endpoint != ""
+}
+
+// newTelemetryEvent describes the failure to fetch the given version
+// of the Kui base from location; of location, only the host is kept
+func newTelemetryEvent(context Context, version string, location string, err error) TelemetryEvent {
+	event := TelemetryEvent{
+		Event:    "download-failure",
+		Kind:     KindOf(err).String(),
+		Platform: kuiPlatform(context, PlatformKey()),
+		Version:  version,
+		Kask:     GetVersion().String(),
+	}
+	if u, err := url.Parse(location); err == nil {
+		event.Host = u.Hostname()
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		event.Status = statusErr.StatusCode
+	}
+	return event
+}
+
+// reportFailure sends, in the background, a TelemetryEvent for the
+// failure to fetch the given version from location, if telemetry is
+// enabled. Whether or not the report arrives, it never affects the
+// outcome of the command.
+func (component *KuiComponent) reportFailure(context Context, version string, location string, err error) {
+	endpoint, enabled := telemetryEndpoint(context)
+	if !enabled || KindOf(err) == OfflineMissError || KindOf(err) == UsageError {
+		return
+	}
+
+	body, marshalErr := json.Marshal(newTelemetryEvent(context, version, location, err))
+	if marshalErr != nil {
+		return
+	}
+
+	if component.telemetry == nil {
+		component.telemetry = &telemetryReports{}
+	}
+	component.telemetry.send(context, endpoint, body)
+}
+
+// send posts body to endpoint in the background
+func (reports *telemetryReports) send(context Context, endpoint string, body []byte) {
+	timeout := telemetryTimeout
+	reports.inFlight.Add(1)
+	go func() {
+		defer reports.inFlight.Done()
+		ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+		if err != nil {
+			context.logger().Debugf("telemetry not sent %v", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := doRequest(context, req)
+		if err != nil {
+			context.logger().Debugf("telemetry not sent %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// flushTelemetry waits for the reports in flight, but for no longer
+// than telemetryTimeout. Those reports are then done with: any later
+// report starts afresh, rather than joining reports that a timed-out
+// flush may still be waiting on.
+func (component *KuiComponent) flushTelemetry() {
+	reports := component.telemetry
+	component.telemetry = nil
+	if reports != nil {
+		reports.flush()
+	}
+}
+
+func (reports *telemetryReports) flush() {
+	done := make(chan struct{})
+	go func() {
+		reports.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(telemetryTimeout):
+	}
+}
@@ -0,0 +1,88 @@
+package kui
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// a telemetry endpoint that records the events posted to it
+func serveTelemetry(events *[]TelemetryEvent, lock *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var event TelemetryEvent
+		if r.Method == "POST" && json.Unmarshal(body, &event) == nil {
+			lock.Lock()
+			*events = append(*events, event)
+			lock.Unlock()
+		}
+	}))
+}
+
+func (suite *KaskTestSuite) TestTelemetryOnlyWhenEnabled() {
+	defer suite.withoutRetryDelay()()
+	var events []TelemetryEvent
+	var lock sync.Mutex
+	telemetry := serveTelemetry(&events, &lock)
+	defer telemetry.Close()
+	gets := 0
+	dist := serveDistAfterFailures(10, http.StatusServiceUnavailable, &gets)
+	defer dist.Close()
+	defer suite.isolate(dist.URL)()
+	defer setenv("KASK_TELEMETRY_URL", telemetry.URL)()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Equal(ExitDownload, ExitCode(err))
+	suite.cmd.flushTelemetry()
+	suite.Empty(events, "telemetry should be off by default")
+
+	defer setenv("KASK_TELEMETRY", "on")()
+	_, err = suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Equal(ExitDownload, ExitCode(err), "telemetry should not change the outcome")
+	suite.cmd.flushTelemetry()
+
+	lock.Lock()
+	defer lock.Unlock()
+	suite.Require().Len(events, 1)
+	suite.Equal(TelemetryEvent{
+		Event:    "download-failure",
+		Kind:     "download",
+		Status:   http.StatusServiceUnavailable,
+		Host:     "127.0.0.1",
+		Platform: kuiPlatform(suite.pluginContext, PlatformKey()),
+		Version:  "1.2.3",
+		Kask:     GetVersion().String(),
+	}, events[0])
+}
+
+func (suite *KaskTestSuite) TestTelemetryDoesNotBlock() {
+	previous := telemetryTimeout
+	telemetryTimeout = 100 * time.Millisecond
+	defer func() { telemetryTimeout = previous }()
+
+	// an endpoint that never answers
+	hung := make(chan struct{})
+	telemetry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer telemetry.Close()
+	defer close(hung)
+
+	dist := serveDist(nil)
+	dist.Close()
+	defer suite.isolate(dist.URL)()
+	defer setenv("KASK_TELEMETRY", "on")()
+	defer setenv("KASK_TELEMETRY_URL", telemetry.URL)()
+
+	// the first Run's flush gives up on its report; the second Run's
+	// report must not then race with it (as go test -race would tell)
+	for run := 0; run < 2; run++ {
+		start := time.Now()
+		err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "prefetch"})
+		suite.Equal(ExitDownload, ExitCode(err))
+		suite.True(time.Since(start) < 2*time.Second, "a hung endpoint should not hold up the command")
+	}
+}
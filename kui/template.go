@@ -0,0 +1,50 @@
+package kui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var distTemplatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// expandDistTemplate renders a KASK_DIST_URL_TEMPLATE, e.g.
+// "https://mirror.example.com/kui/{version}/Kui{suffix}", for the given
+// version and platform. The placeholders are:
+//
+//	{version}  the Kui version, e.g. 1.0.0
+//	{os}       the Kui name of the OS, e.g. linux, darwin, win32
+//	{arch}     the Kui name of the architecture, e.g. x64
+//	{suffix}   the suffix of the dist name, e.g. -base-linux-x64.zip
+//	{channel}  the KASK_CHANNEL, e.g. stable or beta
+func expandDistTemplate(context Context, template string, version string, key string) (string, error) {
+	channel, err := distChannel(context)
+	if err != nil {
+		return "", err
+	}
+	kuiOS, kuiArch := splitPlatformKey(kuiPlatform(context, key))
+	values := map[string]string{
+		"{version}": version,
+		"{os}":      kuiOS,
+		"{arch}":    kuiArch,
+		"{suffix}":  distSuffix(context, key),
+		"{channel}": channel,
+	}
+
+	var unknown []string
+	location := distTemplatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		if value, ok := values[placeholder]; ok {
+			return value
+		}
+		unknown = append(unknown, placeholder)
+		return placeholder
+	})
+
+	if len(unknown) > 0 {
+		return location, fmt.Errorf("unknown placeholder %s in KASK_DIST_URL_TEMPLATE", strings.Join(unknown, ", "))
+	}
+	if strings.ContainsAny(location, "{}") {
+		return location, fmt.Errorf("unbalanced braces in KASK_DIST_URL_TEMPLATE %q", template)
+	}
+	return location, nil
+}
@@ -0,0 +1,60 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+func (suite *KaskTestSuite) TestExpandDistTemplate() {
+	location, err := expandDistTemplate(suite.pluginContext, "https://{os}.example.com/kui/{version}/Kui{suffix}?arch={arch}", "1.2.3", "linux-amd64")
+	suite.Nil(err)
+	suite.Equal("https://linux.example.com/kui/1.2.3/Kui-base-linux-x64.zip?arch=x64", location)
+
+	location, err = expandDistTemplate(suite.pluginContext, "https://example.com/{version}/Kui{suffix}", "1.2.3", "darwin-amd64")
+	suite.Nil(err)
+	suite.Equal("https://example.com/1.2.3/Kui-base-darwin-x64.tar.bz2", location)
+
+	defer setenv("KASK_NATIVE_ARCH", "true")()
+	location, err = expandDistTemplate(suite.pluginContext, "https://example.com/{os}-{arch}", "1.2.3", "windows-386")
+	suite.Nil(err)
+	suite.Equal("https://example.com/win32-ia32", location)
+}
+
+func (suite *KaskTestSuite) TestExpandDistTemplateUnknownPlaceholder() {
+	_, err := expandDistTemplate(suite.pluginContext, "https://example.com/{release}/Kui{suffix}", "1.2.3", "linux-amd64")
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "{release}")
+
+	_, err = expandDistTemplate(suite.pluginContext, "https://example.com/{version/Kui", "1.2.3", "linux-amd64")
+	suite.NotNil(err)
+}
+
+func (suite *KaskTestSuite) TestDistTemplateOverridesHost() {
+	defer setenv("KASK_DIST_URL_TEMPLATE", "https://mirror.example.com/{version}//Kui{suffix}")()
+
+	location, err := distLocation(suite.pluginContext, "1.2.3")
+	suite.Nil(err)
+	suite.Equal("https://mirror.example.com/1.2.3/Kui"+GetDistOSSuffix(suite.pluginContext), location)
+}
+
+func (suite *KaskTestSuite) TestInvalidDistTemplateIsAUsageError() {
+	defer setenv("KASK_DIST_URL_TEMPLATE", "https://mirror.example.com/{nope}")()
+
+	_, err := suite.cmd.DownloadVersionIfNecessary(suite.pluginContext, "1.2.3", false)
+	suite.Equal(ExitUsage, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestGetDistLocationWarnsOfInvalidTemplate() {
+	defer setenv("KASK_DIST_URL_TEMPLATE", "https://mirror.example.com/{nope}")()
+
+	stderr := os.Stderr
+	capture, err := ioutil.TempFile(suite.SaveDir, "stderr")
+	suite.Require().Nil(err)
+	os.Stderr = capture
+	GetDistLocation(suite.pluginContext, "1.2.3")
+	os.Stderr = stderr
+	capture.Close()
+
+	warning, _ := ioutil.ReadFile(capture.Name())
+	suite.Contains(string(warning), "{nope}")
+}
@@ -0,0 +1,185 @@
+package kui
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultReleasesLocation = "https://api.github.com/repos/kui-shell/kask/releases/latest"
+
+// how long we trust a previous answer to "what is the latest release?"
+const updateCheckTTL = 24 * time.Hour
+
+// how long an update-check lock may be held before we consider its
+// owner to have died
+const updateCheckStaleLock = time.Minute
+
+// serializes update checks within this process; the lock file below
+// does the same across processes
+var updateCheckMutex sync.Mutex
+
+// the on-disk memory of the last update check
+type updateCheckCache struct {
+	Latest     string     `json:"latest"`
+	CheckedAt  time.Time  `json:"checkedAt"`
+	Validators Validators `json:"validators"`
+}
+
+func GetReleasesLocation(context Context) string {
+	if location, overrideSet := lookupEnv(context, "KASK_RELEASES"); overrideSet {
+		return location
+	}
+	return defaultReleasesLocation
+}
+
+func updateCheckDisabled(context Context) bool {
+	return strings.EqualFold(getenv(context, "KASK_UPDATE_CHECK"), "off")
+}
+
+func readUpdateCheckCache(cacheFile string) updateCheckCache {
+	var cache updateCheckCache
+	if bytes, err := ioutil.ReadFile(cacheFile); err == nil {
+		json.Unmarshal(bytes, &cache)
+	}
+	return cache
+}
+
+func (cache updateCheckCache) fresh() bool {
+	return cache.Latest != "" && time.Since(cache.CheckedAt) < updateCheckTTL
+}
+
+// acquireLock creates the given lock file, waiting for up to timeout
+// for any other holder to release it. A lock left behind by a dead
+// process is broken once it is older than updateCheckStaleLock.
+func acquireLock(lockFile string, timeout time.Duration) (func(), bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockFile) }, true
+		}
+		if info, err := os.Stat(lockFile); err == nil && time.Since(info.ModTime()) > updateCheckStaleLock {
+			os.Remove(lockFile)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// LatestVersion returns the latest released version of kask. The
+// answer is cached on disk for updateCheckTTL, and concurrent callers,
+// whether in this process or another, share a single request.
+func LatestVersion(context Context) (string, error) {
+	updateCheckMutex.Lock()
+	defer updateCheckMutex.Unlock()
+
+	pluginDir, err := context.PluginDirectory()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(pluginDir, 0700); err != nil {
+		return "", err
+	}
+	cacheFile := filepath.Join(pluginDir, "update-check.json")
+
+	cache := readUpdateCheckCache(cacheFile)
+	if cache.fresh() {
+		context.logger().Debugf("using cached update check %s", cache.Latest)
+		return cache.Latest, nil
+	}
+
+	unlock, locked := acquireLock(cacheFile+".lock", 2*time.Second)
+	if !locked {
+		return "", fmt.Errorf("timed out waiting for another update check")
+	}
+	defer unlock()
+
+	// someone else may have done the work while we waited for the lock
+	cache = readUpdateCheckCache(cacheFile)
+	if cache.fresh() {
+		return cache.Latest, nil
+	}
+
+	releaseFile := filepath.Join(pluginDir, "latest-release.json")
+	if _, err := os.Stat(releaseFile); err != nil {
+		cache.Validators = Validators{}
+	}
+	var validators Validators
+	err = withRetries(stdcontext.Background(), context, func() error {
+		var err error
+		_, validators, err = ConditionalGet(context, GetReleasesLocation(context), releaseFile, cache.Validators)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	bytes, err := ioutil.ReadFile(releaseFile)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(bytes, &release); err != nil || release.TagName == "" {
+		return "", fmt.Errorf("unable to parse the latest release of kask")
+	}
+
+	cache = updateCheckCache{
+		Latest:     strings.TrimPrefix(release.TagName, "v"),
+		CheckedAt:  time.Now(),
+		Validators: validators,
+	}
+	if bytes, err := json.Marshal(cache); err == nil {
+		ioutil.WriteFile(cacheFile, bytes, 0644)
+	}
+
+	return cache.Latest, nil
+}
+
+// newerVersion returns whether a is a later dotted version than b
+func newerVersion(a string, b string) bool {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = toInt(as[i])
+		}
+		if i < len(bs) {
+			y = toInt(bs[i])
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+// notifyOfUpdate tells the user, on stderr, if a newer kask has been released
+func notifyOfUpdate(context MainContext) {
+	if updateCheckDisabled(context) || context.version == "dev" {
+		return
+	}
+
+	latest, err := LatestVersion(context)
+	if err != nil {
+		context.logger().Debugf("update check failed %v", err)
+		return
+	}
+
+	if newerVersion(latest, context.version) {
+		fmt.Fprintf(os.Stderr, "%v\n", yellow(fmt.Sprintf("A newer version of kask is available: %s", latest)))
+	}
+}
@@ -0,0 +1,75 @@
+package kui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+func (suite *KaskTestSuite) serveReleases(requests *int) *httptest.Server {
+	var mutex sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		*requests++
+		mutex.Unlock()
+		w.Write([]byte(`{"tag_name": "v1.2.3"}`))
+	}))
+}
+
+func (suite *KaskTestSuite) TestUpdateCheckWithinTTL() {
+	requests := 0
+	server := suite.serveReleases(&requests)
+	defer server.Close()
+	defer suite.isolate("")()
+	defer setenv("KASK_RELEASES", server.URL)()
+
+	latest, err := LatestVersion(suite.pluginContext)
+	suite.Nil(err)
+	suite.Equal("1.2.3", latest)
+	suite.Equal(1, requests)
+
+	latest, err = LatestVersion(suite.pluginContext)
+	suite.Nil(err)
+	suite.Equal("1.2.3", latest)
+	suite.Equal(1, requests, "a second check within the TTL should use the cache")
+}
+
+func (suite *KaskTestSuite) TestConcurrentUpdateChecks() {
+	requests := 0
+	server := suite.serveReleases(&requests)
+	defer server.Close()
+	defer suite.isolate("")()
+	defer setenv("KASK_RELEASES", server.URL)()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			LatestVersion(suite.pluginContext)
+		}()
+	}
+	wg.Wait()
+	suite.Equal(1, requests)
+}
+
+func (suite *KaskTestSuite) TestUpdateCheckDisabled() {
+	requests := 0
+	server := suite.serveReleases(&requests)
+	defer server.Close()
+	defer suite.isolate("")()
+	defer setenv("KASK_RELEASES", server.URL)()
+	defer setenv("KASK_UPDATE_CHECK", "off")()
+
+	context := *suite.pluginContext
+	context.version = "0.0.1"
+	notifyOfUpdate(context)
+	suite.Equal(0, requests)
+}
+
+func (suite *KaskTestSuite) TestNewerVersion() {
+	suite.True(newerVersion("1.2.3", "1.2.2"))
+	suite.True(newerVersion("1.10.0", "1.9.9"))
+	suite.False(newerVersion("1.2.3", "1.2.3"))
+	suite.False(newerVersion("1.2", "1.2.1"))
+}
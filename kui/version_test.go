@@ -0,0 +1,44 @@
+package kui
+
+import (
+	"bytes"
+	"os/exec"
+)
+
+func (suite *KaskTestSuite) TestVersionWhenKuiFails() {
+	suite.skipUnlessLinux()
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo partial; exit 1")
+
+	err := suite.cmd.printVersion(*suite.pluginContext, "kask", cmd, []string{"version"}, &out)
+	suite.Equal(ExitChild, ExitCode(err))
+	suite.Equal(blue("kask")+"\tdev unknown\n"+blue("kui")+"\t<unavailable>\n", out.String())
+}
+
+func (suite *KaskTestSuite) TestVersionWhenKuiIsSilent() {
+	suite.skipUnlessLinux()
+	var out bytes.Buffer
+	cmd := exec.Command("true")
+
+	err := suite.cmd.printVersion(*suite.pluginContext, "kask", cmd, []string{"version"}, &out)
+	suite.Equal(ExitChild, ExitCode(err))
+	suite.Equal(blue("kask")+"\tdev unknown\n"+blue("kui")+"\t<unavailable>\n", out.String())
+}
+
+func (suite *KaskTestSuite) TestVersionExitCodeWhenKuiFails() {
+	suite.skipUnlessLinux()
+	defer setenv("FAKE_KUI_EXIT", "3")()
+
+	_, err := suite.runFakeKui("version")
+	suite.Equal(ExitChild, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestVersionWhenKuiSucceeds() {
+	suite.skipUnlessLinux()
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", "printf 1.2.3")
+
+	err := suite.cmd.printVersion(*suite.pluginContext, "kask", cmd, []string{"version"}, &out)
+	suite.Nil(err)
+	suite.Equal(blue("kask")+"\tdev unknown\n"+blue("kui")+"\t1.2.3\n", out.String())
+}
@@ -0,0 +1,110 @@
+package kui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// KuiBinary describes the Kui executable that kask would run
+type KuiBinary struct {
+	Path       string `json:"path"`
+	Exists     bool   `json:"exists"`
+	Executable bool   `json:"executable"`
+	// whether it is that of the system-wide cache, per KASK_SYSTEM_DIR
+	System bool `json:"system,omitempty"`
+}
+
+// ResolveKuiBinary returns the Kui executable of the given version,
+// for this platform, whether or not it has been downloaded yet; that
+// of the system-wide cache, if it has this version, as that is the one
+// we would run
+func (component *KuiComponent) ResolveKuiBinary(context Context, version string) (KuiBinary, error) {
+	options, err := GetExtractOptions(context)
+	if err != nil {
+		return KuiBinary{}, newError(UsageError, err)
+	}
+	if systemDir, ok := systemExtract(context, version, options); ok {
+		path, err := filepath.Abs(GetRootCommand(context, systemDir).Path)
+		if err != nil {
+			return KuiBinary{}, err
+		}
+		return KuiBinary{Path: path, Exists: true, Executable: true, System: true}, nil
+	}
+
+	status, err := component.CacheStatusOf(context, version)
+	if err != nil {
+		return KuiBinary{}, err
+	}
+
+	path, err := filepath.Abs(status.Binary)
+	if err != nil {
+		return KuiBinary{}, err
+	}
+
+	binary := KuiBinary{Path: path, Exists: status.BinaryExists}
+	if info, err := os.Stat(path); err == nil && status.BinaryExists {
+		binary.Executable = runtime.GOOS == "windows" || info.Mode()&0111 != 0
+	}
+	return binary, nil
+}
+
+func printKuiBinary(out io.Writer, binary KuiBinary, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(binary)
+	}
+
+	status := "executable"
+	if !binary.Exists {
+		status = "not downloaded"
+	} else if !binary.Executable {
+		status = "not executable"
+	} else if binary.System {
+		status = "executable, system-wide"
+	}
+	fmt.Fprintf(out, "%s\t%v\n", binary.Path, gray(status))
+	return nil
+}
+
+// Which implements `kask which [--json]`
+func (component *KuiComponent) Which(context Context, args []string) error {
+	asJSON := false
+	for _, arg := range args {
+		if arg == "--json" {
+			asJSON = true
+		} else {
+			return newErrorf(UsageError, "usage: kask which [--json]")
+		}
+	}
+
+	binary, err := component.ResolveKuiBinary(context, component.DistVersion(context))
+	if err != nil {
+		return err
+	}
+	return printKuiBinary(os.Stdout, binary, asJSON)
+}
+
+// URL implements `kask url [version]`, printing where the Kui base
+// would be fetched from, without fetching it
+func (component *KuiComponent) URL(context Context, args []string) error {
+	version := component.DistVersion(context)
+	switch len(args) {
+	case 0:
+	case 1:
+		version = args[0]
+	default:
+		return newErrorf(UsageError, "usage: kask url [version]")
+	}
+
+	location, err := distLocation(context, version)
+	if err != nil {
+		return newError(UsageError, err)
+	}
+	fmt.Println(location)
+	return nil
+}
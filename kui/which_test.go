@@ -0,0 +1,66 @@
+package kui
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+)
+
+func (suite *KaskTestSuite) TestWhichBeforeDownload() {
+	defer suite.isolate("http://127.0.0.1:1/")()
+
+	binary, err := suite.cmd.ResolveKuiBinary(suite.pluginContext, suite.version)
+	suite.Require().Nil(err)
+	suite.True(filepath.IsAbs(binary.Path))
+	suite.False(binary.Exists)
+	suite.False(binary.Executable)
+}
+
+func (suite *KaskTestSuite) TestWhichMatchesTheRootCommand() {
+	suite.skipUnlessLinux()
+	server := serveDist(makeFakeDist())
+	defer server.Close()
+	defer suite.isolate(server.URL)()
+
+	command, err := suite.cmd.DownloadDistIfNecessary(suite.pluginContext, false)
+	suite.Require().Nil(err)
+
+	binary, err := suite.cmd.ResolveKuiBinary(suite.pluginContext, suite.version)
+	suite.Require().Nil(err)
+	suite.Equal(command.Path, binary.Path)
+	suite.True(binary.Exists)
+	suite.True(binary.Executable)
+
+	var out bytes.Buffer
+	suite.Nil(printKuiBinary(&out, binary, true))
+	var decoded KuiBinary
+	suite.Nil(json.Unmarshal(out.Bytes(), &decoded))
+	suite.Equal(binary, decoded)
+
+	out.Reset()
+	suite.Nil(printKuiBinary(&out, binary, false))
+	suite.Equal(command.Path+"\t"+gray("executable")+"\n", out.String())
+}
+
+func (suite *KaskTestSuite) TestWhichUsage() {
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "which", "--bogus"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
+
+func (suite *KaskTestSuite) TestURL() {
+	defer suite.isolate("https://mirror.example.com/kui/")()
+
+	out := suite.captureStdout(func() {
+		suite.Nil(suite.cmd.URL(suite.pluginContext, []string{}))
+	})
+	suite.Equal(GetDistLocation(suite.pluginContext, suite.version)+"\n", out)
+
+	defer setenv("KASK_DIST_URL_TEMPLATE", "https://mirror.example.com/kui/{version}/{os}-{arch}{suffix}")()
+	out = suite.captureStdout(func() {
+		suite.Nil(suite.cmd.URL(suite.pluginContext, []string{"1.2.3"}))
+	})
+	suite.Equal(GetDistLocation(suite.pluginContext, "1.2.3")+"\n", out)
+	suite.Contains(out, "/kui/1.2.3/")
+
+	suite.Equal(ExitUsage, ExitCode(suite.cmd.URL(suite.pluginContext, []string{"1.2.3", "4.5.6"})))
+}
@@ -0,0 +1,53 @@
+package kui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// extractWorkdirFlag removes any --workdir <dir> or --workdir=<dir>
+// from args, returning the remaining args and the requested directory
+func extractWorkdirFlag(args []string) ([]string, string, error) {
+	var rest []string
+	workdir := ""
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		switch {
+		case arg == "--workdir":
+			if idx+1 >= len(args) {
+				return nil, "", fmt.Errorf("--workdir requires a directory")
+			}
+			workdir = args[idx+1]
+			idx++
+		case strings.HasPrefix(arg, "--workdir="):
+			workdir = strings.TrimPrefix(arg, "--workdir=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, workdir, nil
+}
+
+// resolveWorkdir returns the working directory for the Kui child: the
+// --workdir flag if given, else $KASK_WORKDIR, else our own working
+// directory. Kui resolves relative paths against it, so we insist that
+// it exists.
+func resolveWorkdir(context Context, flag string) (string, error) {
+	workdir := flag
+	if workdir == "" {
+		workdir = getenv(context, "KASK_WORKDIR")
+	}
+	if workdir == "" {
+		return os.Getwd()
+	}
+
+	info, err := os.Stat(workdir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory: %v", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid working directory: %s is not a directory", workdir)
+	}
+	return workdir, nil
+}
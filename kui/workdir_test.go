@@ -0,0 +1,51 @@
+package kui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (suite *KaskTestSuite) TestWorkdir() {
+	suite.skipUnlessLinux()
+	workdir, _ := ioutil.TempDir(suite.SaveDir, "workdir")
+	workdir, _ = filepath.EvalSymlinks(workdir)
+	record := filepath.Join(suite.SaveDir, "fake-kui-pwd")
+	defer setenv("FAKE_KUI_PWD", record)()
+
+	forwarded, err := suite.runFakeKui("list", "--workdir", workdir)
+	suite.Nil(err)
+	suite.Equal([]string{"list"}, forwarded)
+	pwd, _ := ioutil.ReadFile(record)
+	suite.Equal(workdir, strings.TrimSpace(string(pwd)))
+}
+
+func (suite *KaskTestSuite) TestWorkdirFromEnv() {
+	workdir, _ := ioutil.TempDir(suite.SaveDir, "workdir")
+	defer setenv("KASK_WORKDIR", workdir)()
+
+	resolved, err := resolveWorkdir(suite.pluginContext, "")
+	suite.Nil(err)
+	suite.Equal(workdir, resolved)
+
+	// the flag wins over the environment
+	resolved, err = resolveWorkdir(suite.pluginContext, suite.SaveDir)
+	suite.Nil(err)
+	suite.Equal(suite.SaveDir, resolved)
+}
+
+func (suite *KaskTestSuite) TestWorkdirDefault() {
+	cwd, _ := os.Getwd()
+	resolved, err := resolveWorkdir(suite.pluginContext, "")
+	suite.Nil(err)
+	suite.Equal(cwd, resolved)
+}
+
+func (suite *KaskTestSuite) TestInvalidWorkdir() {
+	err := suite.cmd.Run(*suite.pluginContext, []string{"kask", "list", "--workdir=" + filepath.Join(suite.SaveDir, "nope")})
+	suite.Equal(ExitUsage, ExitCode(err))
+
+	err = suite.cmd.Run(*suite.pluginContext, []string{"kask", "list", "--workdir"})
+	suite.Equal(ExitUsage, ExitCode(err))
+}
@@ -0,0 +1,110 @@
+// Package retry calls a function until it succeeds, backing off
+// exponentially between failed attempts.
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Policy says how often, and how patiently, to retry
+type Policy struct {
+	// how many times in all to call the function; less than 1 means once
+	Attempts int
+
+	// the pause before the first retry
+	Delay time.Duration
+
+	// how much longer each pause is than the last; less than 1 means 2
+	Multiplier float64
+
+	// the longest pause, however many retries there have been; 0 means
+	// no limit
+	MaxDelay time.Duration
+
+	// the fraction, from 0 to 1, of each pause that is random, so that
+	// many clients that failed together do not retry together
+	Jitter float64
+
+	// whether a failure may go away if we try again; nil means always
+	Retryable func(err error) bool
+
+	// called before each retry, with the number of the failed attempt
+	// (from 1) and its error; if it returns an error, we give up with
+	// that error instead
+	OnRetry func(attempt int, err error) error
+}
+
+// Do calls fn until it succeeds, it fails in a way that policy says
+// is not retryable, there have been policy.Attempts calls, or ctx is
+// done. It returns the error of the last call, or that of ctx.
+func Do(ctx context.Context, policy Policy, fn func() error) error {
+	attempts := policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := policy.Delay
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil || attempt >= attempts || (policy.Retryable != nil && !policy.Retryable(err)) {
+			return err
+		}
+
+		if policy.OnRetry != nil {
+			if err := policy.OnRetry(attempt, err); err != nil {
+				return err
+			}
+		}
+
+		if err := sleep(ctx, policy.jittered(delay)); err != nil {
+			return err
+		}
+		delay = policy.next(delay)
+	}
+}
+
+// next returns the pause that follows one of the given length
+func (policy Policy) next(delay time.Duration) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay = time.Duration(float64(delay) * multiplier)
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay
+}
+
+// jittered returns delay, less a random part of policy.Jitter of it
+func (policy Policy) jittered(delay time.Duration) time.Duration {
+	jitter := policy.Jitter
+	if jitter <= 0 {
+		return delay
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	return delay - time.Duration(rand.Float64()*jitter*float64(delay))
+}
+
+// sleep pauses for delay, or until ctx is done, whichever is sooner
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
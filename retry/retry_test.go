@@ -0,0 +1,107 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RetryTestSuite struct {
+	suite.Suite
+}
+
+func TestRetryTestSuite(t *testing.T) {
+	suite.Run(t, new(RetryTestSuite))
+}
+
+var errTransient = errors.New("connection reset by peer")
+var errPermanent = errors.New("404 Not Found")
+
+// a function that fails the first few times it is called
+func failingTimes(failures int, err error, calls *int) func() error {
+	return func() error {
+		*calls++
+		if *calls <= failures {
+			return err
+		}
+		return nil
+	}
+}
+
+func (suite *RetryTestSuite) TestSuccessAfterRetries() {
+	calls, retries := 0, []int{}
+	policy := Policy{
+		Attempts: 3,
+		OnRetry:  func(attempt int, err error) error { retries = append(retries, attempt); return nil },
+	}
+	suite.Nil(Do(context.Background(), policy, failingTimes(2, errTransient, &calls)))
+	suite.Equal(3, calls)
+	suite.Equal([]int{1, 2}, retries)
+}
+
+func (suite *RetryTestSuite) TestAttemptsAreBounded() {
+	calls := 0
+	err := Do(context.Background(), Policy{Attempts: 3}, failingTimes(10, errTransient, &calls))
+	suite.Equal(errTransient, err)
+	suite.Equal(3, calls)
+}
+
+func (suite *RetryTestSuite) TestAtLeastOnce() {
+	calls := 0
+	suite.Equal(errTransient, Do(context.Background(), Policy{}, failingTimes(10, errTransient, &calls)))
+	suite.Equal(1, calls)
+}
+
+func (suite *RetryTestSuite) TestPermanentError() {
+	calls := 0
+	policy := Policy{Attempts: 5, Retryable: func(err error) bool { return err != errPermanent }}
+	suite.Equal(errPermanent, Do(context.Background(), policy, failingTimes(10, errPermanent, &calls)))
+	suite.Equal(1, calls)
+}
+
+func (suite *RetryTestSuite) TestOnRetryMayGiveUp() {
+	calls := 0
+	abandoned := errors.New("unable to clean up")
+	policy := Policy{Attempts: 5, OnRetry: func(int, error) error { return abandoned }}
+	suite.Equal(abandoned, Do(context.Background(), policy, failingTimes(10, errTransient, &calls)))
+	suite.Equal(1, calls)
+}
+
+func (suite *RetryTestSuite) TestContextCancel() {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	fn := func() error {
+		calls++
+		cancel()
+		return errTransient
+	}
+
+	start := time.Now()
+	err := Do(ctx, Policy{Attempts: 5, Delay: time.Minute}, fn)
+	suite.True(errors.Is(err, context.Canceled))
+	suite.Equal(1, calls)
+	suite.Less(int64(time.Since(start)), int64(time.Second), "a cancel should interrupt the pause")
+}
+
+func (suite *RetryTestSuite) TestBackoff() {
+	policy := Policy{Delay: time.Second, MaxDelay: 3 * time.Second}
+	suite.Equal(2*time.Second, policy.next(time.Second))
+	suite.Equal(3*time.Second, policy.next(2*time.Second))
+
+	policy = Policy{Delay: time.Second, Multiplier: 1.5}
+	suite.Equal(1500*time.Millisecond, policy.next(time.Second))
+}
+
+func (suite *RetryTestSuite) TestJitter() {
+	suite.Equal(time.Second, Policy{}.jittered(time.Second))
+
+	policy := Policy{Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		delay := policy.jittered(time.Second)
+		suite.LessOrEqual(int64(delay), int64(time.Second))
+		suite.GreaterOrEqual(int64(delay), int64(500*time.Millisecond))
+	}
+}